@@ -3,6 +3,8 @@ package main
 import (
 	"log"
 	"os"
+
+	"github.com/SEB534542/gocookbook/recipes"
 )
 
 // Folders and file names used for config.
@@ -10,7 +12,11 @@ var (
 	folderConfig   = "./config/"
 	fnameRcps      = folderConfig + "recipes.json"
 	fnameConvTable = folderConfig + "conversion.json"
+	fnameUnits     = folderConfig + "units.json"
+	fnameFetch     = folderConfig + "fetch.json"
+	fnameTags      = folderConfig + "tags.json"
 	fnameUsers     = "users.json"
+	folderPhotos   = folderConfig + "photos/"
 	folderLog      = "./log/"
 	fnameLog       = folderLog + "logfile.log"
 )
@@ -37,15 +43,53 @@ func main() {
 	log.SetOutput(f)
 	log.Println("--------Start of program--------")
 
-	// Load recipes
-	err = readJSON(&rcps, fnameRcps)
-	if err != nil {
+	// Load recipes and conversion table, migrating files in a previous version
+	if err := loadVersioned(gocookbook.RecipesSchema, &rcps, fnameRcps); err != nil {
 		log.Println(err)
 	}
-	// Load conversion table
-	err = readJSON(&convTable, fnameConvTable)
-	if err != nil {
+	if err := loadVersioned(gocookbook.ConversionSchema, &gocookbook.Densities, fnameConvTable); err != nil {
 		log.Println(err)
 	}
+	rcpIndex.Reset(rcps)
+	// Load settings of tags (optional)
+	if _, err := os.Stat(fnameTags); err == nil {
+		if err := loadVersioned(gocookbook.TagsSchema, &gocookbook.TagTree, fnameTags); err != nil {
+			log.Println(err)
+		}
+	}
+	// Load additional units (optional)
+	if _, err := os.Stat(fnameUnits); err == nil {
+		if err := gocookbook.Registry.Load(fnameUnits); err != nil {
+			log.Println(err)
+		}
+	}
+	// Load settings for fetching recipes from websites (optional)
+	if _, err := os.Stat(fnameFetch); err == nil {
+		if err := readJSON(fetcher, fnameFetch); err != nil {
+			log.Println(err)
+		}
+	}
 	startServer(8081)
 }
+
+/*
+loadVersioned loads the file fname with data of schema s into v. A file in a
+previous version is backed up and migrated to the current version.
+*/
+func loadVersioned(s gocookbook.Schema, v interface{}, fname string) error {
+	version, err := s.Load(fname, v)
+	if err != nil {
+		return err
+	}
+	for _, m := range s.Migrations[version:] {
+		log.Printf("Migrated '%v' to the next version: %v", fname, m.Description)
+	}
+	return nil
+}
+
+/* saveVersioned stores v into the file fname, with the current version of schema s.*/
+func saveVersioned(s gocookbook.Schema, v interface{}, fname string) {
+	if err := s.Save(fname, v); err != nil {
+		log.Fatal("Error saving JSON:", err)
+	}
+}
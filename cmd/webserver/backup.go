@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SEB534542/gocookbook/recipes"
+)
+
+// Restore modes.
+const (
+	restoreMerge   = "merge"   // Merge the backup with the current data.
+	restoreReplace = "replace" // Replace the current data with the backup.
+)
+
+// restoreAction represents what a restore does (or would do, in a dry run) with a file in the backup.
+type restoreAction struct {
+	Name   string // Path of the file.
+	Action string // Description of the action.
+}
+
+/*
+backupFiles returns all files that are part of a backup: everything in the config
+folder (recipes, conversion table, users, settings and photos) and the visits.
+*/
+func backupFiles() (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.Walk(filepath.Clean(folderConfig), func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(p)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if data, err := os.ReadFile(fnameVisits); err == nil {
+		files[filepath.ToSlash(filepath.Clean(fnameVisits))] = data
+	}
+	return files, nil
+}
+
+/* restorable returns true if the file with path name can be restored from a backup.*/
+func restorable(name string) bool {
+	return strings.HasPrefix(name, filepath.ToSlash(filepath.Clean(folderConfig))+"/") ||
+		name == filepath.ToSlash(filepath.Clean(fnameVisits))
+}
+
+/* handlerBackup downloads a backup of all data. Only available for admins.*/
+func handlerBackup(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	if !dbUsers.IsAdmin(currentUser(req)) {
+		http.Redirect(w, req, "/", http.StatusSeeOther)
+		return
+	}
+	files, err := backupFiles()
+	if err != nil {
+		http.Error(w, "Error creating backup: "+fmt.Sprint(err), http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := gocookbook.WriteBackup(&buf, files); err != nil {
+		http.Error(w, "Error creating backup: "+fmt.Sprint(err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Backup downloaded by %v", currentUser(req))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"gocookbook-%v.zip\"", time.Now().Format("20060102-1504")))
+	w.Write(buf.Bytes())
+}
+
+/*
+handlerRestore restores a backup, either merged with the current data or
+replacing it. With a dry run the backup is only validated and the actions that
+would be taken are shown. Only available for admins.
+*/
+func handlerRestore(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	if !dbUsers.IsAdmin(currentUser(req)) {
+		http.Redirect(w, req, "/", http.StatusSeeOther)
+		return
+	}
+	var msgs []string
+	var actions []restoreAction
+	var report *gocookbook.RestoreReport
+	if req.Method == http.MethodPost {
+		dryRun := req.PostFormValue("DryRun") == "true"
+		mode := req.PostFormValue("Mode")
+		var err error
+		actions, report, err = restore(req, mode, dryRun)
+		switch {
+		case err != nil:
+			msgs = append(msgs, fmt.Sprintf("Herstellen mislukt: %v", err))
+		case dryRun:
+			msgs = append(msgs, "Backup is geldig. Proefrun: er is niets gewijzigd.")
+		default:
+			msgs = append(msgs, "Backup is hersteld.")
+			log.Printf("Backup restored (%v) by %v", mode, currentUser(req))
+		}
+	}
+	data := struct {
+		Msgs    []string
+		Actions []restoreAction
+		Report  *gocookbook.RestoreReport
+	}{
+		msgs,
+		actions,
+		report,
+	}
+	err := tpl.ExecuteTemplate(w, "backup.gohtml", data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/*
+restore reads the uploaded backup and restores it in the given mode. It returns
+the action per file and, when merging, the report of merging the recipes. If
+dryRun is true nothing is written. Otherwise all files are first written to
+temporary files, so if writing fails the current data is kept.
+*/
+func restore(req *http.Request, mode string, dryRun bool) ([]restoreAction, *gocookbook.RestoreReport, error) {
+	if mode != restoreMerge && mode != restoreReplace {
+		return nil, nil, fmt.Errorf("unknown mode '%v'", mode)
+	}
+	f, _, err := req.FormFile("File")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, files, err := gocookbook.ReadBackup(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var actions []restoreAction
+	var report *gocookbook.RestoreReport
+	write := map[string][]byte{}
+	for _, name := range names {
+		if !restorable(name) {
+			actions = append(actions, restoreAction{name, "overgeslagen (onbekende locatie)"})
+			continue
+		}
+		_, errExists := os.Stat(filepath.FromSlash(name))
+		exists := errExists == nil
+		switch {
+		case !exists:
+			write[name] = files[name]
+			actions = append(actions, restoreAction{name, "nieuw"})
+		case mode == restoreReplace:
+			write[name] = files[name]
+			actions = append(actions, restoreAction{name, "vervangen"})
+		default:
+			merged, rr, err := mergeFile(name, files[name])
+			switch {
+			case err != nil:
+				return nil, nil, fmt.Errorf("%v: %w", name, err)
+			case merged == nil:
+				actions = append(actions, restoreAction{name, "overgeslagen (bestaat al)"})
+			default:
+				write[name] = merged
+				actions = append(actions, restoreAction{name, "samengevoegd"})
+			}
+			if rr != nil {
+				report = rr
+			}
+		}
+	}
+	if dryRun {
+		return actions, report, nil
+	}
+	paths := map[string][]byte{}
+	for name, data := range write {
+		p := filepath.FromSlash(name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return actions, report, err
+		}
+		paths[p] = data
+	}
+	if err := gocookbook.WriteFiles(paths); err != nil {
+		return actions, report, err
+	}
+	reload()
+	return actions, report, nil
+}
+
+/*
+mergeFile takes the path and contents of a file in a backup and returns the
+contents merged with the current data. For recipes it also returns the report of
+merging. It returns nil if the file cannot be merged and the current file is kept.
+*/
+func mergeFile(name string, data []byte) ([]byte, *gocookbook.RestoreReport, error) {
+	switch name {
+	case filepath.ToSlash(filepath.Clean(fnameRcps)):
+		var restored gocookbook.Cookbook
+		if err := gocookbook.RecipesSchema.Unmarshal(data, &restored); err != nil {
+			return nil, nil, err
+		}
+		merged, rr := gocookbook.MergeRecipes(gocookbook.Cookbook(rcps), restored)
+		b, err := gocookbook.RecipesSchema.Marshal(merged)
+		return b, &rr, err
+	case filepath.ToSlash(filepath.Clean(fnameConvTable)):
+		var restored gocookbook.DensityTable
+		if err := gocookbook.ConversionSchema.Unmarshal(data, &restored); err != nil {
+			return nil, nil, err
+		}
+		merged := gocookbook.DensityTable{}
+		for k, v := range restored {
+			merged[k] = v
+		}
+		for k, v := range gocookbook.Densities {
+			merged[k] = v
+		}
+		b, err := gocookbook.ConversionSchema.Marshal(merged)
+		return b, nil, err
+	case filepath.ToSlash(filepath.Clean(fnameTags)):
+		var restored gocookbook.TagTable
+		if err := gocookbook.TagsSchema.Unmarshal(data, &restored); err != nil {
+			return nil, nil, err
+		}
+		merged := gocookbook.TagTable{}
+		for k, v := range restored {
+			merged[k] = v
+		}
+		for k, v := range gocookbook.TagTree {
+			merged[k] = v
+		}
+		b, err := gocookbook.TagsSchema.Marshal(merged)
+		return b, nil, err
+	case filepath.ToSlash(filepath.Clean(folderConfig + fnameUsers)):
+		restored := map[string]user{}
+		if err := usersSchema.Unmarshal(data, &restored); err != nil {
+			return nil, nil, err
+		}
+		for k, v := range dbUsers.Uns {
+			restored[k] = v
+		}
+		b, err := usersSchema.Marshal(restored)
+		return b, nil, err
+	}
+	return nil, nil, nil
+}
+
+/* reload loads all data again from disk, after a restore.*/
+func reload() {
+	if err := loadVersioned(gocookbook.RecipesSchema, &rcps, fnameRcps); err != nil {
+		log.Println(err)
+	}
+	if err := loadVersioned(gocookbook.ConversionSchema, &gocookbook.Densities, fnameConvTable); err != nil {
+		log.Println(err)
+	}
+	rcpIndex.Reset(rcps)
+	tt := gocookbook.TagTable{}
+	if _, err := os.Stat(fnameTags); err == nil {
+		if err := loadVersioned(gocookbook.TagsSchema, &tt, fnameTags); err != nil {
+			log.Println(err)
+		}
+	}
+	gocookbook.TagTree = tt
+	loadUsers(folderConfig + fnameUsers)
+	if _, err := os.Stat(fnameUnits); err == nil {
+		if err := gocookbook.Registry.Load(fnameUnits); err != nil {
+			log.Println(err)
+		}
+	}
+	if _, err := os.Stat(fnameFetch); err == nil {
+		if err := readJSON(fetcher, fnameFetch); err != nil {
+			log.Println(err)
+		}
+	}
+	if err := loadVersioned(visitsSchema, &dbVisits, fnameVisits); err != nil {
+		log.Println(err)
+	}
+}
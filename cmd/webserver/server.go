@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -40,18 +46,29 @@ var (
 		"fseconds":          seconds,
 		"fdate":             dateTime,
 		"fplusOne":          plusOne,
+		"fcatalogue":        catalogueMatch,
+		"fpercent":          percent,
+		"ftagColor":         tagColor,
+		"ftagsWithParents":  tagsWithParents,
 	} // Map with all functions that can be used within html.
 	dbSessions = map[string]string{} // session ID, username
 	dbUsers    = Users{}
-	dbVisits   = []visit{} // Visits to this website.
+	dbVisits   = []visit{}                              // Visits to this website.
+	fetcher    = gocookbook.NewFetcher()                // Fetches recipes from websites.
+	photos     = gocookbook.NewPhotoStore(folderPhotos) // Stores photos of recipes.
+	rcpIndex   = gocookbook.NewIndex(nil)               // Full-text index of all recipes, used to search.
 )
 
+// visitsSchema is the schema of the visits file, which has no previous versions.
+var visitsSchema = gocookbook.Schema{Kind: "visits"}
+
 const cookieSession = "session"
 
 var (
-	maxIngrs = 30 // Maximum amount of Ingredients that can be added on webpage.
-	maxSteps = 20 // Maximum amount of Steps that can be added on webpage.
-	convRows = 10 // Rows where additional conversion data can be added.
+	maxIngrs = 30               // Maximum amount of Ingredients that can be added on webpage.
+	maxSteps = 20               // Maximum amount of Steps that can be added on webpage.
+	maxForm  = int64(100 << 20) // Maximum size of a submitted recipe form, including photos.
+	convRows = 10               // Rows where additional conversion data can be added.
 )
 
 func init() {
@@ -71,7 +88,7 @@ func startServer(port int) {
 	// load Users
 	loadUsers(folderConfig + fnameUsers)
 	// load visits
-	err := readJSON(&dbVisits, fnameVisits)
+	err := loadVersioned(visitsSchema, &dbVisits, fnameVisits)
 	if err != nil {
 		log.Printf("Unable to load previous visits from '%v': %v", fnameVisits, err)
 	}
@@ -80,14 +97,29 @@ func startServer(port int) {
 	key := ""  // address of key file
 	log.Printf("Launching website at localhost:%v", port)
 	http.HandleFunc("/", handlerMain)
+	http.HandleFunc("/api/search", handlerAPISearch)
+	http.HandleFunc("/search/save", handlerSaveSearch)
+	http.HandleFunc("/search/delete", handlerDeleteSearch)
 	http.Handle("/favicon.ico", http.NotFoundHandler())
 	http.HandleFunc("/recipe/", handlerRecipe)
 	http.HandleFunc("/edit/", handlerEditRcp)
+	http.HandleFunc("/photo/", handlerPhoto)
 	http.HandleFunc("/add", handlerAddRcp)
+	http.HandleFunc("/preview", handlerPreview)
+	http.HandleFunc("/import", handlerImport)
+	http.HandleFunc("/import/file", handlerImportFile)
+	http.HandleFunc("/import/dir", handlerImportDir)
 	http.HandleFunc("/delete/", handlerDelete)
 	http.HandleFunc("/conv", handlerConversion)
 	http.HandleFunc("/export/recipes", handlerExportRcps)
 	http.HandleFunc("/export/table", handlerExportTable)
+	http.HandleFunc("/export/jsonld", handlerExportJSONLD)
+	http.HandleFunc("/export/file", handlerExportFile)
+	http.HandleFunc("/cookbook", handlerCookbook)
+	http.HandleFunc("/pantry", handlerPantry)
+	http.HandleFunc("/tags", handlerTags)
+	http.HandleFunc("/backup", handlerRestore)
+	http.HandleFunc("/backup/download", handlerBackup)
 	http.HandleFunc("/log/", handlerLog)
 	http.HandleFunc("/login", handlerLogin)
 	http.HandleFunc("/profile", handlerProfile)
@@ -274,27 +306,34 @@ recipes, ingrediënts or tags.
 */
 func handlerMain(w http.ResponseWriter, req *http.Request) {
 	addVisit(req)
-	var cb gocookbook.Cookbook
-	var item string
-	// check if results need to be filtered on an item that is posted
-	if req.Method == http.MethodPost {
-		item = strings.Trim(req.PostFormValue("Item"), " ")
-		cb = findIngr(rcps, item)
-	} else {
-		cb = rcps
+	var results []gocookbook.SearchResult
+	var msg string
+	// check if results need to be filtered on a query that is posted, or opened from a saved search
+	item := strings.Trim(req.FormValue("Item"), " ")
+	if item != "" {
+		var err error
+		if results, err = rcpIndex.Query(item); err != nil {
+			msg = fmt.Sprint(err)
+		}
 	}
 	data := struct {
-		Recipes []Recipe
-		Tags    []string
-		Known   bool
-		Admin   bool
-		Item    string
+		Recipes  []Recipe
+		Results  []gocookbook.SearchResult
+		Tags     []string
+		Known    bool
+		Admin    bool
+		Item     string
+		Msg      string
+		Searches []savedSearch
 	}{
-		cb,
+		rcps,
+		results,
 		tags(rcps),
 		alreadyLoggedIn(req),
 		dbUsers.IsAdmin(currentUser(req)),
 		item,
+		msg,
+		dbUsers.Searches(currentUser(req)),
 	}
 	err := tpl.ExecuteTemplate(w, "index.gohtml", data)
 	if err != nil {
@@ -302,6 +341,79 @@ func handlerMain(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+/*
+handlerAPISearch searches the recipes with the query in parameter q, e.g.
+/api/search?q=tag:soep+time:<30m, and returns the results as JSON. The
+query language is the same as on the main page.
+*/
+func handlerAPISearch(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if !alreadyLoggedIn(req) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"Error": "not logged in"})
+		return
+	}
+	results, err := rcpIndex.Query(req.FormValue("q"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+	type apiResult struct {
+		Id       int
+		Name     string
+		Tags     []string
+		Dur      time.Duration
+		Portions float64
+		Score    float64
+		Field    string                   `json:",omitempty"`
+		Snippet  []gocookbook.SnippetPart `json:",omitempty"`
+		Link     string
+	}
+	output := make([]apiResult, len(results))
+	for i, r := range results {
+		output[i] = apiResult{r.Recipe.Id, r.Recipe.Name, r.Recipe.Tags, r.Recipe.Dur, r.Recipe.Portions, r.Score,
+			r.Field, r.Snippet, fmt.Sprintf("/recipe/%v", r.Recipe.Id)}
+	}
+	if err := json.NewEncoder(w).Encode(output); err != nil {
+		log.Println(err)
+	}
+}
+
+/* handlerSaveSearch stores the posted query under the posted name for the current user.*/
+func handlerSaveSearch(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	if req.Method == http.MethodPost {
+		q := strings.Trim(req.PostFormValue("Query"), " ")
+		name := strings.Trim(req.PostFormValue("Name"), " ")
+		if name == "" {
+			name = q
+		}
+		if _, err := gocookbook.ParseQuery(q); err == nil && q != "" {
+			dbUsers.SaveSearch(currentUser(req), name, q)
+		}
+	}
+	http.Redirect(w, req, "/", http.StatusSeeOther)
+}
+
+/* handlerDeleteSearch removes the saved search with the posted name for the current user.*/
+func handlerDeleteSearch(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	if req.Method == http.MethodPost {
+		dbUsers.RemoveSearch(currentUser(req), req.PostFormValue("Name"))
+	}
+	http.Redirect(w, req, "/", http.StatusSeeOther)
+}
+
 /* handlerExportRcps prints all recipes in JSON on the webpage.*/
 func handlerExportRcps(w http.ResponseWriter, req *http.Request) {
 	addVisit(req)
@@ -317,6 +429,218 @@ func handlerExportRcps(w http.ResponseWriter, req *http.Request) {
 	fmt.Fprintf(w, output)
 }
 
+/* handlerExportJSONLD prints all recipes as schema.org JSON-LD on the webpage.*/
+func handlerExportJSONLD(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	output, err := gocookbook.ExportJSONLD(rcps...)
+	if err != nil {
+		msg := "Error exporting:" + fmt.Sprint(err)
+		http.Error(w, msg, http.StatusExpectationFailed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ld+json; charset=utf-8")
+	w.Write(output)
+}
+
+/* handlerExportFile downloads all recipes in the format of another recipe manager.*/
+func handlerExportFile(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	f := gocookbook.FileFormat(req.URL.Query().Get("format"))
+	var buf bytes.Buffer
+	if err := gocookbook.Export(f, &buf, rcps); err != nil {
+		msg := "Error exporting:" + fmt.Sprint(err)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"recepten%v\"", f.Extension(len(rcps))))
+	w.Write(buf.Bytes())
+}
+
+/*
+handlerCookbook shows the page to select recipes for a cookbook, by tag and/or
+by recipe, and downloads the cookbook as PDF.
+*/
+func handlerCookbook(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	var msg string
+	if req.Method == http.MethodPost {
+		req.ParseForm()
+		selected := []Recipe{}
+		for _, rcp := range rcps {
+			if containsString(req.PostForm["Tag"], rcp.Tags...) || containsString(req.PostForm["Id"], strconv.Itoa(rcp.Id)) {
+				selected = append(selected, rcp)
+			}
+		}
+		title := strings.TrimSpace(req.PostFormValue("Title"))
+		if title == "" {
+			title = "Kookboek"
+		}
+		if len(selected) > 0 {
+			var buf bytes.Buffer
+			if err := gocookbook.CookbookPDF(&buf, title, selected, dbUsers.Format(currentUser(req))); err != nil {
+				http.Error(w, "Error creating PDF: "+fmt.Sprint(err), http.StatusInternalServerError)
+				return
+			}
+			log.Printf("Cookbook '%v' with %v recipes created by %v", title, len(selected), currentUser(req))
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%v.pdf", url.PathEscape(title)))
+			w.Write(buf.Bytes())
+			return
+		}
+		msg = "Selecteer ten minste één tag of recept."
+	}
+	data := struct {
+		Msg     string
+		Tags    []string
+		Recipes []Recipe
+	}{
+		msg,
+		tags(rcps),
+		rcps,
+	}
+	err := tpl.ExecuteTemplate(w, "cookbook.gohtml", data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/*
+handlerPantry shows the pantry of the current user and the recipes that can be
+made with it, ranked by the share of their ingredients that are in the pantry.
+A posted pantry is stored for the user first.
+*/
+func handlerPantry(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	un := currentUser(req)
+	if req.Method == http.MethodPost {
+		dbUsers.SetPantry(un, gocookbook.ParsePantry(req.PostFormValue("Pantry")))
+	}
+	portions, _ := strconv.ParseFloat(req.FormValue("Portions"), 64)
+	if math.IsNaN(portions) || math.IsInf(portions, 0) || portions < 0 {
+		portions = 0
+	}
+	pantry := dbUsers.Pantry(un)
+	data := struct {
+		Pantry   string
+		Portions float64
+		Matches  []gocookbook.PantryMatch
+		Format   gocookbook.AmountFormat
+	}{
+		pantry.Text(),
+		portions,
+		pantry.Match(gocookbook.Cookbook(rcps), portions),
+		dbUsers.Format(un),
+	}
+	err := tpl.ExecuteTemplate(w, "pantry.gohtml", data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/*
+handlerTags shows all tags with the number of recipes for each tag, and allows
+admins to rename and merge tags across all recipes and to set the parent and
+colour of a tag. The changes are made on copies of the recipes and tags, which
+only replace the current data once they are saved. When merging, recipes and
+tags are saved together: if either file cannot be written, neither is changed.
+*/
+func handlerTags(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	if !dbUsers.IsAdmin(currentUser(req)) {
+		http.Redirect(w, req, "/", http.StatusSeeOther)
+		return
+	}
+	var msg string
+	if req.Method == http.MethodPost {
+		req.ParseForm()
+		switch {
+		case req.PostFormValue("Merge") != "":
+			from, to := req.PostForm["Tag"], toTitle(strings.TrimSpace(req.PostFormValue("To")))
+			cb, tt, err := gocookbook.TagTree.Merge(gocookbook.Cookbook(rcps), from, to)
+			if err != nil {
+				msg = fmt.Sprint(err)
+				break
+			}
+			err = gocookbook.SaveAll(
+				gocookbook.SchemaFile{Schema: gocookbook.RecipesSchema, Name: fnameRcps, Data: cb},
+				gocookbook.SchemaFile{Schema: gocookbook.TagsSchema, Name: fnameTags, Data: tt},
+			)
+			if err != nil {
+				log.Printf("Unable to save merged tags %v: %v", from, err)
+				msg = "Tags niet samengevoegd: " + fmt.Sprint(err)
+				break
+			}
+			rcps, gocookbook.TagTree = cb, tt
+			rcpIndex.Reset(rcps)
+			log.Printf("Tags %v merged into '%v' by %v", from, to, currentUser(req))
+			msg = fmt.Sprintf("%v samengevoegd tot '%v'", strings.Join(from, ", "), to)
+		case req.PostFormValue("Name") != "":
+			name := req.PostFormValue("Name")
+			tag := gocookbook.Tag{Parent: req.PostFormValue("Parent")}
+			if req.PostFormValue("NoColor") == "" {
+				tag.Color = req.PostFormValue("Color")
+			}
+			tt, err := gocookbook.TagTree.Set(name, tag)
+			if err != nil {
+				msg = fmt.Sprint(err)
+				break
+			}
+			if err := gocookbook.TagsSchema.Save(fnameTags, tt); err != nil {
+				log.Printf("Unable to save tag '%v': %v", name, err)
+				msg = fmt.Sprintf("'%v' niet opgeslagen: %v", name, err)
+				break
+			}
+			gocookbook.TagTree = tt
+			log.Printf("Tag '%v' updated by %v", name, currentUser(req))
+			msg = fmt.Sprintf("'%v' opgeslagen", name)
+		}
+	}
+	data := struct {
+		Msg  string
+		Tags []gocookbook.TagCount
+	}{
+		msg,
+		gocookbook.TagTree.List(gocookbook.Cookbook(rcps)),
+	}
+	err := tpl.ExecuteTemplate(w, "tags.gohtml", data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/* containsString returns true if xs contains any of the strings in ys.*/
+func containsString(xs []string, ys ...string) bool {
+	for _, x := range xs {
+		for _, y := range ys {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 /* handlerExportTable prints the conversion table in JSON on the webpage.*/
 func handlerExportTable(w http.ResponseWriter, req *http.Request) {
 	addVisit(req)
@@ -324,7 +648,7 @@ func handlerExportTable(w http.ResponseWriter, req *http.Request) {
 		http.Redirect(w, req, "/login", http.StatusSeeOther)
 		return
 	}
-	output, err := jsonStringPretty(convTable)
+	output, err := jsonStringPretty(gocookbook.Densities)
 	if err != nil {
 		msg := "Error saving:" + fmt.Sprint(err)
 		http.Error(w, msg, http.StatusExpectationFailed)
@@ -339,7 +663,9 @@ the new number of persons and it sends the response back.
 */
 func handlerRecipe(w http.ResponseWriter, req *http.Request) {
 	addVisit(req)
-	id, err := strconv.Atoi(req.URL.Path[len("/recipe/"):])
+	path := req.URL.Path[len("/recipe/"):]
+	ext := filepath.Ext(path)
+	id, err := strconv.Atoi(strings.TrimSuffix(path, ext))
 	if err != nil {
 		http.Redirect(w, req, "/", http.StatusBadRequest)
 		return
@@ -349,21 +675,66 @@ func handlerRecipe(w http.ResponseWriter, req *http.Request) {
 		http.Redirect(w, req, "/", http.StatusNotFound)
 		return
 	}
-	if req.Method == http.MethodPost {
-		if persons, err := strconv.ParseFloat(req.PostFormValue("Portions"), 64); err == nil {
-			rcp = adjustRcp(rcp, persons)
+	schema, err := gocookbook.ExportJSONLD(rcp)
+	if err != nil {
+		log.Println(err)
+	}
+	switch ext {
+	case ".jsonld":
+		w.Header().Set("Content-Type", "application/ld+json; charset=utf-8")
+		w.Write(schema)
+		return
+	case ".md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		io.WriteString(w, rcp.Markdown())
+		return
+	case ".pdf":
+		// PDF of the recipe as shown, scaled to the portions in the link
+		if p := req.URL.Query().Get("portions"); p != "" {
+			persons, err := strconv.ParseFloat(p, 64)
+			if err == nil {
+				rcp, err = adjustRcp(rcp, persons)
+			}
+			if err != nil {
+				http.Error(w, "Invalid portions: "+fmt.Sprint(err), http.StatusBadRequest)
+				return
+			}
+		}
+		rcp = rcp.ConvertTo(dbUsers.System(currentUser(req)))
+		var buf bytes.Buffer
+		if err := gocookbook.RecipePDF(&buf, rcp, dbUsers.Format(currentUser(req))); err != nil {
+			http.Error(w, "Error creating PDF: "+fmt.Sprint(err), http.StatusInternalServerError)
+			return
 		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"recept-%v.pdf\"", rcp.Id))
+		w.Write(buf.Bytes())
+		return
+	}
+	var msg string
+	if req.Method == http.MethodPost {
+		rcp, msg = adjustRecipe(req, rcp, dbUsers.System(currentUser(req)))
 	}
 	// Include/update alternate UOMs
 	for i, _ := range rcp.Ingrs {
 		rcp.Ingrs[i].uoms()
 	}
+	// Convert to the preferred system of measurement of the user
+	rcp = rcp.ConvertTo(dbUsers.System(currentUser(req)))
 	data := struct {
 		Recipe Recipe
 		Known  bool
+		Msg    string
+		Shapes []gocookbook.Shape
+		Format gocookbook.AmountFormat
+		JSONLD template.JS
 	}{
 		rcp,
 		alreadyLoggedIn(req),
+		msg,
+		gocookbook.Shapes,
+		dbUsers.Format(currentUser(req)),
+		template.JS(schema),
 	}
 	err = tpl.ExecuteTemplate(w, "recipe.gohtml", data)
 	if err != nil {
@@ -371,6 +742,55 @@ func handlerRecipe(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+/*
+adjustRecipe adjusts the recipe to the portions, the amount of an ingredient or
+the pan that are posted in req, and returns it together with a message if the
+recipe cannot be adjusted. The amount of an ingredient is in the unit it is
+shown in for system s, the preferred system of the user.
+*/
+func adjustRecipe(req *http.Request, rcp Recipe, s gocookbook.System) (Recipe, string) {
+	var msg string
+	switch req.PostFormValue("Mode") {
+	case "ingredient":
+		i, _ := strconv.Atoi(req.PostFormValue("Ingrd"))
+		amount, err := strconv.ParseFloat(req.PostFormValue("Amount"), 64)
+		if err == nil {
+			var r Recipe
+			if r, err = adjustRcpByIngrd(rcp, i, amount, s); err == nil {
+				rcp = r
+			}
+		}
+		if err != nil {
+			msg = "Recept niet aangepast op basis van ingrediënt: " + fmt.Sprint(err)
+		}
+	case "pan":
+		width, _ := strconv.ParseFloat(req.PostFormValue("PanWidth"), 64)
+		length, _ := strconv.ParseFloat(req.PostFormValue("PanLength"), 64)
+		p, err := gocookbook.NewPan(gocookbook.Shape(req.PostFormValue("PanShape")), width, length)
+		if err == nil {
+			var r Recipe
+			if r, err = adjustRcpByPan(rcp, p); err == nil {
+				rcp = r
+			}
+		}
+		if err != nil {
+			msg = "Recept niet aangepast aan bakvorm: " + fmt.Sprint(err)
+		}
+	default:
+		persons, err := strconv.ParseFloat(req.PostFormValue("Portions"), 64)
+		if err == nil {
+			var r Recipe
+			if r, err = adjustRcp(rcp, persons); err == nil {
+				rcp = r
+			}
+		}
+		if err != nil {
+			msg = "Aantal porties niet aangepast: " + fmt.Sprint(err)
+		}
+	}
+	return rcp, msg
+}
+
 /*
 handlerAddRcp generates the html page to enter a new recipe and processes and
 stores the new recipe.
@@ -382,11 +802,20 @@ func handlerAddRcp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	if req.Method == http.MethodPost {
-		rcp := processNewRcp(req)
+		var rcp Recipe
+		if previewed, _ := strconv.ParseBool(req.PostFormValue("Previewed")); previewed {
+			// Ingredients and steps are already parsed and checked in the preview. Ingredients
+			// without amount, e.g. "zout en peper", are kept like processNewRcp does.
+			rcp = processRcp(req)
+			rcp.Ingrs = gocookbook.PreviewIngrds(req.PostFormValue, maxIngrs)
+		} else {
+			rcp = processNewRcp(req)
+		}
 		rcp.Id = newRcpId(rcps)
 		rcps = append(rcps, rcp)
+		rcpIndex.Add(rcp)
 		sort.Slice(rcps, func(i, j int) bool { return rcps[i].Name < rcps[j].Name })
-		SaveToJSON(rcps, fnameRcps)
+		saveVersioned(gocookbook.RecipesSchema, rcps, fnameRcps)
 		log.Printf("New recipe added (id %v", rcp.Id)
 		http.Redirect(w, req, fmt.Sprintf("edit/%v", rcp.Id), http.StatusSeeOther)
 		return
@@ -396,11 +825,71 @@ func handlerAddRcp(w http.ResponseWriter, req *http.Request) {
 		CountIngrs []int
 		CountSteps []int
 		Units      []string
+		Shapes     []gocookbook.Shape
+		Preview    bool
+		Results    []gocookbook.ParseResult
 	}{
 		Recipe{},
 		rangeList(0, maxIngrs),
 		rangeList(0, maxSteps),
-		units,
+		gocookbook.Registry.Units(),
+		gocookbook.Shapes,
+		false,
+		nil,
+	}
+	err := tpl.ExecuteTemplate(w, "add.gohtml", data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/*
+handlerPreview parses the ingredients and steps that are entered as text on the
+page for a new recipe and shows the result as editable table, so any mistakes
+can be corrected before the recipe is stored.
+*/
+func handlerPreview(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Redirect(w, req, "/add", http.StatusSeeOther)
+		return
+	}
+	rcp := processNewRcp(req)
+	results := gocookbook.ParseIngrds(req.PostFormValue("Ingrds"))
+	renderPreview(w, rcp, results)
+}
+
+/*
+renderPreview shows the page for a new recipe with the parsed ingredients and
+steps of rcp as editable table.
+*/
+func renderPreview(w http.ResponseWriter, rcp Recipe, results []gocookbook.ParseResult) {
+	if len(results) > maxIngrs {
+		results = results[:maxIngrs]
+	}
+	if len(rcp.Steps) > maxSteps {
+		rcp.Steps = rcp.Steps[:maxSteps]
+	}
+	data := struct {
+		Recipe
+		CountIngrs []int
+		CountSteps []int
+		Units      []string
+		Shapes     []gocookbook.Shape
+		Preview    bool
+		Results    []gocookbook.ParseResult
+	}{
+		rcp,
+		rangeList(len(results), maxIngrs),
+		rangeList(len(rcp.Steps), maxSteps),
+		gocookbook.Registry.Units(),
+		gocookbook.Shapes,
+		true,
+		results,
 	}
 	err := tpl.ExecuteTemplate(w, "add.gohtml", data)
 	if err != nil {
@@ -408,6 +897,149 @@ func handlerAddRcp(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+/*
+handlerImport imports a recipe from a recipe website, either fetched from the
+URL or from the page source that is uploaded as file or pasted as text, and
+shows it as preview so it can be checked before it is stored.
+*/
+func handlerImport(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	var msg string
+	if req.Method == http.MethodPost {
+		var rcp Recipe
+		var results []gocookbook.ParseResult
+		var err error
+		if link := req.PostFormValue("Url"); link != "" {
+			rcp, results, err = fetcher.Fetch(link)
+		} else {
+			var r io.Reader = strings.NewReader(req.PostFormValue("Html"))
+			if f, _, errFile := req.FormFile("File"); errFile == nil {
+				defer f.Close()
+				r = f
+			}
+			rcp, results, err = gocookbook.ImportHTML(r)
+		}
+		if err == nil {
+			for i, v := range rcp.Tags {
+				rcp.Tags[i] = toTitle(v)
+			}
+			sort.Strings(rcp.Tags)
+			log.Printf("Recipe %v imported", rcp.Name)
+			renderPreview(w, rcp, results)
+			return
+		}
+		msg = fmt.Sprintf("Importeren mislukt: %v", err)
+	}
+	renderImport(w, req, msg, nil)
+}
+
+/*
+handlerImportFile imports all recipes from an export of another recipe manager,
+stores them and shows a report of the import.
+*/
+func handlerImportFile(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Redirect(w, req, "/import", http.StatusSeeOther)
+		return
+	}
+	f, _, err := req.FormFile("File")
+	if err != nil {
+		renderImport(w, req, fmt.Sprintf("Importeren mislukt: %v", err), nil)
+		return
+	}
+	defer f.Close()
+	imported, report, err := gocookbook.Import(gocookbook.FileFormat(req.PostFormValue("Format")), f)
+	if err != nil {
+		renderImport(w, req, fmt.Sprintf("Importeren mislukt: %v", err), nil)
+		return
+	}
+	addImported(req, imported)
+	log.Printf("%v recipes imported from %v", report.Recipes, report.Format)
+	renderImport(w, req, "", &report)
+}
+
+/*
+handlerImportDir imports all Markdown recipes in a folder on the server, e.g. a
+git repository with recipes, and shows a report of the import. Only available
+for admins.
+*/
+func handlerImportDir(w http.ResponseWriter, req *http.Request) {
+	addVisit(req)
+	if !alreadyLoggedIn(req) {
+		http.Redirect(w, req, "/login", http.StatusSeeOther)
+		return
+	}
+	if req.Method != http.MethodPost || !dbUsers.IsAdmin(currentUser(req)) {
+		http.Redirect(w, req, "/import", http.StatusSeeOther)
+		return
+	}
+	dir := filepath.Clean(req.PostFormValue("Dir"))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		renderImport(w, req, fmt.Sprintf("Importeren mislukt: '%v' is geen map", dir), nil)
+		return
+	}
+	imported, report, err := gocookbook.ImportMarkdownDir(os.DirFS(dir))
+	if err != nil {
+		renderImport(w, req, fmt.Sprintf("Importeren mislukt: %v", err), nil)
+		return
+	}
+	addImported(req, imported)
+	log.Printf("%v recipes imported from %v by %v", report.Recipes, dir, currentUser(req))
+	renderImport(w, req, "", &report)
+}
+
+/*
+addImported adds the imported recipes with a new Id, created by the current user,
+and stores all recipes.
+*/
+func addImported(req *http.Request, imported []gocookbook.Recipe) {
+	un, t := currentUser(req), time.Now()
+	for _, rcp := range imported {
+		rcp.Id = newRcpId(rcps)
+		for i, v := range rcp.Tags {
+			rcp.Tags[i] = toTitle(v)
+		}
+		sort.Strings(rcp.Tags)
+		rcp.Createdby, rcp.Updatedby = un, un
+		if rcp.Created.IsZero() {
+			rcp.Created = t
+		}
+		rcp.Updated = t
+		rcps = append(rcps, rcp)
+		rcpIndex.Add(rcp)
+	}
+	sort.Slice(rcps, func(i, j int) bool { return rcps[i].Name < rcps[j].Name })
+	saveVersioned(gocookbook.RecipesSchema, rcps, fnameRcps)
+}
+
+/* renderImport shows the page to import recipes with message msg and the report of an import (optional).*/
+func renderImport(w http.ResponseWriter, req *http.Request, msg string, report *gocookbook.ImportReport) {
+	data := struct {
+		Msg     string
+		Formats []gocookbook.FileFormat
+		Report  *gocookbook.ImportReport
+		Admin   bool
+	}{
+		msg,
+		gocookbook.FileFormats,
+		report,
+		dbUsers.IsAdmin(currentUser(req)),
+	}
+	err := tpl.ExecuteTemplate(w, "import.gohtml", data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
 /* handlerDelete deletes the recipe correspondiing to the id given.*/
 func handlerDelete(w http.ResponseWriter, req *http.Request) {
 	addVisit(req)
@@ -420,8 +1052,16 @@ func handlerDelete(w http.ResponseWriter, req *http.Request) {
 		http.Redirect(w, req, "/", http.StatusBadRequest)
 		return
 	}
+	if rcp, err := findRecipe(rcps, id); err == nil {
+		for _, p := range rcp.Photos {
+			if err := photos.Remove(p); err != nil {
+				log.Println(err)
+			}
+		}
+	}
 	rcps = removeRecipe(rcps, id)
-	SaveToJSON(rcps, fnameRcps)
+	rcpIndex.Remove(id)
+	saveVersioned(gocookbook.RecipesSchema, rcps, fnameRcps)
 	log.Printf("Recipe deleted added (id %v)", id)
 	http.Redirect(w, req, "/", http.StatusSeeOther)
 	return
@@ -447,8 +1087,15 @@ func handlerEditRcp(w http.ResponseWriter, req *http.Request) {
 		http.Redirect(w, req, "/", http.StatusNotFound)
 		return
 	}
+	msgs := []string{}
 	if req.Method == http.MethodPost {
+		req.Body = http.MaxBytesReader(w, req.Body, maxForm)
+		if err := req.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+			http.Error(w, fmt.Sprintf("Formulier kan niet worden verwerkt: %v", err), http.StatusRequestEntityTooLarge)
+			return
+		}
 		rcpNew := processRcp(req)
+		msgs = processPhotos(req, *rcp, &rcpNew)
 		// Set CreatedBy and Created back to original creator and datetime (if not the same and not empty).
 		if rcp.Createdby != "" && rcpNew.Createdby != rcp.Createdby {
 			rcpNew.Createdby = rcp.Createdby
@@ -458,21 +1105,33 @@ func handlerEditRcp(w http.ResponseWriter, req *http.Request) {
 		}
 		// Update existing recipe.
 		*rcp = rcpNew
+		rcpIndex.Add(rcpNew)
 		sort.Slice(rcps, func(i, j int) bool { return rcps[i].Name < rcps[j].Name })
-		SaveToJSON(rcps, fnameRcps)
+		saveVersioned(gocookbook.RecipesSchema, rcps, fnameRcps)
 		log.Printf("Recipe %v updated", rcpNew.Id)
-		http.Redirect(w, req, fmt.Sprintf("/recipe/%v", rcpNew.Id), http.StatusSeeOther)
+		if len(msgs) == 0 {
+			http.Redirect(w, req, fmt.Sprintf("/recipe/%v", rcpNew.Id), http.StatusSeeOther)
+			return
+		}
+		// Show the form again with the photos that could not be stored
+		rcp, _ = findRecipeP(rcps, id)
 	}
 	data := struct {
+		Msgs []string
 		Recipe
 		CountIngrs []int
 		CountSteps []int
 		Units      []string
+		Shapes     []gocookbook.Shape
+		Catalogue  []string
 	}{
+		msgs,
 		*rcp,
 		rangeList(len(rcp.Ingrs), maxIngrs),
 		rangeList(len(rcp.Steps), maxSteps),
-		units,
+		gocookbook.Registry.Units(),
+		gocookbook.Shapes,
+		gocookbook.Densities.Names(),
 	}
 	err = tpl.ExecuteTemplate(w, "edit.gohtml", data)
 	if err != nil {
@@ -505,6 +1164,7 @@ func processRcp(req *http.Request) Recipe {
 	rcp.Notes = strings.Trim(req.PostFormValue("Notes"), " ")
 	rcp.Dur, _ = time.ParseDuration(fmt.Sprintf("%vm", req.PostFormValue("Dur")))
 	rcp.Portions, _ = strconv.ParseFloat(req.PostFormValue("Portions"), 64)
+	rcp.Pan = processPan(req)
 
 	t := stringToSlice(req.PostFormValue("Tags"))
 	rcp.Tags = []string{}
@@ -527,9 +1187,15 @@ func processRcp(req *http.Request) Recipe {
 			continue
 		}
 		ingr.Amount = amount
+		ingr.AmountMax, _ = strconv.ParseFloat(req.PostFormValue(fmt.Sprintf("AmountMax%v", i)), 64)
+		ingr.Approx, _ = strconv.ParseBool(req.PostFormValue(fmt.Sprintf("Approx%v", i)))
 		ingr.Unit = req.PostFormValue(fmt.Sprintf("Unit%v", i))
 		ingr.Item = strings.Trim(strings.ToLower(req.PostFormValue(fmt.Sprintf("Item%v", i))), " ") // All items are stored in lowercase.
 		ingr.Notes = strings.Trim(req.PostFormValue(fmt.Sprintf("Notes%v", i)), " ")
+		ingr.Fixed, _ = strconv.ParseBool(req.PostFormValue(fmt.Sprintf("Fixed%v", i)))
+		if d, ok := gocookbook.Densities.Find(req.PostFormValue(fmt.Sprintf("Canonical%v", i))); ok {
+			ingr.Canonical = d.Name
+		}
 		ingrs[id] = ingr
 		ids = append(ids, id)
 	}
@@ -540,23 +1206,10 @@ func processRcp(req *http.Request) Recipe {
 		rcp.Ingrs[i] = ingrs[id]
 	}
 	// Steps
-	// Gather all steps
-	stepIds := []float64{}
-	steps := map[float64]string{}
-	for i := 0; i < maxSteps; i++ {
-		id, _ := strconv.ParseFloat(req.PostFormValue(fmt.Sprintf("StepId%v", i)), 64)
-		step := req.PostFormValue(fmt.Sprintf("Step%v", i))
-		if step == "" {
-			continue
-		}
-		steps[id] = step
-		stepIds = append(stepIds, id)
-	}
-	// Sort and store steps into recipe
-	rcp.Steps = make([]string, len(stepIds))
-	sort.Float64s(stepIds)
-	for i, id := range stepIds {
-		rcp.Steps[i] = steps[id]
+	order := stepOrder(req)
+	rcp.Steps = make([]string, len(order))
+	for i, n := range order {
+		rcp.Steps[n-1] = req.PostFormValue(fmt.Sprintf("Step%v", i))
 	}
 	// Store source and hyperlink
 	rcp.Source = req.PostFormValue("Source")
@@ -583,6 +1236,137 @@ func processRcp(req *http.Request) Recipe {
 	return rcp
 }
 
+/*
+stepOrder takes a *http.Request and returns for each filled in step on the form
+(by its position on the form) the number of the step in the recipe, starting
+at 1. Steps are ordered by the StepId that is entered for them.
+*/
+func stepOrder(req *http.Request) map[int]int {
+	type step struct {
+		i  int
+		id float64
+	}
+	steps := []step{}
+	for i := 0; i < maxSteps; i++ {
+		if req.PostFormValue(fmt.Sprintf("Step%v", i)) == "" {
+			continue
+		}
+		id, _ := strconv.ParseFloat(req.PostFormValue(fmt.Sprintf("StepId%v", i)), 64)
+		steps = append(steps, step{i, id})
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].id < steps[j].id })
+	order := map[int]int{}
+	for n, s := range steps {
+		order[s.i] = n + 1
+	}
+	return order
+}
+
+/*
+processPhotos takes a *http.Request, the recipe as it was stored and the
+updated recipe. It stores the uploaded photos, moves the existing photos along
+with their (reordered) steps and removes photos that are deleted on the form
+or belong to a deleted step. It returns a message for each photo that could not
+be stored.
+*/
+func processPhotos(req *http.Request, old Recipe, rcp *Recipe) []string {
+	order := stepOrder(req)
+	deleted := map[string]bool{}
+	for _, name := range req.PostForm["DeletePhoto"] {
+		deleted[name] = true
+	}
+	used := map[string]bool{}
+	rcp.Photos = []gocookbook.Photo{}
+	for _, p := range old.Photos {
+		if deleted[p.Name] {
+			continue
+		}
+		if p.Step != 0 {
+			n, ok := order[p.Step-1]
+			if !ok {
+				// Step is deleted
+				continue
+			}
+			p.Step = n
+		}
+		rcp.Photos = append(rcp.Photos, p)
+		used[p.Name] = true
+	}
+	msgs := []string{}
+	if req.MultipartForm != nil {
+		uploads := map[string]int{"Photo": 0}
+		for i, n := range order {
+			uploads[fmt.Sprintf("StepPhoto%v", i)] = n
+		}
+		for field, step := range uploads {
+			for _, fh := range req.MultipartForm.File[field] {
+				f, err := fh.Open()
+				if err != nil {
+					msgs = append(msgs, fmt.Sprintf("Foto '%v' is niet toegevoegd: %v", fh.Filename, err))
+					continue
+				}
+				p, err := photos.Add(rcp.Id, step, f)
+				f.Close()
+				if err != nil {
+					msgs = append(msgs, fmt.Sprintf("Foto '%v' is niet toegevoegd: %v", fh.Filename, err))
+					continue
+				}
+				rcp.Photos = append(rcp.Photos, p)
+				used[p.Name] = true
+			}
+		}
+		sort.SliceStable(rcp.Photos, func(i, j int) bool { return rcp.Photos[i].Step < rcp.Photos[j].Step })
+	}
+	// Remove files that are no longer used by the recipe
+	for _, p := range old.Photos {
+		if !used[p.Name] {
+			if err := photos.Remove(p); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	return msgs
+}
+
+/*
+handlerPhoto serves a stored photo of a recipe, e.g.
+/photo/20-1a2b3c4d5e6f-thumb.jpg. Like the recipes themselves, photos can be
+viewed without logging in. As the name of a photo changes when its contents
+change, browsers may cache the photo indefinitely.
+*/
+func handlerPhoto(w http.ResponseWriter, req *http.Request) {
+	path, err := photos.Path(req.URL.Path[len("/photo/"):])
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeContent(w, req, fi.Name(), fi.ModTime(), f)
+}
+
+/*
+processPan takes a *http.Request and extracts the pan from the form POST data.
+If no valid pan is entered, an empty Pan is returned.
+*/
+func processPan(req *http.Request) gocookbook.Pan {
+	width, _ := strconv.ParseFloat(req.PostFormValue("PanWidth"), 64)
+	length, _ := strconv.ParseFloat(req.PostFormValue("PanLength"), 64)
+	p, _ := gocookbook.NewPan(gocookbook.Shape(req.PostFormValue("PanShape")), width, length)
+	return p
+}
+
 /*
 processNewRcp takes a *http.requested and extracts the form POST data
 into a recipe, which is returned.
@@ -596,6 +1380,7 @@ func processNewRcp(req *http.Request) Recipe {
 	rcp.Notes = strings.Trim(req.PostFormValue("Notes"), " ")
 	rcp.Dur, _ = time.ParseDuration(fmt.Sprintf("%vm", req.PostFormValue("Dur")))
 	rcp.Portions, _ = strconv.ParseFloat(req.PostFormValue("Portions"), 64)
+	rcp.Pan = processPan(req)
 
 	t := stringToSlice(req.PostFormValue("Tags"))
 	rcp.Tags = []string{}
@@ -607,9 +1392,13 @@ func processNewRcp(req *http.Request) Recipe {
 	}
 	sort.Strings(rcp.Tags)
 	// Ingredients
-	rcp.Ingrs = textToIngrds(req.PostFormValue("Ingrds"))
+	rcp.Ingrs = gocookbook.TextToIngrds(req.PostFormValue("Ingrds"))
 	// Steps
-	rcp.Steps = textToLines(req.PostFormValue("Steps"))
+	var notes string
+	rcp.Steps, notes = gocookbook.TextToSteps(req.PostFormValue("Steps"))
+	if notes != "" {
+		rcp.Notes = strings.TrimSpace(rcp.Notes + "\n" + notes)
+	}
 	// Store source and hyperlink
 	rcp.Source = req.PostFormValue("Source")
 	rcp.SourceLink = req.PostFormValue("SourceLink")
@@ -646,27 +1435,55 @@ func handlerConversion(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	if req.Method == http.MethodPost {
-		for k, _ := range convTable {
-			if req.PostFormValue(fmt.Sprintf("%v-delete", k)) != "" {
-				delete(convTable, k)
-			} else {
-				convTable[k], _ = strconv.ParseFloat(req.PostFormValue(k), 64)
+		// Rebuild the table from all rows on the page, including the rows for new items
+		rows, _ := strconv.Atoi(req.PostFormValue("Rows"))
+		dt := gocookbook.DensityTable{}
+		for i := 0; i < rows+convRows; i++ {
+			if del, _ := strconv.ParseBool(req.PostFormValue(fmt.Sprintf("Delete%v", i))); del {
+				continue
 			}
-		}
-		for i := 0; i < convRows; i++ {
-			if k := strings.ToLower(req.PostFormValue(fmt.Sprint(i))); k != "" {
-				convTable[k], _ = strconv.ParseFloat(req.PostFormValue(fmt.Sprintf("value-%v", i)), 64)
+			d := gocookbook.Density{
+				Name:     req.PostFormValue(fmt.Sprintf("Name%v", i)),
+				Measured: gocookbook.Unit(req.PostFormValue(fmt.Sprintf("Measured%v", i))),
+				Source:   strings.Trim(req.PostFormValue(fmt.Sprintf("Source%v", i)), " "),
+				Notes:    strings.Trim(req.PostFormValue(fmt.Sprintf("Notes%v", i)), " "),
+			}
+			d.MlPerGram, _ = strconv.ParseFloat(req.PostFormValue(fmt.Sprintf("MlPerGram%v", i)), 64)
+			d.PieceWeight, _ = strconv.ParseFloat(req.PostFormValue(fmt.Sprintf("PieceWeight%v", i)), 64)
+			for _, size := range []gocookbook.Size{gocookbook.Small, gocookbook.Large} {
+				if f, _ := strconv.ParseFloat(req.PostFormValue(fmt.Sprintf("%v%v", size, i)), 64); f > 0 {
+					if d.Sizes == nil {
+						d.Sizes = map[gocookbook.Size]float64{}
+					}
+					d.Sizes[size] = f
+				}
 			}
+			for _, v := range stringToSlice(req.PostFormValue(fmt.Sprintf("Synonyms%v", i))) {
+				if v != "" {
+					d.Synonyms = append(d.Synonyms, strings.ToLower(v))
+				}
+			}
+			dt.Add(d)
 		}
-		SaveToJSON(convTable, fnameConvTable)
+		gocookbook.Densities = dt
+		saveVersioned(gocookbook.ConversionSchema, gocookbook.Densities, fnameConvTable)
+		// Synonyms are part of the index
+		rcpIndex.Reset(rcps)
 	}
 
+	names := gocookbook.Densities.Names()
+	rows := make([]gocookbook.Density, len(names))
+	for i, k := range names {
+		rows[i] = gocookbook.Densities[k]
+	}
 	data := struct {
-		ConvTable map[string]float64
+		Densities []gocookbook.Density
 		AddRows   []int
+		Units     []string
 	}{
-		convTable,
-		rangeList(0, convRows),
+		rows,
+		rangeList(len(rows), len(rows)+convRows),
+		gocookbook.Registry.Units(),
 	}
 	err := tpl.ExecuteTemplate(w, "conversion.gohtml", data)
 	if err != nil {
@@ -830,7 +1647,28 @@ func addVisit(req *http.Request) {
 		Un:   un,
 	}
 	dbVisits = append(dbVisits, v)
-	SaveToJSON(dbVisits, fnameVisits)
+	saveVersioned(visitsSchema, dbVisits, fnameVisits)
+}
+
+/*
+catalogueMatch takes an Ingredient that is not linked to a canonical ingredient
+yet and returns the best matching canonical ingredient as suggestion. It returns
+"" if the Ingredient is already linked or nothing matches. The suggestion is
+only stored when the user accepts it.
+*/
+func catalogueMatch(i gocookbook.Ingredient) string {
+	if i.Canonical != "" {
+		return ""
+	}
+	if d, _, ok := gocookbook.Densities.Match(i.Item); ok {
+		return d.Name
+	}
+	return ""
+}
+
+/* percent takes a fraction (e.g. 0.8) and returns it as a percentage (e.g. "80%").*/
+func percent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
 }
 
 /* plusOne takes an integer and returns the integer +1*/
@@ -840,7 +1678,7 @@ func plusOne(i int) int {
 
 /*
 Tags receives a slice of Recipe and returns the unique Tags as a slice
-of string
+of string, including the parents of those tags.
 */
 func tags(rcps []Recipe) []string {
 	list := map[string]bool{}
@@ -853,8 +1691,21 @@ func tags(rcps []Recipe) []string {
 	for k, _ := range list {
 		output = append(output, k)
 	}
-	sort.Strings(output)
-	return output
+	return gocookbook.TagTree.WithAncestors(output)
+}
+
+/* tagColor takes the name of a tag and returns its colour, or "" if it has none.*/
+func tagColor(tag string) string {
+	return gocookbook.TagTree.Color(tag)
+}
+
+/*
+tagsWithParents takes the tags of a recipe and returns them together with their
+parents, so a recipe with the tag "Pasta" is also shown when filtering on its
+parent "Diner".
+*/
+func tagsWithParents(tags []string) []string {
+	return gocookbook.TagTree.WithAncestors(tags)
 }
 
 /*
@@ -892,6 +1743,7 @@ func handlerProfile(w http.ResponseWriter, req *http.Request) {
 		p := req.FormValue("CurrentPassword")
 		unNew := req.FormValue("NewUsername")
 		pNew := req.FormValue("NewPassword")
+		var searches []savedSearch
 		// Verify password
 		err := dbUsers.CheckPwd(un, p)
 		if err != nil {
@@ -907,6 +1759,7 @@ func handlerProfile(w http.ResponseWriter, req *http.Request) {
 				http.Error(w, s, http.StatusForbidden)
 				return
 			}
+			searches = dbUsers.Searches(un)
 			dbUsers.Remove(un)
 			un = unNew
 		}
@@ -915,14 +1768,28 @@ func handlerProfile(w http.ResponseWriter, req *http.Request) {
 			p = pNew
 		}
 		dbUsers.AddUpdate(un, p, false)
+		if searches != nil {
+			// Saved searches move along with a new username
+			dbUsers.SetSearches(un, searches)
+		}
+		dbUsers.SetFormat(un, gocookbook.AmountFormat(req.FormValue("Format")))
+		dbUsers.SetSystem(un, gocookbook.System(req.FormValue("System")))
 		msg = "User has been updated"
 	}
 	data := struct {
 		Username string
 		Message  string
+		Format   gocookbook.AmountFormat
+		Formats  []gocookbook.AmountFormat
+		System   gocookbook.System
+		Systems  []gocookbook.System
 	}{
 		un,
 		msg,
+		dbUsers.Format(un),
+		gocookbook.AmountFormats,
+		dbUsers.System(un),
+		gocookbook.Systems,
 	}
 	err := tpl.ExecuteTemplate(w, "profile.gohtml", data)
 	if err != nil {
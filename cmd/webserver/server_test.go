@@ -1,7 +1,14 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+
+	"github.com/SEB534542/gocookbook/recipes"
 )
 
 func TestStartsWith(t *testing.T) {
@@ -46,3 +53,52 @@ func TestRemoveFromSlice(t *testing.T) {
 		}
 	}
 }
+
+func TestMigrateUsersV1(t *testing.T) {
+	data, err := migrateUsersV1([]byte(`{"chef": {"Password": "", "Admin": true}, "kok": {"Username": "kok"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uns := map[string]user{}
+	if err := json.Unmarshal(data, &uns); err != nil {
+		t.Fatal(err)
+	}
+	for un, u := range uns {
+		if u.Username != un {
+			t.Errorf("Want username '%v', Got: '%v'", un, u.Username)
+		}
+	}
+	if !uns["chef"].Admin {
+		t.Errorf("Want chef to stay admin, Got: %+v", uns["chef"])
+	}
+}
+
+func TestAdjustRecipe(t *testing.T) {
+	rcp := Recipe{Portions: 4, Ingrs: []gocookbook.Ingredient{
+		{Amount: 1, Unit: "cup", Item: "melk"},
+		{Amount: 100, Unit: "g", Item: "suiker"},
+	}}
+	post := func(form url.Values) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/recipe/10", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+	// A metric user sees 1 cup as 236.6 ml and enters 473.2 ml to double the recipe
+	form := url.Values{"Mode": {"ingredient"}, "Ingrd": {"0"}, "Amount": {"473.176"}}
+	got, msg := adjustRecipe(post(form), rcp, gocookbook.Metric)
+	if msg != "" || got.Portions < 7.99 || got.Portions > 8.01 {
+		t.Errorf("Want 8 portions for a metric user, Got: %v (%v)", got.Portions, msg)
+	}
+	// A user who sees the recipe as written enters cups
+	form.Set("Amount", "2")
+	if got, msg := adjustRecipe(post(form), rcp, gocookbook.AsWritten); msg != "" || got.Portions != 8 {
+		t.Errorf("Want 8 portions for a user with the recipe as written, Got: %v (%v)", got.Portions, msg)
+	}
+	// Invalid amounts leave the recipe unchanged with a message
+	for _, amount := range []string{"NaN", "Inf", "0", "twee"} {
+		form.Set("Amount", amount)
+		if got, msg := adjustRecipe(post(form), rcp, gocookbook.Metric); msg == "" || got.Portions != 4 {
+			t.Errorf("Amount %v failed. Want message and 4 portions, Got: %v (%v)", amount, got.Portions, msg)
+		}
+	}
+}
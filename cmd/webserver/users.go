@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 
+	"github.com/SEB534542/gocookbook/recipes"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -15,9 +17,27 @@ type Users struct {
 
 // user represents a username, with a password and an indicator if the user is an admin.
 type user struct {
-	Username string // Username for logging in.
-	Password []byte // Password for user to log in.
-	Admin    bool   // True if admin user.
+	Username string                  // Username for logging in.
+	Password []byte                  // Password for user to log in.
+	Admin    bool                    // True if admin user.
+	Format   gocookbook.AmountFormat // Preferred format for displaying amounts.
+	System   gocookbook.System       // Preferred system of measurement for displaying ingredients.
+	Searches []savedSearch           // Saved search queries.
+	Pantry   gocookbook.Pantry       // Ingredients the user has at home.
+}
+
+// savedSearch represents a search query that a user has saved under a name.
+type savedSearch struct {
+	Name  string // Name of the search, e.g. "Snel vegetarisch".
+	Query string // Query, e.g. "tag:vegetarisch time:<30m".
+}
+
+// usersSchema is the schema of the users file.
+var usersSchema = gocookbook.Schema{
+	Kind: "users",
+	Migrations: []gocookbook.Migration{
+		{"store the username with each user", migrateUsersV1},
+	},
 }
 
 // CreateUsers takes a file name, loads the Users from the JSON and returns it.
@@ -36,7 +56,7 @@ a new Users is created with the default user and password as specified in this
 method.
 */
 func (dbUsers Users) Load() {
-	err := readJSON(&dbUsers.Uns, dbUsers.Fname)
+	err := loadVersioned(usersSchema, &dbUsers.Uns, dbUsers.Fname)
 	if err != nil {
 		log.Printf("Unable to load users from '%v': %v", dbUsers.Fname, err)
 		log.Print("Setting default user")
@@ -56,8 +76,10 @@ func (dbUsers Users) AddUpdate(un, p string, b bool) {
 			log.Print(err)
 			return
 		}
-		dbUsers.Uns[un] = user{un, pwd, b}
-		SaveToJSON(dbUsers.Uns, dbUsers.Fname)
+		u := dbUsers.Uns[un]
+		u.Username, u.Password, u.Admin = un, pwd, b
+		dbUsers.Uns[un] = u
+		saveVersioned(usersSchema, dbUsers.Uns, dbUsers.Fname)
 	}
 }
 
@@ -85,10 +107,122 @@ func (dbUsers Users) IsAdmin(un string) bool {
 	return false
 }
 
+/*
+SetFormat takes a username and an AmountFormat and stores the format as the
+preferred format for displaying amounts for that user.
+*/
+func (dbUsers Users) SetFormat(un string, f gocookbook.AmountFormat) {
+	u, ok := dbUsers.Uns[un]
+	if !ok {
+		return
+	}
+	u.Format = f
+	dbUsers.Uns[un] = u
+	saveVersioned(usersSchema, dbUsers.Uns, dbUsers.Fname)
+}
+
+/*
+Format takes a username and returns the preferred format for displaying amounts
+for that user. If the user does not exist or has no preference, the default
+format is returned.
+*/
+func (dbUsers Users) Format(un string) gocookbook.AmountFormat {
+	if u, ok := dbUsers.Uns[un]; ok && u.Format != "" {
+		return u.Format
+	}
+	return gocookbook.FormatKitchen
+}
+
+/*
+SetSystem takes a username and a System and stores the system as the preferred
+system of measurement for that user.
+*/
+func (dbUsers Users) SetSystem(un string, s gocookbook.System) {
+	u, ok := dbUsers.Uns[un]
+	if !ok {
+		return
+	}
+	u.System = s
+	dbUsers.Uns[un] = u
+	saveVersioned(usersSchema, dbUsers.Uns, dbUsers.Fname)
+}
+
+/*
+System takes a username and returns the preferred system of measurement for
+that user. If the user does not exist or has no preference, ingredients are
+displayed as written.
+*/
+func (dbUsers Users) System(un string) gocookbook.System {
+	if u, ok := dbUsers.Uns[un]; ok && u.System != "" {
+		return u.System
+	}
+	return gocookbook.AsWritten
+}
+
+/*
+SaveSearch takes a username, a name and a search query and stores the query
+under that name for the user. An existing search with the same name is
+replaced.
+*/
+func (dbUsers Users) SaveSearch(un, name, q string) {
+	searches := dbUsers.Searches(un)
+	for i, s := range searches {
+		if s.Name == name {
+			searches[i].Query = q
+			dbUsers.SetSearches(un, searches)
+			return
+		}
+	}
+	dbUsers.SetSearches(un, append(searches, savedSearch{name, q}))
+}
+
+/* RemoveSearch takes a username and a name and removes the saved search with that name.*/
+func (dbUsers Users) RemoveSearch(un, name string) {
+	searches := []savedSearch{}
+	for _, s := range dbUsers.Searches(un) {
+		if s.Name != name {
+			searches = append(searches, s)
+		}
+	}
+	dbUsers.SetSearches(un, searches)
+}
+
+/* SetSearches takes a username and stores the saved searches of that user.*/
+func (dbUsers Users) SetSearches(un string, searches []savedSearch) {
+	u, ok := dbUsers.Uns[un]
+	if !ok {
+		return
+	}
+	u.Searches = searches
+	dbUsers.Uns[un] = u
+	saveVersioned(usersSchema, dbUsers.Uns, dbUsers.Fname)
+}
+
+/* Searches takes a username and returns the saved searches of that user.*/
+func (dbUsers Users) Searches(un string) []savedSearch {
+	return append([]savedSearch{}, dbUsers.Uns[un].Searches...)
+}
+
+/* SetPantry takes a username and stores the pantry of that user.*/
+func (dbUsers Users) SetPantry(un string, p gocookbook.Pantry) {
+	u, ok := dbUsers.Uns[un]
+	if !ok {
+		return
+	}
+	u.Pantry = p
+	dbUsers.Uns[un] = u
+	saveVersioned(usersSchema, dbUsers.Uns, dbUsers.Fname)
+}
+
+/* Pantry takes a username and returns the pantry of that user.*/
+func (dbUsers Users) Pantry(un string) gocookbook.Pantry {
+	return dbUsers.Uns[un].Pantry
+}
+
 /* Remove takes a username and removes the user.*/
 func (dbUsers Users) Remove(un string) {
 	delete(dbUsers.Uns, un)
-	SaveToJSON(dbUsers.Uns, dbUsers.Fname)
+	saveVersioned(usersSchema, dbUsers.Uns, dbUsers.Fname)
 }
 
 /*
@@ -124,3 +258,20 @@ func (dbUsers Users) Users() []string {
 	}
 	return xs
 }
+
+/*
+migrateUsersV1 upgrades the users to version 1: users stored without a username
+get the username they are stored under.
+*/
+func migrateUsersV1(data []byte) ([]byte, error) {
+	uns := map[string]map[string]interface{}{}
+	if err := json.Unmarshal(data, &uns); err != nil {
+		return nil, err
+	}
+	for un, u := range uns {
+		if s, _ := u["Username"].(string); s == "" {
+			u["Username"] = un
+		}
+	}
+	return json.Marshal(uns)
+}
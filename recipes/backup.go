@@ -0,0 +1,191 @@
+package gocookbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	BackupVersion  = 1               // Version of the backup format written by WriteBackup.
+	backupManifest = "manifest.json" // Name of the manifest in a backup archive.
+
+	// Limits for reading a backup, like the limits for imports but higher as a backup contains all recipes and photos.
+	maxBackupFileBytes = 100 << 20 // Maximum size of one file in a backup.
+	maxBackupBytes     = 1 << 30   // Maximum size of all files in a backup together.
+)
+
+// BackupManifest describes the contents of a backup archive.
+type BackupManifest struct {
+	Version int          // Version of the backup format.
+	Created time.Time    // Datetime the backup was created.
+	Files   []BackupFile // All files in the backup, except the manifest.
+}
+
+// BackupFile describes a file in a backup archive.
+type BackupFile struct {
+	Name   string // Path of the file, relative to the working directory of the application, e.g. "config/recipes.json".
+	Size   int64  // Size in bytes.
+	SHA256 string // Checksum of the contents, hex encoded.
+}
+
+// RestoreReport contains the result of merging a backup with the current recipes.
+type RestoreReport struct {
+	Added      int // Recipes that are new.
+	Updated    int // Recipes that replaced an older version of the same recipe.
+	Unchanged  int // Recipes for which the current version is kept, as it is the same or newer.
+	Renumbered int // Recipes that are new, but added with a new Id as their Id is used by another recipe.
+}
+
+var (
+	errorNoManifest     = errors.New("backup does not contain a manifest")         // Archive is not a backup.
+	errorBackupVersion  = errors.New("backup is made with a newer version")        // Backup cannot be read by this version.
+	errorBackupChecksum = errors.New("backup is corrupt, checksum differs")        // File differs from the manifest.
+	errorBackupFile     = errors.New("backup contains an invalid or missing file") // File is not in the manifest or is missing.
+)
+
+// WriteBackup takes the files to back up, by path, and writes them to w as a zip archive with a manifest containing
+// the size and checksum of each file.
+func WriteBackup(w io.Writer, files map[string][]byte) error {
+	m := BackupManifest{Version: BackupVersion, Created: time.Now()}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !validBackupPath(name) {
+			return fmt.Errorf("%w: %v", errorBackupFile, name)
+		}
+		sum := sha256.Sum256(files[name])
+		m.Files = append(m.Files, BackupFile{name, int64(len(files[name])), hex.EncodeToString(sum[:])})
+	}
+	zw := zip.NewWriter(w)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create(backupManifest)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadBackup takes a backup archive created by WriteBackup and returns its manifest and files, by path. It returns
+// an error if the archive is not a valid backup: the manifest is missing, the version is not supported, or a file is
+// missing, not in the manifest or has a different checksum, or if a file, or all files together, are too large.
+func ReadBackup(data []byte) (BackupManifest, map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return BackupManifest{}, nil, fmt.Errorf("unable to read backup: %w", err)
+	}
+	var m BackupManifest
+	files := map[string][]byte{}
+	found := false
+	var read int64
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return BackupManifest{}, nil, err
+		}
+		b, err := io.ReadAll(&limitReader{rc, min(maxBackupFileBytes, maxBackupBytes-read)})
+		rc.Close()
+		read += int64(len(b))
+		if err != nil {
+			return BackupManifest{}, nil, fmt.Errorf("%v: %w", f.Name, err)
+		}
+		if f.Name == backupManifest {
+			if err := json.Unmarshal(b, &m); err != nil {
+				return BackupManifest{}, nil, fmt.Errorf("%w: %v", errorNoManifest, err)
+			}
+			found = true
+			continue
+		}
+		files[f.Name] = b
+	}
+	switch {
+	case !found:
+		return BackupManifest{}, nil, errorNoManifest
+	case m.Version > BackupVersion:
+		return BackupManifest{}, nil, fmt.Errorf("%w (version %v)", errorBackupVersion, m.Version)
+	}
+	listed := map[string]bool{}
+	for _, bf := range m.Files {
+		b, ok := files[bf.Name]
+		if !ok || !validBackupPath(bf.Name) {
+			return BackupManifest{}, nil, fmt.Errorf("%w: %v", errorBackupFile, bf.Name)
+		}
+		sum := sha256.Sum256(b)
+		if int64(len(b)) != bf.Size || hex.EncodeToString(sum[:]) != bf.SHA256 {
+			return BackupManifest{}, nil, fmt.Errorf("%w: %v", errorBackupChecksum, bf.Name)
+		}
+		listed[bf.Name] = true
+	}
+	for name := range files {
+		if !listed[name] {
+			return BackupManifest{}, nil, fmt.Errorf("%w: %v", errorBackupFile, name)
+		}
+	}
+	return m, files, nil
+}
+
+// validBackupPath returns true if name is a relative path within the working directory, e.g. "config/recipes.json".
+func validBackupPath(name string) bool {
+	return name != "" && name != backupManifest && !strings.HasPrefix(name, "/") && !strings.Contains(name, `\`) &&
+		path.Clean(name) == name && !strings.HasPrefix(name, "../") && name != ".."
+}
+
+// MergeRecipes takes the current recipes and the recipes from a backup and returns the merged recipes, together with
+// a report. A recipe in the backup with the same Id and creation time as a current recipe is the same recipe; the
+// version that was updated last is kept. Other recipes in the backup are added, with a new Id if their Id is in use.
+func MergeRecipes(current, restored Cookbook) (Cookbook, RestoreReport) {
+	var rr RestoreReport
+	merged := make(Cookbook, len(current))
+	copy(merged, current)
+	var conflicts Cookbook
+	for _, r := range restored {
+		c, err := findRecipe(merged, r.Id)
+		switch {
+		case err != nil:
+			merged = append(merged, r)
+			rr.Added++
+		case !c.Created.Equal(r.Created):
+			// renumbered after all other recipes are added, so the new Id is not used by a restored recipe
+			conflicts = append(conflicts, r)
+		case r.Updated.After(c.Updated):
+			*c = r
+			rr.Updated++
+		default:
+			rr.Unchanged++
+		}
+	}
+	for _, r := range conflicts {
+		r.Id = newRecipeId(merged)
+		merged = append(merged, r)
+		rr.Renumbered++
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return merged, rr
+}
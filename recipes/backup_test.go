@@ -0,0 +1,102 @@
+package gocookbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackup(t *testing.T) {
+	files := map[string][]byte{
+		"config/recipes.json":       []byte(`[{"Id":10}]`),
+		"config/users.json":         []byte(`{}`),
+		"config/photos/10/foto.jpg": {0xff, 0xd8, 0xff},
+	}
+	var buf bytes.Buffer
+	if err := WriteBackup(&buf, files); err != nil {
+		t.Fatal(err)
+	}
+	m, got, err := ReadBackup(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != BackupVersion || len(m.Files) != 3 || !reflect.DeepEqual(got, files) {
+		t.Errorf("Backup differs. Got: %+v, %v", m, got)
+	}
+	if err := WriteBackup(&bytes.Buffer{}, map[string][]byte{"../etc/passwd": nil}); !errors.Is(err, errorBackupFile) {
+		t.Errorf("Want error '%v', Got: '%v'", errorBackupFile, err)
+	}
+}
+
+func TestReadBackupInvalid(t *testing.T) {
+	archive := func(entries map[string]string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, s := range entries {
+			f, _ := zw.Create(name)
+			f.Write([]byte(s))
+		}
+		zw.Close()
+		return buf.Bytes()
+	}
+	manifest := `{"Version":1,"Files":[{"Name":"config/recipes.json","Size":2,"SHA256":"4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"}]}`
+	cases := []struct {
+		data []byte
+		want error
+	}{
+		{archive(map[string]string{"config/recipes.json": "[]"}), errorNoManifest},
+		{archive(map[string]string{backupManifest: `{"Version":99}`}), errorBackupVersion},
+		{archive(map[string]string{backupManifest: manifest, "config/recipes.json": "{}"}), errorBackupChecksum},
+		{archive(map[string]string{backupManifest: manifest}), errorBackupFile},
+		{archive(map[string]string{backupManifest: manifest, "config/recipes.json": "[]", "extra": "x"}), errorBackupFile},
+	}
+	for i, c := range cases {
+		if _, _, err := ReadBackup(c.data); !errors.Is(err, c.want) {
+			t.Errorf("Case %v failed. Want error '%v', Got: '%v'", i, c.want, err)
+		}
+	}
+	if _, _, err := ReadBackup(archive(map[string]string{backupManifest: manifest, "config/recipes.json": "[]"})); err != nil {
+		t.Errorf("Want no error, Got: '%v'", err)
+	}
+	// A file that is too large when decompressed is not read completely
+	large := archive(map[string]string{backupManifest: manifest, "config/recipes.json": strings.Repeat(" ", maxBackupFileBytes+1)})
+	if _, _, err := ReadBackup(large); !errors.Is(err, errorImportTooLarge) {
+		t.Errorf("Want error '%v', Got: '%v'", errorImportTooLarge, err)
+	}
+}
+
+func TestMergeRecipes(t *testing.T) {
+	t1 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	current := Cookbook{
+		{Id: 10, Name: "Soep", Created: t1, Updated: t1},
+		{Id: 20, Name: "Taart", Created: t1, Updated: t2},
+		{Id: 30, Name: "Salade", Created: t1, Updated: t1},
+	}
+	restored := Cookbook{
+		{Id: 10, Name: "Soep (nieuw)", Created: t1, Updated: t2}, // newer version
+		{Id: 20, Name: "Taart (oud)", Created: t1, Updated: t1},  // older version
+		{Id: 30, Name: "Pasta", Created: t2, Updated: t2},        // other recipe with same Id
+		{Id: 40, Name: "Brood", Created: t1, Updated: t1},        // new
+	}
+	got, rr := MergeRecipes(current, restored)
+	if want := (RestoreReport{Added: 1, Updated: 1, Unchanged: 1, Renumbered: 1}); rr != want {
+		t.Errorf("Wrong report. Want: %+v, Got: %+v", want, rr)
+	}
+	want := map[int]string{10: "Soep (nieuw)", 20: "Taart", 30: "Salade", 40: "Brood", 50: "Pasta"}
+	if len(got) != len(want) {
+		t.Fatalf("Want %v recipes, Got: %+v", len(want), got)
+	}
+	for _, r := range got {
+		if want[r.Id] != r.Name {
+			t.Errorf("Recipe %v: Want '%v', Got: '%v'", r.Id, want[r.Id], r.Name)
+		}
+	}
+	if current[0].Name != "Soep" {
+		t.Errorf("Current recipes are changed")
+	}
+}
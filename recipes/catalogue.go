@@ -0,0 +1,196 @@
+package gocookbook
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The DensityTable doubles as the catalogue of canonical ingredients: each Density is a canonical ingredient with
+// its synonyms (e.g. Dutch and English names). Ingredients in recipes are linked to it through Ingredient.Canonical.
+
+// Scores used by Match, from an exact match to the minimum score for a suggestion.
+const (
+	scoreExact    = 1.0
+	scoreSingular = 0.95 // Same word in singular form, e.g. "uien" and "ui".
+	scoreWord     = 0.8  // Item contains the name as separate word(s), e.g. "gesnipperde ui" and "ui".
+	scoreFuzzy    = 0.9  // Multiplied with the similarity for spelling mistakes, e.g. "courgete" and "courgette".
+	scoreMin      = 0.6  // Minimum score for a match.
+)
+
+// Match takes an item and returns the canonical ingredient in the DensityTable that matches the item best, the score
+// of the match (between 0 and 1) and true. Next to exact matches it accepts singular/plural variants, items that
+// contain the name (e.g. "fijngehakte ui") and spelling mistakes. It returns false if nothing matches well enough.
+func (dt DensityTable) Match(item string) (Density, float64, bool) {
+	key := fold(item)
+	if key == "" {
+		return Density{}, 0, false
+	}
+	var best Density
+	var bestScore float64
+	for _, n := range dt.index().names {
+		if s := matchScore(key, n.folded); s > bestScore {
+			best, bestScore = dt[n.key], s
+		}
+	}
+	if bestScore < scoreMin {
+		return Density{}, 0, false
+	}
+	return best, bestScore, true
+}
+
+// matchScore takes a (folded) item and name and returns how well they match, between 0 and 1.
+func matchScore(item, name string) float64 {
+	if item == name {
+		return scoreExact
+	}
+	if name == "" {
+		return 0
+	}
+	if sameWord(item, name) {
+		return scoreSingular
+	}
+	// item contains all words of the name, e.g. "fijngehakte rode ui" and "rode ui"
+	words, nameWords := strings.Fields(item), strings.Fields(name)
+	found := 0
+	for _, nw := range nameWords {
+		for _, w := range words {
+			if sameWord(w, nw) {
+				found++
+				break
+			}
+		}
+	}
+	if found == len(nameWords) {
+		return scoreWord
+	}
+	// spelling mistakes in the complete item, or in one of the words if the name is a single word
+	s := scoreFuzzy * similarity(item, name)
+	if len(nameWords) == 1 {
+		for _, w := range words {
+			if x := scoreWord * similarity(w, name); x > s {
+				s = x
+			}
+		}
+	}
+	return s
+}
+
+// sameWord takes two words and returns true if they are equal or if one is the singular or plural of the other.
+func sameWord(a, b string) bool {
+	if a == b {
+		return true
+	}
+	for _, x := range singulars(a) {
+		for _, y := range singulars(b) {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// singulars takes a word and returns the word itself and all possible singular forms, based on the most common
+// Dutch and English plural forms, e.g. "uien" returns "ui" and "tomatoes" returns "tomato".
+func singulars(w string) []string {
+	xs := []string{w}
+	add := func(s string) {
+		if len([]rune(s)) > 1 {
+			xs = append(xs, s)
+		}
+	}
+	switch {
+	case strings.HasSuffix(w, "'s"):
+		add(w[:len(w)-2])
+	case strings.HasSuffix(w, "ies"):
+		add(w[:len(w)-3] + "y")
+	case strings.HasSuffix(w, "ves"):
+		add(w[:len(w)-3] + "f")
+	case strings.HasSuffix(w, "es"):
+		add(w[:len(w)-2])
+		add(w[:len(w)-1])
+	case strings.HasSuffix(w, "s"):
+		add(w[:len(w)-1])
+	case strings.HasSuffix(w, "eren"):
+		add(w[:len(w)-4])
+		add(w[:len(w)-2])
+	case strings.HasSuffix(w, "en"):
+		stem := w[:len(w)-2]
+		add(stem)
+		r := []rune(stem)
+		if n := len(r); n > 2 && !isVowel(r[n-1]) && isVowel(r[n-2]) && !isVowel(r[n-3]) {
+			// open syllable, e.g. "tomaten" to "tomaat"
+			add(string(r[:n-1]) + string(r[n-2]) + string(r[n-1]))
+		}
+		if n := len(r); n > 2 && r[n-1] == r[n-2] {
+			// double consonant, e.g. "kippen" to "kip"
+			add(string(r[:n-1]))
+		}
+	}
+	return xs
+}
+
+// isVowel returns true if r is a vowel.
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiou", r)
+}
+
+// similarity takes two strings and returns how similar they are between 0 (different) and 1 (equal), based on the
+// Levenshtein distance between both strings.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	max := len(ra)
+	if len(rb) > max {
+		max = len(rb)
+	}
+	if max == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(max)
+}
+
+// levenshtein returns the minimum number of single character edits needed to change a into b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// minInt returns the lowest of the given integers.
+func minInt(x int, xi ...int) int {
+	for _, v := range xi {
+		if v < x {
+			x = v
+		}
+	}
+	return x
+}
+
+// fold takes a string and returns it in lowercase, without diacritics and surrounding spaces, e.g. "Crème " returns
+// "creme".
+func fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	r, _, err := transform.String(t, s)
+	if err != nil {
+		r = s
+	}
+	return strings.Join(strings.Fields(strings.ToLower(r)), " ")
+}
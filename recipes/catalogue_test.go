@@ -0,0 +1,69 @@
+package gocookbook
+
+import (
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	dt := DensityTable{}
+	dt.Add(Density{Name: "ui", Synonyms: []string{"onion"}})
+	dt.Add(Density{Name: "tomaat", Synonyms: []string{"tomato"}})
+	dt.Add(Density{Name: "courgette", Synonyms: []string{"zucchini"}})
+	dt.Add(Density{Name: "ei", Synonyms: []string{"egg"}})
+	dt.Add(Density{Name: "crème fraîche"})
+	dt.Add(Density{Name: "kip", Synonyms: []string{"kipfilet", "chicken"}})
+	cases := []struct {
+		item string
+		want string
+		ok   bool
+	}{
+		{"ui", "ui", true},
+		{"Uien", "ui", true},
+		{"onions", "ui", true},
+		{"tomaten", "tomaat", true},
+		{"tomatoes", "tomaat", true},
+		{"eieren", "ei", true},
+		{"courgete", "courgette", true},
+		{"gesnipperde ui", "ui", true},
+		{"creme fraiche", "crème fraîche", true},
+		{"kippen", "kip", true},
+		{"banaan", "", false},
+		{"", "", false},
+	}
+	for i, c := range cases {
+		d, score, ok := dt.Match(c.item)
+		if ok != c.ok || d.Name != c.want {
+			t.Errorf("Case %v failed for '%v'. Want: '%v' (%v), Got: '%v' (%v, %v)", i, c.item, c.want, c.ok, d.Name, ok, score)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	cases := []struct {
+		s    string
+		want string
+	}{
+		{"Crème Fraîche ", "creme fraiche"},
+		{"  ijs  met   slagroom", "ijs met slagroom"},
+		{"Ingrediënten", "ingredienten"},
+	}
+	for i, c := range cases {
+		if got := fold(c.s); got != c.want {
+			t.Errorf("Case %v failed. Want: '%v', Got: '%v'", i, c.want, got)
+		}
+	}
+}
+
+func TestFindIngrCatalogue(t *testing.T) {
+	Densities = DensityTable{}
+	defer func() { Densities = DensityTable{} }()
+	Densities.Add(Density{Name: "courgette", Synonyms: []string{"zucchini"}})
+	rcps := Cookbook{
+		{Id: 1, Name: "Test1", Ingrs: []Ingredient{{Item: "zucchini"}}},
+		{Id: 2, Name: "Test2", Ingrs: []Ingredient{{Item: "groente", Canonical: "courgette"}}},
+		{Id: 3, Name: "Test3", Ingrs: []Ingredient{{Item: "pasta"}}},
+	}
+	if got := findIngr(rcps, "Courgettes"); len(got) != 2 {
+		t.Errorf("Want: %v recipes, Got: %v", 2, len(got))
+	}
+}
@@ -0,0 +1,270 @@
+package gocookbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	reCookIngrd    = regexp.MustCompile(`@(?:([^@#~{}\n]+?)\{([^}]*)\}|([^\s@#~{}.,;:!?()]+))(?:\(([^)]*)\))?`) // Ingredient, e.g. "@salt", "@olive oil{2%tbsp}" or "@onion{1}(chopped)".
+	reCookware     = regexp.MustCompile(`#(?:([^@#~{}\n]+?)\{[^}]*\}|([^\s@#~{}.,;:!?()]+))`)                   // Cookware, e.g. "#pot" or "#frying pan{}".
+	reCookTimer    = regexp.MustCompile(`~[^@#~{}\s]*\{([^}]*)\}`)                                              // Timer, e.g. "~{25%minutes}".
+	reCookComment  = regexp.MustCompile(`(?s)\[-.*?-\]|--[^\n]*`)                                               // Comment, e.g. "-- comment" or "[- comment -]".
+	reCookMetadata = regexp.MustCompile(`^>>\s*([^:]+?)\s*:\s*(.*)$`)                                           // Metadata, e.g. ">> servings: 4".
+)
+
+// importCooklang takes a Cooklang file (.cook) or a zip file with .cook files and returns the recipes in it. The
+// name of a recipe is taken from its title in the metadata or else from the file name.
+func importCooklang(r io.Reader, ir *ImportReport) ([]Recipe, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return []Recipe{cooklangToRecipe(string(data), "", ir)}, nil
+	}
+	rcps := []Recipe{}
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ".cook") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := ir.unpack(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", f.Name, err)
+		}
+		name := strings.TrimSuffix(path.Base(f.Name), path.Ext(f.Name))
+		rcps = append(rcps, cooklangToRecipe(string(b), name, ir))
+	}
+	return rcps, nil
+}
+
+// cooklangToRecipe takes the text of a Cooklang recipe and returns it as Recipe.
+func cooklangToRecipe(s, name string, ir *ImportReport) Recipe {
+	rcp := Recipe{Name: name, Tags: []string{}}
+	lines := strings.Split(normalizeText(s), "\n")
+	// YAML front matter is handled as metadata
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				for j := 1; j < i; j++ {
+					lines[j] = ">> " + lines[j]
+				}
+				lines = append(lines[1:i], lines[i+1:]...)
+				break
+			}
+		}
+	}
+	lines = strings.Split(reCookComment.ReplaceAllString(strings.Join(lines, "\n"), ""), "\n")
+	var text, notes []string
+	var ingrds []string
+	known := map[string]bool{}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if m := reCookMetadata.FindStringSubmatch(trimmed); m != nil {
+			cooklangMetadata(&rcp, strings.ToLower(m[1]), strings.Trim(m[2], `"' `), ir)
+			continue
+		}
+		switch {
+		case strings.HasPrefix(trimmed, ">"):
+			notes = append(notes, strings.TrimSpace(trimmed[1:]))
+			continue
+		case strings.HasPrefix(trimmed, "="):
+			// section, e.g. "== Saus =="
+			continue
+		}
+		// Ingredients
+		line = reCookIngrd.ReplaceAllStringFunc(line, func(x string) string {
+			m := reCookIngrd.FindStringSubmatch(x)
+			item, quantity, note := m[1]+m[3], strings.TrimPrefix(strings.TrimSpace(m[2]), "="), m[4]
+			if quantity == "" && known[strings.ToLower(item)] {
+				return item
+			}
+			known[strings.ToLower(item)] = true
+			xs := strings.Fields(strings.Replace(quantity, "%", " ", 1))
+			ingrd := strings.Join(append(xs, item), " ")
+			if note != "" {
+				ingrd += ", " + note
+			}
+			ingrds = append(ingrds, ingrd)
+			return item
+		})
+		// Cookware and timers
+		line = reCookware.ReplaceAllString(line, "$1$2")
+		line = reCookTimer.ReplaceAllStringFunc(line, func(x string) string {
+			m := reCookTimer.FindStringSubmatch(x)
+			return strings.Join(strings.Fields(strings.Replace(m[1], "%", " ", 1)), " ")
+		})
+		text = append(text, line)
+	}
+	rcp.Ingrs = ir.ingredients(rcp.Name, ingrds)
+	var tips string
+	rcp.Steps, tips = cooklangSteps(text)
+	rcp.Notes = joinNotes(rcp.Notes, strings.Join(notes, "\n"), tips)
+	return rcp
+}
+
+// cooklangSteps takes the lines of a Cooklang recipe without markup and returns the steps, where each paragraph is a
+// step, and any trailing notes.
+func cooklangSteps(lines []string) ([]string, string) {
+	var paragraphs []string
+	var p []string
+	for _, line := range append(lines, "") {
+		if strings.TrimSpace(line) == "" {
+			if len(p) > 0 {
+				paragraphs = append(paragraphs, strings.Join(p, " "))
+				p = nil
+			}
+			continue
+		}
+		p = append(p, strings.Join(strings.Fields(line), " "))
+	}
+	return TextToSteps(strings.Join(paragraphs, "\n\n"))
+}
+
+// cooklangMetadata takes a key and value of metadata in a Cooklang recipe and stores it in the Recipe.
+func cooklangMetadata(rcp *Recipe, key, value string, ir *ImportReport) {
+	switch key {
+	case "title", "name":
+		rcp.Name = value
+	case "servings", "serves", "yield":
+		rcp.Portions = schemaYield(value)
+	case "time", "duration", "time required", "total time":
+		rcp.Dur = parseTextDuration(value)
+	case "tags", "tag", "category", "categories", "course", "cuisine":
+		for _, t := range strings.Split(strings.Trim(value, "[]"), ",") {
+			if t = strings.Trim(t, `"' `); t != "" && !containsFold(rcp.Tags, t) {
+				rcp.Tags = append(rcp.Tags, t)
+			}
+		}
+	case "source", "source.url", "url":
+		if isURL(value) {
+			rcp.SourceLink = value
+		}
+		if rcp.Source == "" || key == "source" {
+			rcp.Source = value
+		}
+	case "author", "source.name":
+		rcp.Source = value
+	case "description", "introduction", "notes":
+		rcp.Notes = joinNotes(rcp.Notes, value)
+	default:
+		if value != "" {
+			ir.skip(key)
+		}
+	}
+}
+
+// exportCooklang writes the recipes in Cooklang to w: a single .cook file for one recipe and a zip file with a .cook
+// file per recipe for multiple recipes.
+func exportCooklang(w io.Writer, rcps []Recipe) error {
+	if len(rcps) == 1 {
+		_, err := io.WriteString(w, recipeToCooklang(rcps[0]))
+		return err
+	}
+	zw := zip.NewWriter(w)
+	names := map[string]int{}
+	for _, rcp := range rcps {
+		f, err := zw.Create(fileName(rcp.Name, names) + ".cook")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, recipeToCooklang(rcp)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// recipeToCooklang returns the Recipe in Cooklang. Each ingredient is marked in the first step that mentions it;
+// ingredients that are not mentioned in any step are listed in a separate first step.
+func recipeToCooklang(rcp Recipe) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ">> title: %v\n", rcp.Name)
+	if rcp.Portions > 0 {
+		fmt.Fprintf(&b, ">> servings: %v\n", formatPortions(rcp.Portions))
+	}
+	if d := formatTextDuration(rcp.Dur); d != "" {
+		fmt.Fprintf(&b, ">> time: %v\n", d)
+	}
+	if len(rcp.Tags) > 0 {
+		fmt.Fprintf(&b, ">> tags: %v\n", strings.Join(rcp.Tags, ", "))
+	}
+	switch {
+	case rcp.SourceLink != "":
+		fmt.Fprintf(&b, ">> source: %v\n", rcp.SourceLink)
+	case rcp.Source != "":
+		fmt.Fprintf(&b, ">> source: %v\n", rcp.Source)
+	}
+	b.WriteString("\n")
+	// Mark ingredients in the steps, using placeholders to not match text within earlier markup
+	steps := append([]string{}, rcp.Steps...)
+	var markup, missing []string
+	for _, v := range rcp.Ingrs {
+		m := cooklangIngredient(v)
+		re, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(v.Item) + `\b`)
+		found := false
+		for i := range steps {
+			if err != nil || v.Item == "" {
+				break
+			}
+			if loc := re.FindStringIndex(steps[i]); loc != nil {
+				steps[i] = steps[i][:loc[0]] + fmt.Sprintf("\x00%d\x00", len(markup)) + steps[i][loc[1]:]
+				markup = append(markup, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) > 0 {
+		steps = append([]string{"Ingrediënten: " + strings.Join(missing, ", ")}, steps...)
+	}
+	for _, s := range steps {
+		for i, m := range markup {
+			s = strings.Replace(s, fmt.Sprintf("\x00%d\x00", i), m, 1)
+		}
+		b.WriteString(s + "\n\n")
+	}
+	for _, line := range textLines(rcp.Notes) {
+		fmt.Fprintf(&b, "> %v\n", line)
+	}
+	return b.String()
+}
+
+// cooklangIngredient returns the Ingredient as Cooklang ingredient, e.g. "@olive oil{2%el}(extra vierge)".
+func cooklangIngredient(i Ingredient) string {
+	var q string
+	if i.Amount > 0 {
+		q = mmAmount(i)
+		if strings.Contains(q, " ") {
+			// mixed fraction, e.g. "1 1/2"
+			q = decimal(i.Amount)
+		}
+		if i.Unit != pcs && i.Unit != "" {
+			q += "%" + string(i.Unit)
+		}
+	}
+	s := fmt.Sprintf("@%v{%v}", i.Item, q)
+	if i.Notes != "" {
+		s += fmt.Sprintf("(%v)", i.Notes)
+	}
+	return s
+}
+
+// isURL returns true if s starts with http:// or https://.
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
@@ -0,0 +1,209 @@
+package gocookbook
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Density represents the conversion data for one (canonical) ingredient.
+type Density struct {
+	Name        string           // Canonical name of the ingredient, in lowercase.
+	Synonyms    []string         // Other names for the same ingredient, e.g. "tarwebloem" for "bloem".
+	MlPerGram   float64          // Milliliters for 1 gram of the ingredient, zero if unknown.
+	PieceWeight float64          // Average weight in grams of 1 (medium sized) piece, e.g. 50 for an egg, zero if unknown.
+	Sizes       map[Size]float64 // Average weight in grams of 1 piece per size, if it differs from PieceWeight.
+	Measured    Unit             // Unit the density was measured in, e.g. cup.
+	Source      string           // Source of the values.
+	Notes       string           // Notes on the values, e.g. "sifted".
+}
+
+type Size string // Size represents the size of a piece, e.g. a small onion.
+
+// Different sizes of pieces.
+const (
+	Small  = Size("klein")
+	Medium = Size("middel")
+	Large  = Size("groot")
+)
+
+// Sizes contains all sizes for which a piece weight can be maintained.
+var Sizes = []Size{Small, Medium, Large}
+
+// sizeWords contains the words that indicate the size of a piece in an item or notes.
+var sizeWords = map[string]Size{
+	"klein":       Small,
+	"kleine":      Small,
+	"small":       Small,
+	"middel":      Medium,
+	"middelgroot": Medium,
+	"middelgrote": Medium,
+	"medium":      Medium,
+	"groot":       Large,
+	"grote":       Large,
+	"large":       Large,
+	"big":         Large,
+}
+
+// DensityTable contains the conversion data for all ingredients, by canonical name.
+type DensityTable map[string]Density
+
+// Densities is the DensityTable used to convert ingredients between mass and volume.
+var Densities = DensityTable{}
+
+// densityIndex contains the folded names and synonyms of the ingredients in a DensityTable, so they do not have to be
+// folded and sorted again for every item that is looked up.
+type densityIndex struct {
+	names []densityName     // Folded names and synonyms, in the order of the canonical names.
+	forms map[string]string // Every singular form of the folded names and synonyms, with the first canonical name.
+}
+
+// densityName represents a folded name or synonym of an ingredient in a densityIndex.
+type densityName struct {
+	key    string // Canonical name of the ingredient in the DensityTable.
+	folded string // Folded name or synonym.
+}
+
+// densityCache holds the densityIndex of the DensityTable that was used last. It is built again when another table
+// is used or when the table is changed by Add. Changing a table in any other way than Add or UnmarshalJSON requires
+// a new table.
+var densityCache struct {
+	sync.Mutex
+	table DensityTable
+	size  int
+	index *densityIndex
+}
+
+// index returns the densityIndex of the DensityTable, building it if the table is not the one in the densityCache or
+// has changed since.
+func (dt DensityTable) index() *densityIndex {
+	densityCache.Lock()
+	defer densityCache.Unlock()
+	c := &densityCache
+	if c.index != nil && len(dt) == c.size && reflect.ValueOf(dt).Pointer() == reflect.ValueOf(c.table).Pointer() {
+		return c.index
+	}
+	ix := &densityIndex{forms: map[string]string{}}
+	for _, k := range dt.Names() {
+		d := dt[k]
+		for _, name := range append([]string{d.Name}, d.Synonyms...) {
+			f := fold(name)
+			ix.names = append(ix.names, densityName{k, f})
+			for _, form := range singulars(f) {
+				if _, ok := ix.forms[form]; !ok {
+					ix.forms[form] = k
+				}
+			}
+		}
+	}
+	c.table, c.size, c.index = dt, len(dt), ix
+	return ix
+}
+
+// Find takes an item and returns the Density for that item and true. The item matches a Density if it equals the
+// canonical name or one of the synonyms, ignoring case, diacritics and plural forms. It returns false if no Density
+// matches.
+func (dt DensityTable) Find(item string) (Density, bool) {
+	item = fold(item)
+	if d, ok := dt[item]; ok {
+		return d, true
+	}
+	// like sameWord: the item matches if it has a singular form in common with a name, the first name wins
+	ix := dt.index()
+	var key string
+	for _, form := range singulars(item) {
+		if k, ok := ix.forms[form]; ok && (key == "" || k < key) {
+			key = k
+		}
+	}
+	if key == "" {
+		return Density{}, false
+	}
+	return dt[key], true
+}
+
+// PieceWeight takes an Ingredient and returns the average weight in grams of 1 piece of the Ingredient and true.
+// The size of the piece is taken from the item or notes, e.g. "grote ui" or "ui, klein". It returns false if no
+// piece weight is known for the Ingredient.
+func (dt DensityTable) PieceWeight(i Ingredient) (float64, bool) {
+	size := Medium
+	var xs []string
+	for _, w := range strings.Fields(i.Item) {
+		if s, ok := sizeWords[strings.ToLower(w)]; ok {
+			size = s
+			continue
+		}
+		xs = append(xs, w)
+	}
+	for _, w := range strings.FieldsFunc(i.Notes, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if s, ok := sizeWords[strings.ToLower(w)]; ok {
+			size = s
+		}
+	}
+	item := strings.Join(xs, " ")
+	if i.Canonical != "" {
+		item = i.Canonical
+	}
+	d, ok := dt.Find(item)
+	if !ok {
+		return 0, false
+	}
+	if f, ok := d.Sizes[size]; ok && f > 0 {
+		return f, true
+	}
+	return d.PieceWeight, d.PieceWeight > 0
+}
+
+// SizeWeight takes a Size and returns the average weight in grams of 1 piece of that size, zero if not maintained.
+func (d Density) SizeWeight(s Size) float64 {
+	return d.Sizes[s]
+}
+
+// Add takes a Density and adds it to the DensityTable, replacing any existing Density with the same name.
+func (dt DensityTable) Add(d Density) {
+	d.Name = strings.ToLower(strings.TrimSpace(d.Name))
+	if d.Name == "" {
+		return
+	}
+	dt[d.Name] = d
+	densityCache.Lock()
+	densityCache.index = nil
+	densityCache.Unlock()
+}
+
+// Names returns the canonical names of all ingredients in the DensityTable, sorted.
+func (dt DensityTable) Names() []string {
+	xs := make([]string, 0, len(dt))
+	for k := range dt {
+		xs = append(xs, k)
+	}
+	sort.Strings(xs)
+	return xs
+}
+
+// UnmarshalJSON decodes the DensityTable from JSON. Next to the current format it accepts the previous format of
+// conversion.json, a map of item to milliliters per gram, which is migrated into a Density per item.
+func (dt *DensityTable) UnmarshalJSON(data []byte) error {
+	m := map[string]Density{}
+	if err := json.Unmarshal(data, &m); err == nil {
+		*dt = DensityTable{}
+		for k, d := range m {
+			if d.Name == "" {
+				d.Name = k
+			}
+			dt.Add(d)
+		}
+		return nil
+	}
+	old := map[string]float64{}
+	if err := json.Unmarshal(data, &old); err != nil {
+		return err
+	}
+	*dt = DensityTable{}
+	for k, f := range old {
+		dt.Add(Density{Name: k, MlPerGram: f})
+	}
+	return nil
+}
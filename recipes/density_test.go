@@ -0,0 +1,92 @@
+package gocookbook
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFindDensity(t *testing.T) {
+	dt := DensityTable{}
+	dt.Add(Density{Name: "Bloem", Synonyms: []string{"tarwebloem", "Patentbloem"}, MlPerGram: 1.8})
+	cases := []struct {
+		item string
+		ok   bool
+	}{
+		{"bloem", true},
+		{"Tarwebloem", true},
+		{"patentbloem ", true},
+		{"suiker", false},
+	}
+	for i, c := range cases {
+		if d, ok := dt.Find(c.item); ok != c.ok || (ok && d.Name != "bloem") {
+			t.Errorf("Case %v failed for '%v'. Want: %v, Got: %v (%+v)", i, c.item, c.ok, ok, d)
+		}
+	}
+	// The index is built again after adding an ingredient and for another table
+	dt.Add(Density{Name: "suiker", Synonyms: []string{"kristalsuiker"}})
+	if d, ok := dt.Find("kristalsuikers"); !ok || d.Name != "suiker" {
+		t.Errorf("Want suiker after adding it, Got: %v (%+v)", ok, d)
+	}
+	other := DensityTable{"rijst": {Name: "rijst", Synonyms: []string{"basmati"}}}
+	if d, ok := other.Find("Basmati"); !ok || d.Name != "rijst" {
+		t.Errorf("Want rijst from another table, Got: %v (%+v)", ok, d)
+	}
+	if _, ok := dt.Find("basmati"); ok {
+		t.Errorf("Want basmati not in the first table")
+	}
+}
+
+func TestMigrateDensities(t *testing.T) {
+	cases := []string{
+		`{"bloem": 1.8, "suiker": 1.2}`,
+		`{"bloem": {"MlPerGram": 1.8}, "suiker": {"Name": "suiker", "MlPerGram": 1.2}}`,
+	}
+	for i, c := range cases {
+		var dt DensityTable
+		if err := json.Unmarshal([]byte(c), &dt); err != nil {
+			t.Fatalf("Case %v failed: %v", i, err)
+		}
+		if d, ok := dt.Find("bloem"); !ok || d.Name != "bloem" || d.MlPerGram != 1.8 || len(dt) != 2 {
+			t.Errorf("Case %v failed. Got: %+v", i, dt)
+		}
+	}
+	var dt DensityTable
+	if err := json.Unmarshal([]byte(`["bloem"]`), &dt); err == nil {
+		t.Errorf("no error for invalid table")
+	}
+}
+
+func TestAltUnitsDensity(t *testing.T) {
+	Densities = DensityTable{}
+	defer func() { Densities = DensityTable{} }()
+	Densities.Add(Density{Name: "bloem", Synonyms: []string{"tarwebloem"}, MlPerGram: 2})
+	in := NewIngredient(100, gram, "tarwebloem", "")
+	if want := "200 ml / 0.8 cup"; in.AltUnits != want {
+		t.Errorf("Want: '%v', Got: '%v'", want, in.AltUnits)
+	}
+}
+
+func TestPieceWeight(t *testing.T) {
+	Densities = DensityTable{}
+	defer func() { Densities = DensityTable{} }()
+	Densities.Add(Density{Name: "ui", Synonyms: []string{"uien", "onion", "onions"}, PieceWeight: 150, Sizes: map[Size]float64{Small: 80, Large: 250}})
+	Densities.Add(Density{Name: "ei", Synonyms: []string{"eieren"}, PieceWeight: 50})
+	cases := []struct {
+		in   Ingredient
+		want string
+	}{
+		{Ingredient{Amount: 2, Unit: pcs, Item: "onions"}, "2 onions (≈ 300 g)"},
+		{Ingredient{Amount: 2, Unit: pcs, Item: "grote uien"}, "2 grote uien (≈ 500 g)"},
+		{Ingredient{Amount: 1, Unit: pcs, Item: "ui", Notes: "klein, gesnipperd"}, "1 ui, klein, gesnipperd (≈ 80 g)"},
+		{Ingredient{Amount: 3, Unit: pcs, Item: "grote eieren"}, "3 grote eieren (≈ 150 g)"},
+		{Ingredient{Amount: 1, Unit: pcs, Item: "banaan"}, "1 banaan"},
+	}
+	for i, c := range cases {
+		if got := c.in.Print(); got != c.want {
+			t.Errorf("Case %v failed. Want: '%v', Got: '%v'", i, c.want, got)
+		}
+	}
+	if g, ok := (Ingredient{Amount: 0.5, Unit: kilo, Item: "ui"}).Grams(); !ok || g != 500 {
+		t.Errorf("Want: %v, Got: %v (%v)", 500, g, ok)
+	}
+}
@@ -0,0 +1,164 @@
+package gocookbook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Fetcher fetches recipe pages from websites and imports the recipe in them.
+type Fetcher struct {
+	Timeout  time.Duration // Maximum duration of a request.
+	MaxBytes int64         // Maximum size of a page in bytes.
+	Allow    []string      // Hosts that can be fetched, including their subdomains. If empty, all hosts are allowed.
+	Deny     []string      // Hosts that cannot be fetched, including their subdomains, next to denyHosts.
+
+	private bool              // Private addresses can be fetched, only used to test with a local server.
+	hosts   map[string]string // Addresses of hosts that are used instead of DNS, only used in tests.
+}
+
+// denyHosts contains the hosts that can never be fetched, whatever the Deny list is. Addresses that hosts resolve to
+// are checked as well, see checkAddress.
+var denyHosts = []string{"localhost", "localhost.localdomain", "ip6-localhost", "metadata.google.internal"}
+
+var (
+	errorInvalidURL     = errors.New("invalid url, must start with http:// or https://") // URL cannot be fetched.
+	errorHostNotAllowed = errors.New("host is not allowed")                              // Host is denied or not in the allow list.
+	errorPageTooLarge   = errors.New("page is too large")                                // Page exceeds MaxBytes.
+	errorPrivateAddress = errors.New("address is not public")                            // Host resolves to a local or private network.
+)
+
+// NewFetcher returns a Fetcher with a timeout of 10 seconds and a maximum page size of 5 MB. Like any Fetcher it never
+// connects to loopback, private, link-local, unspecified or multicast addresses.
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Timeout:  10 * time.Second,
+		MaxBytes: maxPageBytes,
+	}
+}
+
+// Fetch takes the URL of a recipe page, fetches the page and returns the recipe in it with SourceLink set to the URL,
+// together with the ParseResult of each ingredient. It returns an error if the URL or its host is not allowed, the
+// page cannot be fetched or is too large, or if it does not contain a recipe.
+func (f *Fetcher) Fetch(link string) (Recipe, []ParseResult, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Recipe{}, nil, errorInvalidURL
+	}
+	if !f.allowed(u.Hostname()) {
+		return Recipe{}, nil, fmt.Errorf("%w: %v", errorHostNotAllowed, u.Hostname())
+	}
+	dialer := &net.Dialer{Timeout: f.Timeout}
+	if !f.private {
+		dialer.Control = checkAddress
+	}
+	client := &http.Client{
+		Timeout: f.Timeout,
+		Transport: &http.Transport{
+			// no proxy, as the address of the proxy would be checked instead of the address of the host
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if host, port, err := net.SplitHostPort(addr); err == nil && f.hosts[host] != "" {
+					addr = net.JoinHostPort(f.hosts[host], port)
+				}
+				return dialer.DialContext(ctx, network, addr)
+			},
+			TLSHandshakeTimeout:   f.Timeout,
+			ResponseHeaderTimeout: f.Timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if !f.allowed(req.URL.Hostname()) {
+				return fmt.Errorf("%w: %v", errorHostNotAllowed, req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return Recipe{}, nil, fmt.Errorf("unable to fetch '%v': %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Recipe{}, nil, fmt.Errorf("unable to fetch '%v': %v", u, resp.Status)
+	}
+	var r io.Reader = resp.Body
+	if f.MaxBytes > 0 {
+		if resp.ContentLength > f.MaxBytes {
+			return Recipe{}, nil, errorPageTooLarge
+		}
+		r = io.LimitReader(resp.Body, f.MaxBytes+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Recipe{}, nil, fmt.Errorf("unable to fetch '%v': %w", u, err)
+	}
+	if f.MaxBytes > 0 && int64(len(data)) > f.MaxBytes {
+		return Recipe{}, nil, errorPageTooLarge
+	}
+	rcp, results, err := importHTML(data)
+	if err != nil {
+		return Recipe{}, nil, err
+	}
+	rcp.SourceLink = u.String()
+	if rcp.Source == "" || strings.HasPrefix(rcp.Source, "http") {
+		rcp.Source = u.Hostname()
+	}
+	return rcp, results, nil
+}
+
+// allowed takes a host and returns true if it is not denied and, if there is an allow list, it is allowed. Hosts that
+// are IP addresses are only allowed if the address is public.
+func (f *Fetcher) allowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, d := range append(denyHosts, f.Deny...) {
+		if matchHost(host, d) {
+			return false
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil && !f.private && !publicIP(ip) {
+		return false
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, a := range f.Allow {
+		if matchHost(host, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAddress is used as Control of the net.Dialer of a Fetcher. It is called after the host is resolved and returns an
+// error if the address that is connected to is not public, so a host cannot point to the local network.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %v", errorPrivateAddress, host)
+	}
+	return nil
+}
+
+// publicIP returns false if the IP address is a loopback, private, link-local, unspecified or multicast address.
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast())
+}
+
+// matchHost returns true if host equals pattern or is a subdomain of it, e.g. "www.example.com" and "example.com".
+func matchHost(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pattern), "."))
+	return pattern != "" && (host == pattern || strings.HasSuffix(host, "."+pattern))
+}
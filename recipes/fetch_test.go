@@ -0,0 +1,110 @@
+package gocookbook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const samplePage = `<html><head><title>Soep</title>
+<script type="application/ld+json">{"@context":"https://schema.org","@type":"Recipe","name":"Tomatensoep",
+"recipeYield":"4","totalTime":"PT40M","recipeIngredient":["1 kg tomaten","1 ui"],
+"recipeInstructions":"Snijd de tomaten.\nKook 30 minuten."}</script></head><body></body></html>`
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/soep", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, samplePage)
+	})
+	mux.HandleFunc("/groot", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, samplePage+strings.Repeat(" ", 2000))
+	})
+	mux.HandleFunc("/traag", func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, samplePage)
+	})
+	mux.HandleFunc("/leeg", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "<html><body>Geen recept</body></html>")
+	})
+	mux.HandleFunc("/verplaatst", func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "/soep", http.StatusFound)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	f := &Fetcher{Timeout: 100 * time.Millisecond, MaxBytes: 1000, private: true}
+	rcp, results, err := f.Fetch(ts.URL + "/soep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcp.Name != "Tomatensoep" || rcp.Portions != 4 || len(results) != 2 || len(rcp.Steps) != 2 {
+		t.Errorf("Wrong recipe. Got: %+v", rcp)
+	}
+	if rcp.SourceLink != ts.URL+"/soep" || rcp.Source != "127.0.0.1" {
+		t.Errorf("Wrong source. Got: '%v', '%v'", rcp.Source, rcp.SourceLink)
+	}
+	if _, _, err := f.Fetch(ts.URL + "/verplaatst"); err != nil {
+		t.Errorf("Redirect failed: %v", err)
+	}
+
+	cases := []struct {
+		f    *Fetcher
+		link string
+		want error
+	}{
+		{f, "ftp://example.com/soep", errorInvalidURL},
+		{f, "soep", errorInvalidURL},
+		{f, ts.URL + "/groot", errorPageTooLarge},
+		{f, ts.URL + "/leeg", errorNoRecipe},
+		{NewFetcher(), ts.URL + "/soep", errorHostNotAllowed},
+		{&Fetcher{Allow: []string{"example.com"}}, ts.URL + "/soep", errorHostNotAllowed},
+		{NewFetcher(), "http://169.254.169.254/latest/meta-data/", errorHostNotAllowed},
+		{NewFetcher(), "http://10.0.0.1/", errorHostNotAllowed},
+		{NewFetcher(), "http://192.168.1.1/", errorHostNotAllowed},
+		{NewFetcher(), "http://127.0.0.2/", errorHostNotAllowed},
+		{NewFetcher(), "http://0.0.0.0/", errorHostNotAllowed},
+		{NewFetcher(), "http://[::ffff:127.0.0.1]/", errorHostNotAllowed},
+		{NewFetcher(), "http://[fe80::1]/", errorHostNotAllowed},
+		{&Fetcher{Deny: []string{"example.com"}}, "http://localhost/", errorHostNotAllowed},
+	}
+	for i, c := range cases {
+		if _, _, err := c.f.Fetch(c.link); !errors.Is(err, c.want) {
+			t.Errorf("Case %v failed for '%v'. Want error '%v', Got: '%v'", i, c.link, c.want, err)
+		}
+	}
+	if _, _, err := f.Fetch(ts.URL + "/traag"); err == nil {
+		t.Errorf("Want timeout error, Got: nil")
+	}
+	if _, _, err := f.Fetch(ts.URL + "/onbekend"); err == nil {
+		t.Errorf("Want error for status 404, Got: nil")
+	}
+	// A host name that resolves to a local address is rejected when connecting
+	u, _ := url.Parse(ts.URL)
+	local := &Fetcher{Timeout: time.Second, hosts: map[string]string{"recepten.test": "127.0.0.1"}}
+	if _, _, err := local.Fetch("http://recepten.test:" + u.Port() + "/soep"); !errors.Is(err, errorPrivateAddress) {
+		t.Errorf("Want error '%v', Got: '%v'", errorPrivateAddress, err)
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	cases := []struct {
+		host, pattern string
+		want          bool
+	}{
+		{"example.com", "example.com", true},
+		{"www.example.com", "example.com", true},
+		{"www.example.com", ".Example.com", true},
+		{"badexample.com", "example.com", false},
+		{"example.com", "", false},
+	}
+	for _, c := range cases {
+		if got := matchHost(c.host, c.pattern); got != c.want {
+			t.Errorf("'%v', '%v': Want %v, Got: %v", c.host, c.pattern, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package gocookbook
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FileFormat string // FileFormat represents a file format of another recipe manager.
+
+// Supported formats for importing and exporting recipes.
+const (
+	Paprika    = FileFormat("paprika")    // Paprika archive (.paprikarecipes): zip file with a gzipped JSON file per recipe.
+	Mealie     = FileFormat("mealie")     // Mealie JSON export: a recipe or a list of recipes.
+	Tandoor    = FileFormat("tandoor")    // Tandoor export: zip file with a zip file per recipe containing recipe.json.
+	MealMaster = FileFormat("mealmaster") // MealMaster text file (.mmf), containing one or more recipes.
+	Cooklang   = FileFormat("cooklang")   // Cooklang file (.cook) with one recipe, or a zip file with a .cook file per recipe.
+	Markdown   = FileFormat("markdown")   // Markdown file (.md) with one recipe, or a zip file with a .md file per recipe.
+)
+
+// FileFormats contains all formats that can be imported and exported.
+var FileFormats = []FileFormat{Paprika, Mealie, Tandoor, MealMaster, Cooklang, Markdown}
+
+var errorUnknownFormat = errors.New("unknown format") // Format is not supported.
+
+// ImportReport contains the result of an import.
+type ImportReport struct {
+	Format   FileFormat // Format that was imported.
+	Recipes  int        // Number of recipes imported.
+	Warnings []string   // Issues found while importing, e.g. ingredients that are not fully recognised.
+	Skipped  []string   // Fields in the import that are not stored, sorted.
+
+	unpacked int64 // Number of bytes decompressed from the imported file so far.
+}
+
+// Limits for imported files, so a file that is very large, or very large when decompressed (a zip bomb), cannot
+// exhaust the memory of the server.
+const (
+	maxImportBytes   = 50 << 20  // Maximum size of an imported file.
+	maxEntryBytes    = 10 << 20  // Maximum size of one decompressed file in an imported archive.
+	maxUnpackedBytes = 100 << 20 // Maximum size of all decompressed files in an imported archive together.
+)
+
+var errorImportTooLarge = errors.New("file is too large") // Imported file or a file in it exceeds a limit.
+
+// limitReader reads from R like io.LimitReader, but returns errorImportTooLarge once more than N bytes are read.
+type limitReader struct {
+	R io.Reader // Underlying reader.
+	N int64     // Number of bytes that can still be read.
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.N+1 {
+		p = p[:l.N+1]
+	}
+	n, err := l.R.Read(p)
+	if l.N -= int64(n); l.N < 0 {
+		return n, errorImportTooLarge
+	}
+	return n, err
+}
+
+// unpack reads a decompressed file from an imported archive, e.g. a file in a zip file. It returns
+// errorImportTooLarge if the file is larger than maxEntryBytes, or if all files that are unpacked for the ImportReport
+// together are larger than maxUnpackedBytes.
+func (ir *ImportReport) unpack(r io.Reader) ([]byte, error) {
+	n := min(int64(maxEntryBytes), maxUnpackedBytes-ir.unpacked)
+	data, err := io.ReadAll(&limitReader{r, n})
+	ir.unpacked += int64(len(data))
+	return data, err
+}
+
+// warn adds a warning for recipe name to the ImportReport.
+func (ir *ImportReport) warn(name, format string, a ...interface{}) {
+	ir.Warnings = append(ir.Warnings, fmt.Sprintf("%v: %v", name, fmt.Sprintf(format, a...)))
+}
+
+// skip adds the fields to the skipped fields of the ImportReport, if they are not already in it.
+func (ir *ImportReport) skip(fields ...string) {
+	for _, f := range fields {
+		i := sort.SearchStrings(ir.Skipped, f)
+		if i < len(ir.Skipped) && ir.Skipped[i] == f {
+			continue
+		}
+		ir.Skipped = append(ir.Skipped, "")
+		copy(ir.Skipped[i+1:], ir.Skipped[i:])
+		ir.Skipped[i] = f
+	}
+}
+
+// skipUnknown adds all keys in m that are not in known to the skipped fields, ignoring empty values.
+func (ir *ImportReport) skipUnknown(m map[string]interface{}, known ...string) {
+	for k, v := range m {
+		if isEmpty(v) || containsFold(known, k) {
+			continue
+		}
+		ir.skip(k)
+	}
+}
+
+// ingredients takes the lines with ingredients of recipe name, parses them and returns the Ingredients. Lines that are
+// not fully recognised are added as warning to the ImportReport.
+func (ir *ImportReport) ingredients(name string, lines []string) []Ingredient {
+	xi := []Ingredient{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		r := ParseIngredient(line)
+		// ingredients without amount, e.g. "zout", are common and not reported
+		if r.Confidence < minConfidence && r.Ingredient.Amount > 0 {
+			ir.warn(name, "ingredient '%v' not fully recognised", line)
+		}
+		xi = append(xi, r.Ingredient)
+	}
+	return xi
+}
+
+const minConfidence = 0.7 // Parse results with a lower confidence are reported when importing.
+
+// Import takes a FileFormat and a reader with a file in that FileFormat and returns the recipes in it together with a
+// report of the import. It returns an error if the format is unknown or the file cannot be read.
+func Import(f FileFormat, r io.Reader) ([]Recipe, ImportReport, error) {
+	ir := ImportReport{Format: f}
+	var rcps []Recipe
+	var err error
+	r = &limitReader{r, maxImportBytes}
+	switch f {
+	case Paprika:
+		rcps, err = importPaprika(r, &ir)
+	case Mealie:
+		rcps, err = importMealie(r, &ir)
+	case Tandoor:
+		rcps, err = importTandoor(r, &ir)
+	case MealMaster:
+		rcps, err = importMealMaster(r, &ir)
+	case Cooklang:
+		rcps, err = importCooklang(r, &ir)
+	case Markdown:
+		rcps, err = importMarkdown(r, &ir)
+	default:
+		return nil, ir, errorUnknownFormat
+	}
+	if err != nil {
+		return nil, ir, fmt.Errorf("unable to import %v: %w", f, err)
+	}
+	for i := range rcps {
+		if rcps[i].Tags == nil {
+			rcps[i].Tags = []string{}
+		}
+		if rcps[i].Name == "" {
+			rcps[i].Name = fmt.Sprintf("Recept %v", i+1)
+			ir.warn(rcps[i].Name, "recipe has no name")
+		}
+	}
+	ir.Recipes = len(rcps)
+	return rcps, ir, nil
+}
+
+// Export takes a FileFormat and writes the recipes in that FileFormat to w. It returns an error if the format is
+// unknown or the recipes cannot be written.
+func Export(f FileFormat, w io.Writer, rcps []Recipe) error {
+	switch f {
+	case Paprika:
+		return exportPaprika(w, rcps)
+	case Mealie:
+		return exportMealie(w, rcps)
+	case Tandoor:
+		return exportTandoor(w, rcps)
+	case MealMaster:
+		return exportMealMaster(w, rcps)
+	case Cooklang:
+		return exportCooklang(w, rcps)
+	case Markdown:
+		return exportMarkdown(w, rcps)
+	}
+	return errorUnknownFormat
+}
+
+// Extension returns the file extension for an export in FileFormat f with the given number of recipes.
+func (f FileFormat) Extension(n int) string {
+	switch f {
+	case Paprika:
+		return ".paprikarecipes"
+	case Mealie:
+		return ".json"
+	case MealMaster:
+		return ".mmf"
+	case Cooklang:
+		if n == 1 {
+			return ".cook"
+		}
+	case Markdown:
+		if n == 1 {
+			return ".md"
+		}
+	}
+	return ".zip"
+}
+
+var reTextDuration = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(d|dag|dagen|days?|h|hrs?|hours?|u|uur|uren|m|mins?|minutes?|minuten|minuut)\b`) // Part of a duration in text, e.g. "1 hr".
+
+// parseTextDuration takes a duration as text, e.g. "1 hr 30 mins", "45 minuten", "PT45M" or "45", and returns it as
+// time.Duration. A number without unit is regarded as minutes. It returns zero if s is not a duration.
+func parseTextDuration(s string) time.Duration {
+	s = strings.TrimSpace(s)
+	if d := parseISODuration(s); d > 0 {
+		return d
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(time.Minute))
+	}
+	var d time.Duration
+	for _, m := range reTextDuration.FindAllStringSubmatch(s, -1) {
+		f, _ := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		unit := time.Minute
+		switch strings.ToLower(m[2])[0] {
+		case 'd':
+			unit = 24 * time.Hour
+		case 'h', 'u':
+			unit = time.Hour
+		}
+		d += time.Duration(f * float64(unit))
+	}
+	return d
+}
+
+// isEmpty returns true if v is a zero JSON value: nil, false, zero, an empty string, list or object.
+func isEmpty(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !x
+	case float64:
+		return x == 0
+	case string:
+		return x == ""
+	case []interface{}:
+		return len(x) == 0
+	case map[string]interface{}:
+		return len(x) == 0
+	}
+	return false
+}
+
+// textLines takes a text and returns the non-empty lines in it.
+func textLines(s string) []string {
+	xs := []string{}
+	for _, line := range textToLines(s) {
+		if line = strings.TrimSpace(line); line != "" {
+			xs = append(xs, line)
+		}
+	}
+	return xs
+}
@@ -0,0 +1,330 @@
+package gocookbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var sampleRcp = Recipe{
+	Name: "Pannenkoeken",
+	Ingrs: []Ingredient{
+		{Amount: 250, Unit: gram, Item: "bloem"},
+		{Amount: 500, Unit: ml, Item: "melk"},
+		{Amount: 2, Unit: pcs, Item: "eieren"},
+		{Amount: 1.5, Unit: tbsp, Item: "boter", Notes: "gesmolten"},
+	},
+	Steps:      []string{"Meng de bloem met de melk.", "Klop de eieren en de boter erdoor.", "Bak dunne pannenkoeken."},
+	Tags:       []string{"Ontbijt", "Zoet"},
+	Portions:   4,
+	Dur:        75 * time.Minute,
+	Notes:      "Lekker met stroop.",
+	SourceLink: "https://example.com/pannenkoeken",
+}
+
+func TestFormatsRoundTrip(t *testing.T) {
+	for _, f := range FileFormats {
+		var buf bytes.Buffer
+		if err := Export(f, &buf, []Recipe{sampleRcp}); err != nil {
+			t.Fatalf("%v: export failed: %v", f, err)
+		}
+		rcps, ir, err := Import(f, &buf)
+		if err != nil {
+			t.Fatalf("%v: import failed: %v", f, err)
+		}
+		if len(rcps) != 1 || ir.Recipes != 1 {
+			t.Fatalf("%v: want 1 recipe, Got: %v", f, len(rcps))
+		}
+		got := rcps[0]
+		if got.Name != sampleRcp.Name || got.Portions != sampleRcp.Portions {
+			t.Errorf("%v: wrong name or portions. Got: '%v', %v", f, got.Name, got.Portions)
+		}
+		if f != MealMaster && got.Dur != sampleRcp.Dur {
+			t.Errorf("%v: wrong duration. Got: %v", f, got.Dur)
+		}
+		if !reflect.DeepEqual(got.Steps, sampleRcp.Steps) {
+			t.Errorf("%v: wrong steps.\nGot:\t%q\nWant:\t%q", f, got.Steps, sampleRcp.Steps)
+		}
+		if !reflect.DeepEqual(got.Tags, sampleRcp.Tags) {
+			t.Errorf("%v: wrong tags. Got: %q", f, got.Tags)
+		}
+		if !strings.Contains(got.Notes, sampleRcp.Notes) {
+			t.Errorf("%v: notes missing. Got: %q", f, got.Notes)
+		}
+		if len(got.Ingrs) != len(sampleRcp.Ingrs) {
+			t.Fatalf("%v: wrong number of ingredients. Got: %+v", f, got.Ingrs)
+		}
+		for i, w := range sampleRcp.Ingrs {
+			if g := got.Ingrs[i]; g.Amount != w.Amount || g.Unit != w.Unit || g.Item != w.Item || g.Notes != w.Notes {
+				t.Errorf("%v: ingredient %v differs.\nGot:\t%+v\nWant:\t%+v", f, i, g, w)
+			}
+		}
+		if len(ir.Warnings) != 0 {
+			t.Errorf("%v: unexpected warnings: %q", f, ir.Warnings)
+		}
+	}
+}
+
+func TestFormatsMultiple(t *testing.T) {
+	second := sampleRcp
+	second.Name = "Wentelteefjes"
+	for _, f := range FileFormats {
+		var buf bytes.Buffer
+		if err := Export(f, &buf, []Recipe{sampleRcp, second}); err != nil {
+			t.Fatalf("%v: export failed: %v", f, err)
+		}
+		rcps, _, err := Import(f, &buf)
+		if err != nil {
+			t.Fatalf("%v: import failed: %v", f, err)
+		}
+		if len(rcps) != 2 || rcps[1].Name != "Wentelteefjes" {
+			t.Errorf("%v: want 2 recipes, Got: %v", f, len(rcps))
+		}
+	}
+	if _, _, err := Import(FileFormat("onbekend"), strings.NewReader("")); err != errorUnknownFormat {
+		t.Errorf("Want error '%v', Got: '%v'", errorUnknownFormat, err)
+	}
+}
+
+func TestImportPaprika(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create("Soep.paprikarecipe")
+	gw := gzip.NewWriter(f)
+	gw.Write([]byte(`{"name":"Soep","ingredients":"1 kg tomaten\n1 ui\n2 handen noten of 100 g amandelen","directions":"Snijd alles.\n\nKook 30 minuten.",
+		"servings":"4 personen","prep_time":"10 min","cook_time":"30 mins","categories":["Soep"],"source":"Oma",
+		"created":"2020-05-13 19:09:01","photo_data":"abc","rating":5,"uid":"X"}`))
+	gw.Close()
+	zw.Close()
+	rcps, ir, err := Import(Paprika, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcp := rcps[0]
+	if rcp.Name != "Soep" || rcp.Portions != 4 || rcp.Dur != 40*time.Minute || len(rcp.Ingrs) != 3 || len(rcp.Steps) != 2 || rcp.Source != "Oma" || rcp.Created.Year() != 2020 {
+		t.Errorf("Wrong recipe. Got: %+v", rcp)
+	}
+	if want := []string{"photo_data", "rating"}; !reflect.DeepEqual(ir.Skipped, want) {
+		t.Errorf("Wrong skipped fields. Got: %q, Want: %q", ir.Skipped, want)
+	}
+	if len(ir.Warnings) != 1 || !strings.Contains(ir.Warnings[0], "2 handen noten") {
+		t.Errorf("Want warning for unrecognised ingredient, Got: %q", ir.Warnings)
+	}
+}
+
+func TestImportMealie(t *testing.T) {
+	s := `{"id":"1","name":"Soep","description":"Lekker.","recipeServings":4,"totalTime":"1 hour",
+	"recipeIngredient":[
+		{"quantity":1,"unit":{"name":"kilogram"},"food":{"name":"tomaten"},"note":"rijp"},
+		{"quantity":0,"unit":null,"food":null,"note":"zout"},
+		{"originalText":"2 el olijfolie"}],
+	"recipeInstructions":[{"text":"Snijd de tomaten."},{"text":"Kook 30 minuten."}],
+	"tags":[{"name":"Soep"}],"recipeCategory":[{"name":"Diner"}],"orgURL":"https://example.com/soep",
+	"nutrition":{"calories":"100"},"image":"x.jpg"}`
+	rcps, ir, err := Import(Mealie, strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcp := rcps[0]
+	if rcp.Portions != 4 || rcp.Dur != time.Hour || rcp.Notes != "Lekker." || !reflect.DeepEqual(rcp.Tags, []string{"Soep", "Diner"}) {
+		t.Errorf("Wrong recipe. Got: %+v", rcp)
+	}
+	want := []Ingredient{{Amount: 1, Unit: kilo, Item: "tomaten", Notes: "rijp"}, {Item: "zout"}, {Amount: 2, Unit: tbsp, Item: "olijfolie"}}
+	for i, w := range want {
+		if g := rcp.Ingrs[i]; g.Amount != w.Amount || g.Unit != w.Unit || g.Item != w.Item || g.Notes != w.Notes {
+			t.Errorf("Ingredient %v differs.\nGot:\t%+v\nWant:\t%+v", i, g, w)
+		}
+	}
+	if want := []string{"image", "nutrition"}; !reflect.DeepEqual(ir.Skipped, want) {
+		t.Errorf("Wrong skipped fields. Got: %q, Want: %q", ir.Skipped, want)
+	}
+}
+
+func TestImportTandoor(t *testing.T) {
+	s := `{"name":"Soep","description":"","keywords":[{"name":"Soep"}],"working_time":10,"waiting_time":30,"servings":2,
+	"steps":[{"instruction":"Snijd de tomaten.","ingredients":[
+		{"food":{"name":"tomaten"},"unit":{"name":"g"},"amount":500,"note":""},
+		{"food":null,"unit":null,"amount":0,"note":"Kruiden","is_header":true},
+		{"food":{"name":"peper"},"unit":{"name":"g"},"amount":0,"note":"","no_amount":true}]},
+	{"instruction":"Kook 30 minuten.","ingredients":[]}],"nutrition":{"calories":1}}`
+	rcps, ir, err := Import(Tandoor, strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcp := rcps[0]
+	if rcp.Dur != 40*time.Minute || rcp.Portions != 2 || len(rcp.Steps) != 2 || len(rcp.Ingrs) != 2 || rcp.Ingrs[0].Amount != 500 || rcp.Ingrs[1].Item != "peper" {
+		t.Errorf("Wrong recipe. Got: %+v", rcp)
+	}
+	if len(ir.Warnings) != 1 || !strings.Contains(ir.Warnings[0], "Kruiden") || !reflect.DeepEqual(ir.Skipped, []string{"nutrition"}) {
+		t.Errorf("Wrong report. Got: %+v", ir)
+	}
+}
+
+func TestImportLimits(t *testing.T) {
+	recipe := []byte(`{"name":"Soep","steps":[]}`)
+	large := append(recipe, bytes.Repeat([]byte(" "), maxEntryBytes)...)
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(large)
+	gw.Close()
+	cases := []struct {
+		f    FileFormat
+		data []byte
+		n    int // Number of recipes if there is no error.
+		err  error
+	}{
+		{Tandoor, zipData(map[string][]byte{"1.zip": zipData(map[string][]byte{"recipe.json": recipe})}), 1, nil},
+		{Tandoor, zipData(map[string][]byte{"1.zip": zipData(map[string][]byte{"2.zip": zipData(map[string][]byte{"recipe.json": recipe})})}), 0, nil},
+		{Tandoor, zipData(map[string][]byte{"recipe.json": large}), 0, errorImportTooLarge},
+		{Mealie, zipData(map[string][]byte{"soep.json": large}), 0, errorImportTooLarge},
+		{Cooklang, zipData(map[string][]byte{"soep.cook": large}), 0, errorImportTooLarge},
+		{Markdown, zipData(map[string][]byte{"soep.md": large}), 0, errorImportTooLarge},
+		{Paprika, zipData(map[string][]byte{"Soep.paprikarecipe": gz.Bytes()}), 0, errorImportTooLarge},
+	}
+	for i, c := range cases {
+		rcps, _, err := Import(c.f, bytes.NewReader(c.data))
+		if !errors.Is(err, c.err) || err == nil && len(rcps) != c.n {
+			t.Errorf("Case %v failed for %v. Want %v recipes, error: %v, Got: %v, %v", i, c.f, c.n, c.err, len(rcps), err)
+		}
+	}
+	// The decompressed files together are limited as well
+	files := map[string][]byte{}
+	for i := 0; i < maxUnpackedBytes/maxEntryBytes+1; i++ {
+		files[fmt.Sprintf("%v.json", i)] = large[:maxEntryBytes]
+	}
+	if _, _, err := Import(Mealie, bytes.NewReader(zipData(files))); !errors.Is(err, errorImportTooLarge) {
+		t.Errorf("Want error: %v, Got: %v", errorImportTooLarge, err)
+	}
+}
+
+// zipData returns a zip file with the given files by name.
+func zipData(files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, _ := zw.Create(name)
+		f.Write(data)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestImportMealMaster(t *testing.T) {
+	s := `Some text before the recipe
+
+MMMMM----- Recipe via Meal-Master (tm) v8.05
+ 
+      Title: Chili
+ Categories: Main dish, Beef
+      Yield: 6 servings
+ 
+      1 lb ground beef                         2 T  chili powder
+  1 1/2 c  onions, chopped
+           -finely
+      1 pk taco seasoning
+ 
+MMMMM--------------------------SAUCE---------------------------
+      1 cn tomato sauce
+ 
+  Brown the beef. Add the onions and
+  cook until soft.
+ 
+  Stir in the rest and simmer.
+ 
+MMMMM
+`
+	rcps, ir, err := Import(MealMaster, strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rcps) != 1 {
+		t.Fatalf("Want 1 recipe, Got: %v", len(rcps))
+	}
+	rcp := rcps[0]
+	if rcp.Name != "Chili" || rcp.Portions != 6 || !reflect.DeepEqual(rcp.Tags, []string{"Main dish", "Beef"}) {
+		t.Errorf("Wrong recipe. Got: %+v", rcp)
+	}
+	want := []Ingredient{
+		{Amount: 1, Unit: lb, Item: "ground beef"},
+		{Amount: 2, Unit: tbsp, Item: "chili powder"},
+		{Amount: 1.5, Unit: cup, Item: "onions", Notes: "chopped finely"},
+		{Amount: 1, Unit: pcs, Item: "pk taco seasoning"},
+		{Amount: 1, Unit: can, Item: "tomato sauce"},
+	}
+	if len(rcp.Ingrs) != len(want) {
+		t.Fatalf("Wrong ingredients. Got: %+v", rcp.Ingrs)
+	}
+	for i, w := range want {
+		if g := rcp.Ingrs[i]; g.Amount != w.Amount || g.Unit != w.Unit || g.Item != w.Item || g.Notes != w.Notes {
+			t.Errorf("Ingredient %v differs.\nGot:\t%+v\nWant:\t%+v", i, g, w)
+		}
+	}
+	if want := []string{"Brown the beef. Add the onions and cook until soft.", "Stir in the rest and simmer."}; !reflect.DeepEqual(rcp.Steps, want) {
+		t.Errorf("Wrong steps.\nGot:\t%q\nWant:\t%q", rcp.Steps, want)
+	}
+	if len(ir.Warnings) == 0 || !strings.Contains(strings.Join(ir.Warnings, " "), "'pk'") {
+		t.Errorf("Want warning for unknown unit, Got: %q", ir.Warnings)
+	}
+}
+
+func TestImportCooklang(t *testing.T) {
+	s := `---
+title: Soep
+servings: 2
+tags: [soep, snel]
+cuisine: Italiaans
+---
+-- een opmerking
+Snijd de @tomaten{500%g} en de @ui{1}(gesnipperd) in een #grote pan{}.
+Voeg @zout toe.
+
+Kook ~{25%minutes} [- niet te lang -] en voeg nog wat @zout toe.
+
+> Lekker met brood.
+`
+	rcps, _, err := Import(Cooklang, strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcp := rcps[0]
+	if rcp.Name != "Soep" || rcp.Portions != 2 || !reflect.DeepEqual(rcp.Tags, []string{"soep", "snel", "Italiaans"}) || rcp.Notes != "Lekker met brood." {
+		t.Errorf("Wrong recipe. Got: %+v", rcp)
+	}
+	if want := []string{"Snijd de tomaten en de ui in een grote pan. Voeg zout toe.", "Kook 25 minutes en voeg nog wat zout toe."}; !reflect.DeepEqual(rcp.Steps, want) {
+		t.Errorf("Wrong steps.\nGot:\t%q\nWant:\t%q", rcp.Steps, want)
+	}
+	want := []Ingredient{{Amount: 500, Unit: gram, Item: "tomaten"}, {Amount: 1, Unit: pcs, Item: "ui", Notes: "gesnipperd"}, {Item: "zout"}}
+	if len(rcp.Ingrs) != len(want) {
+		t.Fatalf("Wrong ingredients. Got: %+v", rcp.Ingrs)
+	}
+	for i, w := range want {
+		if g := rcp.Ingrs[i]; g.Amount != w.Amount || g.Unit != w.Unit || g.Item != w.Item || g.Notes != w.Notes {
+			t.Errorf("Ingredient %v differs.\nGot:\t%+v\nWant:\t%+v", i, g, w)
+		}
+	}
+}
+
+func TestParseTextDuration(t *testing.T) {
+	cases := []struct {
+		s    string
+		want time.Duration
+	}{
+		{"1 hr 30 mins", 90 * time.Minute},
+		{"45 minuten", 45 * time.Minute},
+		{"1 uur", time.Hour},
+		{"PT20M", 20 * time.Minute},
+		{"25", 25 * time.Minute},
+		{"", 0},
+	}
+	for _, c := range cases {
+		if got := parseTextDuration(c.s); got != c.want {
+			t.Errorf("'%v': Want %v, Got: %v", c.s, c.want, got)
+		}
+	}
+}
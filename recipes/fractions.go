@@ -0,0 +1,162 @@
+package gocookbook
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type AmountFormat string // AmountFormat represents how amounts of an Ingredient are displayed.
+
+// Different formats for displaying amounts.
+const (
+	FormatKitchen = AmountFormat("keuken")   // Fractions for cups and spoons, decimals for metric units.
+	FormatDecimal = AmountFormat("decimaal") // Decimals for all units.
+)
+
+// AmountFormats contains all formats that can be selected by a user.
+var AmountFormats = []AmountFormat{FormatKitchen, FormatDecimal}
+
+// vulgarFractions contains the value of all Unicode vulgar fraction characters.
+var vulgarFractions = map[rune]float64{
+	'½': 1.0 / 2,
+	'⅓': 1.0 / 3,
+	'⅔': 2.0 / 3,
+	'¼': 1.0 / 4,
+	'¾': 3.0 / 4,
+	'⅕': 1.0 / 5,
+	'⅖': 2.0 / 5,
+	'⅗': 3.0 / 5,
+	'⅘': 4.0 / 5,
+	'⅙': 1.0 / 6,
+	'⅚': 5.0 / 6,
+	'⅐': 1.0 / 7,
+	'⅛': 1.0 / 8,
+	'⅜': 3.0 / 8,
+	'⅝': 5.0 / 8,
+	'⅞': 7.0 / 8,
+	'⅑': 1.0 / 9,
+	'⅒': 1.0 / 10,
+	'↉': 0,
+}
+
+// kitchenFractions contains the fractions that are used when printing an amount, in ascending order.
+var kitchenFractions = []struct {
+	f float64
+	s string
+}{
+	{1.0 / 8, "⅛"},
+	{1.0 / 4, "¼"},
+	{1.0 / 3, "⅓"},
+	{3.0 / 8, "⅜"},
+	{1.0 / 2, "½"},
+	{5.0 / 8, "⅝"},
+	{2.0 / 3, "⅔"},
+	{3.0 / 4, "¾"},
+	{7.0 / 8, "⅞"},
+}
+
+var errorNoNumber = errors.New("not a number") // Text cannot be parsed into an amount.
+
+// parseNumber takes a string and returns the amount it represents. Next to regular numbers it accepts
+// Unicode vulgar fractions ("½"), numbers followed by a fraction ("1½") and fractions with a slash ("1/2").
+func parseNumber(s string) (float64, error) {
+	if s == "" {
+		return 0, errorNoNumber
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return 0, errorNoNumber
+		}
+		return f, nil
+	}
+	// Number ending with a vulgar fraction, e.g. "1½" or "½"
+	xr := []rune(s)
+	if frac, ok := vulgarFractions[xr[len(xr)-1]]; ok {
+		if len(xr) == 1 {
+			return frac, nil
+		}
+		if whole, err := strconv.Atoi(string(xr[:len(xr)-1])); err == nil && whole >= 0 {
+			return float64(whole) + frac, nil
+		}
+		return 0, errorNoNumber
+	}
+	// Fraction with a (fraction) slash, e.g. "1/2" or "1⁄2"
+	s = strings.ReplaceAll(s, "⁄", "/")
+	if x := strings.Index(s, "/"); x != -1 {
+		num, err1 := strconv.Atoi(s[:x])
+		den, err2 := strconv.Atoi(s[x+1:])
+		if err1 == nil && err2 == nil && num >= 0 && den > 0 {
+			return float64(num) / float64(den), nil
+		}
+	}
+	return 0, errorNoNumber
+}
+
+// isFraction takes a string and returns true if it only contains a fraction, e.g. "1/2" or "½", which can follow a
+// whole number to form a mixed number like "1 1/2".
+func isFraction(s string) bool {
+	xr := []rune(s)
+	if len(xr) == 1 {
+		_, ok := vulgarFractions[xr[0]]
+		return ok
+	}
+	if !strings.ContainsAny(s, "/⁄") {
+		return false
+	}
+	_, err := parseNumber(s)
+	return err == nil
+}
+
+// formatAmount takes an amount, the Unit of that amount and a format and returns the amount as a string. With
+// FormatKitchen amounts in cups and spoons are shown as kitchen fractions (e.g. 1⅛), all other amounts are shown
+// as decimals, with more precision for small amounts.
+func formatAmount(f float64, u Unit, af AmountFormat) string {
+	if af != FormatDecimal && (u == cup || u == tbsp || u == tsp) {
+		return fraction(f)
+	}
+	return decimal(f)
+}
+
+// fraction takes an amount and returns it as a whole number with the closest kitchen fraction, e.g. 1.12 returns "1⅛".
+func fraction(f float64) string {
+	whole := math.Floor(f)
+	rest := f - whole
+	s := ""
+	switch {
+	case rest < kitchenFractions[0].f/2:
+	case rest >= 1-(1-kitchenFractions[len(kitchenFractions)-1].f)/2:
+		whole++
+	default:
+		best := kitchenFractions[0]
+		for _, v := range kitchenFractions[1:] {
+			if math.Abs(rest-v.f) < math.Abs(rest-best.f) {
+				best = v
+			}
+		}
+		s = best.s
+	}
+	switch {
+	case whole == 0 && s == "":
+		return decimal(f)
+	case whole == 0:
+		return s
+	}
+	return strconv.FormatFloat(whole, 'f', -1, 64) + s
+}
+
+// decimal takes an amount and returns it as a string with a sensible number of decimals: none from 100 onwards, one
+// from 1 onwards and two for smaller amounts.
+func decimal(f float64) string {
+	var x float64
+	switch a := math.Abs(f); {
+	case a >= 100:
+		x = math.Round(f)
+	case a >= 1:
+		x = round(f)
+	default:
+		x = math.Round(f*100) / 100
+	}
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
@@ -0,0 +1,80 @@
+package gocookbook
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	cases := []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"2", 2, true},
+		{"1.5", 1.5, true},
+		{"½", 0.5, true},
+		{"⅓", 1.0 / 3, true},
+		{"¾", 0.75, true},
+		{"⅛", 0.125, true},
+		{"1½", 1.5, true},
+		{"2⅔", 2 + 2.0/3, true},
+		{"1/2", 0.5, true},
+		{"3⁄4", 0.75, true},
+		{"1/0", 0, false},
+		{"a½", 0, false},
+		{"el", 0, false},
+		{"", 0, false},
+	}
+	for i, c := range cases {
+		got, err := parseNumber(c.s)
+		if (err == nil) != c.ok || math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("Case %v failed for '%v'. Want: %v (%v), Got: %v (%v)", i, c.s, c.want, c.ok, got, err)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	cases := []struct {
+		f    float64
+		u    Unit
+		af   AmountFormat
+		want string
+	}{
+		{0.5, cup, FormatKitchen, "½"},
+		{1.0 / 3, cup, FormatKitchen, "⅓"},
+		{1.125, cup, FormatKitchen, "1⅛"},
+		{0.3, tbsp, FormatKitchen, "⅓"},
+		{2.98, tsp, FormatKitchen, "3"},
+		{0.01, tsp, FormatKitchen, "0.01"},
+		{0.5, cup, FormatDecimal, "0.5"},
+		{1.0 / 3, cup, FormatDecimal, "0.33"},
+		{1.25, kilo, FormatKitchen, "1.3"},
+		{12.34, gram, FormatKitchen, "12.3"},
+		{123.4, gram, FormatKitchen, "123"},
+	}
+	for i, c := range cases {
+		if got := formatAmount(c.f, c.u, c.af); got != c.want {
+			t.Errorf("Case %v failed. Want: %v, Got: %v", i, c.want, got)
+		}
+	}
+}
+
+func TestTextToIngrdsFractions(t *testing.T) {
+	cases := []struct {
+		s    string
+		want float64
+	}{
+		{"⅓ cup suiker", 1.0 / 3},
+		{"1½ el olie", 1.5},
+		{"1 1/2 cup bloem", 1.5},
+		{"2 ½ tl zout", 2.5},
+		{"3", 3},
+	}
+	for i, c := range cases {
+		got := TextToIngrds(c.s)
+		if len(got) != 1 || math.Abs(got[0].Amount-c.want) > 1e-9 {
+			t.Errorf("Case %v failed for '%v'. Want: %v, Got: %+v", i, c.s, c.want, got)
+		}
+	}
+}
@@ -10,29 +10,36 @@ type Unit string // Unit represents a Unit of Measurement.
 
 // Ingrident represents an ingredient for a recipe.
 type Ingredient struct {
-	Amount   float64 // Amount of units.
-	Unit     Unit    // Unit of Measurement (UOM), e.g. grams etc.
-	Item     string  // Item itself, e.g. a banana.
-	Notes    string  // Instruction for preparation, e.g. cooked.
-	AltUnits string  // Alternative UOM and the required amount for that unit.
+	Amount    float64 // Amount of units, or the minimum amount if it is a range.
+	AmountMax float64 // Maximum amount of units if it is a range, e.g. 3 for "2-3 cloves garlic". Zero if not a range.
+	Approx    bool    // Amount is approximate, e.g. "about 200 g".
+	Unit      Unit    // Unit of Measurement (UOM), e.g. grams etc.
+	Item      string  // Item itself, e.g. a banana.
+	Notes     string  // Instruction for preparation, e.g. cooked.
+	AltUnits  string  // Alternative UOM and the required amount for that unit.
+	Fixed     bool    // Amount is not scaled when adjusting portions, e.g. a pinch of salt or 1 bay leaf.
+	Written   string  `json:"-"` // Amount and unit as written in the recipe, if converted to another System for display.
+	Canonical string  // Name of the canonical ingredient in the catalogue (Densities) the item is linked to.
 }
 
-var convTable = map[string]float64{} // convTable contains the item conversion from 1 gram to ml.
-
-// Different types of volumes and masses used for conversion. Note: don't change the actual string without changing the existing data and adding it to the var units.
+// Different types of volumes and masses used for conversion. Note: don't change the actual string without changing the existing data and the definition in defaultUnits.
 const (
-	gram = Unit("g")
-	cup  = Unit("cup")
-	ml   = Unit("ml")
-	tbsp = Unit("el")
-	tsp  = Unit("tl")
-	pcs  = Unit("stuks")
+	gram  = Unit("g")
+	kilo  = Unit("kg")
+	cup   = Unit("cup")
+	ml    = Unit("ml")
+	liter = Unit("l")
+	tbsp  = Unit("el")
+	tsp   = Unit("tl")
+	pcs   = Unit("stuks")
 )
 
+// Thresholds used by promote to switch to a larger unit after scaling.
 var (
-	tbspToMl = 14.7867648 // ml for 1 tablespoon.
-	tspToMl  = 4.92892159 // ml for 1 teaspoon.
-	cuptoMl  = 236.588237 // ml for 1 cup.
+	maxTsp  = 3.0    // From 3 teaspoons onwards tablespoons are used.
+	maxTbsp = 4.0    // Above 4 tablespoons milliliters are used.
+	maxGram = 1000.0 // From 1000 grams onwards kilograms are used.
+	maxMl   = 1000.0 // From 1000 milliliters onwards liters are used.
 )
 
 // NewIngredient takes all parameters for creating an Ingredient, validates all parameters and returns it as an Ingredient.
@@ -51,54 +58,111 @@ func NewIngredient(amount float64, unit Unit, item, notes string) Ingredient {
 // altUnits takes a pointer to an Ingredient, determines the amount for alternative Unit of Measurements and updates the combined string in the field AltUnites
 func (i *Ingredient) altUnits() {
 	var xs []string
-	switch i.Unit {
-	case gram:
-		m := round(gramToMl(i.Item, i.Amount))
-		if m != 0.0 {
-			c := round(m / cuptoMl)
-			xs = append(xs, fmt.Sprintf("%v %v", m, ml), fmt.Sprintf("%v %v", c, cup))
+	// alt returns the amount f of the minimum in another unit, together with the maximum if it is a range
+	alt := func(f float64, u Unit) string {
+		if i.Amount > 0 && i.AmountMax > i.Amount {
+			return fmt.Sprintf("%v-%v %v", round(f), round(f*i.AmountMax/i.Amount), u)
+		}
+		return fmt.Sprintf("%v %v", round(f), u)
+	}
+	d, _ := Registry.Get(i.Unit)
+	switch {
+	case d.Dim == Count:
+		if g, ok := i.Grams(); ok {
+			xs = append(xs, "≈ "+alt(g, gram))
 		}
-	case cup:
-		m := round(i.Amount * cuptoMl)
-		if m != 0.0 {
-			xs = append(xs, fmt.Sprintf("%v %v", m, ml))
-			g := round(mlToGram(i.Item, m))
-			if g != 0.0 {
-				xs = append(xs, fmt.Sprintf("%v %v", g, gram))
-			}
+	case d.Factor == 0:
+	case d.Dim == Mass:
+		g := i.Amount * d.Factor
+		if i.Unit != gram {
+			xs = append(xs, alt(g, gram))
 		}
-	case ml:
-		c := round(i.Amount / cuptoMl)
-		xs = append(xs, fmt.Sprintf("%v %v", c, cup))
-		g := round(mlToGram(i.Item, i.Amount))
-		if g != 0.0 {
-			xs = append(xs, fmt.Sprintf("%v %v", g, gram))
+		m := gramToMl(i.key(), g)
+		if round(m) != 0.0 {
+			xs = append(xs, alt(m, ml), alt(m/cuptoMl, cup))
 		}
-	case tbsp:
-		m := round(i.Amount * tbspToMl)
-		c := round(1 / cuptoMl * m)
-		xs = append(xs, fmt.Sprintf("%v %v", m, ml), fmt.Sprintf("%v %v", c, cup))
-		g := round(mlToGram(i.Item, m))
-		if g != 0.0 {
-			xs = append(xs, fmt.Sprintf("%v %v", g, gram))
+	case d.Dim == Volume:
+		m := i.Amount * d.Factor
+		if i.Unit != ml {
+			xs = append(xs, alt(m, ml))
 		}
-	case tsp:
-		m := round(i.Amount * tspToMl)
-		c := round(1 / cuptoMl * m)
-		xs = append(xs, fmt.Sprintf("%v %v", m, ml), fmt.Sprintf("%v %v", c, cup))
-		g := round(mlToGram(i.Item, m))
-		if g != 0.0 {
-			xs = append(xs, fmt.Sprintf("%v %v", g, gram))
+		if i.Unit != cup {
+			xs = append(xs, alt(m/cuptoMl, cup))
+		}
+		g := mlToGram(i.key(), m)
+		if round(g) != 0.0 {
+			xs = append(xs, alt(g, gram))
 		}
 	}
 	i.AltUnits = strings.Join(xs, " / ")
 }
 
+// key returns the name used to look up the Ingredient in the catalogue: the canonical ingredient if it is linked,
+// otherwise the item itself.
+func (i Ingredient) key() string {
+	if i.Canonical != "" {
+		return i.Canonical
+	}
+	return i.Item
+}
+
+// Grams returns the weight of the Ingredient in grams and true. Ingredients measured in volume are converted using
+// the density and counted ingredients using the average piece weight of the item. For a range it is the weight of the
+// minimum amount; GramsMax returns the weight of the maximum. It returns false if the weight cannot be determined.
+func (i Ingredient) Grams() (float64, bool) {
+	d, ok := Registry.Get(i.Unit)
+	switch {
+	case !ok || d.Factor == 0:
+		return 0, false
+	case d.Dim == Mass:
+		return i.Amount * d.Factor, true
+	case d.Dim == Volume:
+		g := mlToGram(i.key(), i.Amount*d.Factor)
+		return g, g != 0
+	case d.Dim == Count:
+		w, ok := Densities.PieceWeight(i)
+		return i.Amount * d.Factor * w, ok
+	}
+	return 0, false
+}
+
+// GramsMax returns the weight in grams of the maximum amount of the Ingredient and true, like Grams. If the Ingredient
+// is not a range, it is the same as Grams.
+func (i Ingredient) GramsMax() (float64, bool) {
+	if i.AmountMax > i.Amount {
+		i.Amount = i.AmountMax
+	}
+	return i.Grams()
+}
+
+// promote takes an amount and its Unit and returns the amount in a larger Unit if the amount has grown past the
+// threshold for that Unit, e.g. 16 tl becomes 5⅓ el, which in turn becomes 78.9 ml. Otherwise it is returned as is.
+func promote(f float64, u Unit) (float64, Unit) {
+	var to Unit
+	switch {
+	case u == tsp && f >= maxTsp:
+		to = tbsp
+	case u == tbsp && f > maxTbsp:
+		to = ml
+	case u == ml && f >= maxMl:
+		to = liter
+	case u == gram && f >= maxGram:
+		to = kilo
+	default:
+		return f, u
+	}
+	x, err := Registry.Convert(f, u, to)
+	if err != nil {
+		return f, u
+	}
+	return promote(x, to)
+}
+
 // gramToMl takes an item and number of grams, looks up the item in the
 // conversion table and returns the number of milliliters for x grams of the item.
 func gramToMl(item string, x float64) float64 {
-	if f, ok := convTable[item]; ok {
-		return x * f
+	if d, ok := Densities.Find(item); ok {
+		return x * d.MlPerGram
 	}
 	return 0.0
 }
@@ -106,8 +170,8 @@ func gramToMl(item string, x float64) float64 {
 // mlToGram takes an item and number of milliliters, looks up the item in the
 // conversion table and returns the number of grams for x milliliters of the item.
 func mlToGram(item string, x float64) float64 {
-	if f, ok := convTable[item]; ok {
-		return x / f
+	if d, ok := Densities.Find(item); ok && d.MlPerGram != 0 {
+		return x / d.MlPerGram
 	}
 	return 0.0
 }
@@ -119,18 +183,56 @@ func round(f float64) float64 {
 
 // Print returns the Ingredient with all available information (depending on the type of ingredient) as a string.
 func (i Ingredient) Print() string {
-	i.altUnits()
+	return i.PrintAs(FormatKitchen)
+}
+
+// PrintAs returns the Ingredient as a string like Print, with the amount displayed in the AmountFormat af.
+func (i Ingredient) PrintAs(af AmountFormat) string {
+	if i.Written != "" {
+		i.AltUnits = i.Written
+	} else {
+		i.altUnits()
+	}
+	s := i.text(af)
+	if i.AltUnits != "" {
+		return fmt.Sprintf("%v (%v)", s, i.AltUnits)
+	}
+	return s
+}
+
+// Text returns the Ingredient as a single line of text without alternative units, e.g. "1 el olijfolie, extra
+// vierge", which can be parsed again by ParseIngredient.
+func (i Ingredient) Text() string {
+	if i.Amount == 0 {
+		// e.g. "zout naar smaak"
+		return strings.TrimSuffix(fmt.Sprintf("%v, %v", i.Item, strings.ToLower(i.Notes)), ", ")
+	}
+	return i.text(FormatKitchen)
+}
+
+// text returns the amount, unit, item and notes of the Ingredient as a string, with the amount in AmountFormat af.
+func (i Ingredient) text(af AmountFormat) string {
 	var s string
 	if i.Unit == pcs {
-		s = fmt.Sprintf("%v %v", i.Amount, i.Item)
+		s = fmt.Sprintf("%v %v", i.printAmount(af), i.Item)
 	} else {
-		s = fmt.Sprintf("%v %v %v", i.Amount, i.Unit, i.Item)
+		s = fmt.Sprintf("%v %v %v", i.printAmount(af), i.Unit, i.Item)
 	}
 	if i.Notes != "" {
 		s = fmt.Sprintf("%v, %v", s, strings.ToLower(i.Notes))
 	}
-	if i.AltUnits != "" {
-		return fmt.Sprintf("%v (%v)", s, i.AltUnits)
+	return s
+}
+
+// printAmount returns the amount of the Ingredient as a string in the AmountFormat af, including the maximum if it is
+// a range (e.g. "2-3") and an indication if it is approximate (e.g. "ca. 200").
+func (i Ingredient) printAmount(af AmountFormat) string {
+	s := formatAmount(i.Amount, i.Unit, af)
+	if i.AmountMax > i.Amount {
+		s = fmt.Sprintf("%v-%v", s, formatAmount(i.AmountMax, i.Unit, af))
+	}
+	if i.Approx {
+		s = "ca. " + s
 	}
 	return s
 }
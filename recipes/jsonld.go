@@ -0,0 +1,406 @@
+package gocookbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	reJSONLD   = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)                    // JSON-LD script block.
+	reItemprop = regexp.MustCompile(`(?is)<([a-z0-9]+)\b([^>]*\bitemprop\s*=\s*["']([^"']+)["'][^>]*)>`)                                 // Element with a microdata property.
+	reContent  = regexp.MustCompile(`(?is)\b(?:content|datetime)\s*=\s*["']([^"']*)["']`)                                                // Value of a microdata property in an attribute.
+	reTags     = regexp.MustCompile(`(?s)<[^>]*>`)                                                                                       // HTML tags.
+	reBreaks   = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>|</div>`)                                                                   // HTML that ends a line.
+	reNumber   = regexp.MustCompile(`\d+(?:[.,]\d+)?`)                                                                                   // Number in a text, e.g. "4" in "4 personen".
+	reDuration = regexp.MustCompile(`(?i)^P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`) // ISO 8601 duration in days, hours, minutes and seconds, e.g. "PT1H30M".
+)
+
+var errorNoRecipe = errors.New("no schema.org recipe found") // HTML does not contain a Recipe in JSON-LD or microdata.
+
+const maxPageBytes = 5 << 20 // Maximum size of an HTML document in bytes, also the default of a Fetcher.
+
+// ImportHTML takes an HTML document, e.g. the page source of a recipe website, and returns the schema.org Recipe in
+// it as a Recipe, together with the ParseResult of each ingredient so they can be checked. The recipe is taken from
+// the JSON-LD in the document or, if there is none, from the microdata. It returns an error if the document is larger
+// than 5 MB or if no recipe is found.
+func ImportHTML(r io.Reader) (Recipe, []ParseResult, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxPageBytes+1))
+	switch {
+	case err != nil:
+		return Recipe{}, nil, fmt.Errorf("unable to read HTML: %w", err)
+	case len(data) > maxPageBytes:
+		return Recipe{}, nil, errorPageTooLarge
+	}
+	return importHTML(data)
+}
+
+// importHTML takes an HTML document and returns the recipe in it like ImportHTML, without limiting its size.
+func importHTML(data []byte) (Recipe, []ParseResult, error) {
+	doc := string(data)
+	m, ok := findJSONLD(doc)
+	if !ok {
+		m, ok = findMicrodata(doc)
+	}
+	if !ok {
+		return Recipe{}, nil, errorNoRecipe
+	}
+	rcp, results := schemaToRecipe(m)
+	return rcp, results, nil
+}
+
+// findJSONLD takes an HTML document and returns the first JSON-LD object of type Recipe and true, or false if the
+// document does not contain a Recipe in JSON-LD.
+func findJSONLD(doc string) (map[string]interface{}, bool) {
+	for _, m := range reJSONLD.FindAllStringSubmatch(doc, -1) {
+		var v interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(m[1])), &v); err != nil {
+			continue
+		}
+		if r, ok := findSchemaRecipe(v); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// findSchemaRecipe takes decoded JSON-LD and returns the (nested) object of type Recipe and true, or false if there is none.
+func findSchemaRecipe(v interface{}) (map[string]interface{}, bool) {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, e := range x {
+			if r, ok := findSchemaRecipe(e); ok {
+				return r, true
+			}
+		}
+	case map[string]interface{}:
+		for _, t := range schemaStrings(x["@type"]) {
+			if t == "Recipe" || strings.HasSuffix(t, "/Recipe") {
+				return x, true
+			}
+		}
+		for _, k := range []string{"@graph", "mainEntity", "mainEntityOfPage"} {
+			if r, ok := findSchemaRecipe(x[k]); ok {
+				return r, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// findMicrodata takes an HTML document and returns the properties of the schema.org Recipe in its microdata and true,
+// or false if the document does not contain a Recipe in microdata. Properties that occur multiple times, e.g.
+// recipeIngredient, are returned as list.
+func findMicrodata(doc string) (map[string]interface{}, bool) {
+	i := strings.Index(strings.ToLower(doc), "schema.org/recipe")
+	if i < 0 {
+		return nil, false
+	}
+	doc = doc[i:]
+	m := map[string]interface{}{}
+	for _, loc := range reItemprop.FindAllStringSubmatchIndex(doc, -1) {
+		tag, attrs, prop := doc[loc[2]:loc[3]], doc[loc[4]:loc[5]], doc[loc[6]:loc[7]]
+		var value string
+		if c := reContent.FindStringSubmatch(attrs); c != nil {
+			value = c[1]
+		} else {
+			end := strings.Index(strings.ToLower(doc[loc[1]:]), "</"+strings.ToLower(tag)+">")
+			if end < 0 {
+				continue
+			}
+			value = doc[loc[1] : loc[1]+end]
+		}
+		value = cleanHTML(value)
+		for _, p := range strings.Fields(prop) {
+			switch old := m[p].(type) {
+			case nil:
+				m[p] = value
+			case string:
+				m[p] = []interface{}{old, value}
+			case []interface{}:
+				m[p] = append(old, value)
+			}
+		}
+	}
+	if len(m) == 0 {
+		return nil, false
+	}
+	return m, true
+}
+
+// schemaToRecipe takes the properties of a schema.org Recipe and returns them as Recipe and the ParseResult of each
+// ingredient.
+func schemaToRecipe(m map[string]interface{}) (Recipe, []ParseResult) {
+	rcp := Recipe{
+		Name:     cleanHTML(schemaString(m["name"])),
+		Notes:    cleanHTML(schemaString(m["description"])),
+		Portions: schemaYield(m["recipeYield"]),
+		Dur:      parseISODuration(schemaString(m["totalTime"])),
+	}
+	if rcp.Dur == 0 {
+		rcp.Dur = parseISODuration(schemaString(m["prepTime"])) + parseISODuration(schemaString(m["cookTime"]))
+	}
+	// Ingredients
+	ingrds := m["recipeIngredient"]
+	if ingrds == nil {
+		ingrds = m["ingredients"]
+	}
+	var lines []string
+	for _, s := range schemaStrings(ingrds) {
+		lines = append(lines, cleanHTML(s))
+	}
+	results := ParseIngrds(strings.Join(lines, "\n"))
+	rcp.Ingrs = make([]Ingredient, len(results))
+	for i, r := range results {
+		rcp.Ingrs[i] = r.Ingredient
+	}
+	// Steps
+	var notes string
+	rcp.Steps, notes = schemaSteps(m["recipeInstructions"])
+	if notes != "" {
+		rcp.Notes = strings.TrimSpace(rcp.Notes + "\n" + notes)
+	}
+	// Tags
+	rcp.Tags = []string{}
+	for _, v := range schemaStrings(m["keywords"]) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(cleanHTML(t)); t != "" && !containsFold(rcp.Tags, t) {
+				rcp.Tags = append(rcp.Tags, t)
+			}
+		}
+	}
+	// Source
+	rcp.SourceLink = schemaString(m["url"])
+	for _, k := range []string{"publisher", "author"} {
+		if rcp.Source = cleanHTML(schemaString(m[k])); rcp.Source != "" {
+			break
+		}
+	}
+	if rcp.Source == "" {
+		rcp.Source = rcp.SourceLink
+	}
+	return rcp, results
+}
+
+// schemaSteps takes schema.org recipeInstructions, either as text, list of texts, HowToSteps or HowToSections, and
+// returns the steps and any trailing notes.
+func schemaSteps(v interface{}) ([]string, string) {
+	var lines []string
+	var add func(v interface{})
+	add = func(v interface{}) {
+		switch x := v.(type) {
+		case string:
+			lines = append(lines, cleanHTML(x))
+		case []interface{}:
+			for _, e := range x {
+				add(e)
+			}
+		case map[string]interface{}:
+			if x["itemListElement"] != nil {
+				add(x["itemListElement"])
+				return
+			}
+			s := schemaString(x["text"])
+			if s == "" {
+				s = schemaString(x["name"])
+			}
+			lines = append(lines, cleanHTML(s))
+		}
+	}
+	add(v)
+	return TextToSteps(strings.Join(lines, "\n\n"))
+}
+
+// schemaString takes a schema.org value and returns it as text. For an object it returns its name, for a list the
+// first element.
+func schemaString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []interface{}:
+		if len(x) > 0 {
+			return schemaString(x[0])
+		}
+	case map[string]interface{}:
+		if s := schemaString(x["name"]); s != "" {
+			return s
+		}
+		return schemaString(x["@id"])
+	}
+	return ""
+}
+
+// schemaStrings takes a schema.org value that can be a single value or a list and returns all values as text.
+func schemaStrings(v interface{}) []string {
+	xs, ok := v.([]interface{})
+	if !ok {
+		xs = []interface{}{v}
+	}
+	var ss []string
+	for _, x := range xs {
+		if s := schemaString(x); s != "" {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
+
+// schemaYield takes a schema.org recipeYield, e.g. "4 personen" or ["4", "4 servings"], and returns the number of
+// portions, zero if unknown.
+func schemaYield(v interface{}) float64 {
+	for _, s := range schemaStrings(v) {
+		if n := reNumber.FindString(s); n != "" {
+			f, err := strconv.ParseFloat(strings.Replace(n, ",", ".", 1), 64)
+			if err == nil && f > 0 {
+				return f
+			}
+		}
+	}
+	return 0
+}
+
+// parseISODuration takes an ISO 8601 duration, e.g. "PT1H30M" or "P0DT0H45M", and returns it as time.Duration. It
+// returns zero if s is not a valid duration.
+func parseISODuration(s string) time.Duration {
+	m := reDuration.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+1] == "" {
+			continue
+		}
+		f, _ := strconv.ParseFloat(m[i+1], 64)
+		d += time.Duration(f * float64(unit))
+	}
+	return d
+}
+
+// cleanHTML takes a text that can contain HTML and returns the text without tags, entities and redundant spaces.
+// Line breaks and paragraphs are kept as new lines.
+func cleanHTML(s string) string {
+	s = reBreaks.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(reTags.ReplaceAllString(s, ""))
+	lines := strings.Split(normalizeText(s), "\n")
+	xs := []string{}
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			xs = append(xs, line)
+		}
+	}
+	return strings.Join(xs, "\n")
+}
+
+// containsFold returns true if xs contains s, ignoring case.
+func containsFold(xs []string, s string) bool {
+	for _, x := range xs {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaRecipe represents a Recipe as schema.org Recipe, to be exported as JSON-LD.
+type SchemaRecipe struct {
+	Context            string        `json:"@context"`
+	Type               string        `json:"@type"`
+	Name               string        `json:"name"`
+	Description        string        `json:"description,omitempty"`
+	RecipeYield        string        `json:"recipeYield,omitempty"`
+	TotalTime          string        `json:"totalTime,omitempty"`
+	RecipeIngredient   []string      `json:"recipeIngredient"`
+	RecipeInstructions []SchemaStep  `json:"recipeInstructions"`
+	Keywords           string        `json:"keywords,omitempty"`
+	Author             *SchemaPerson `json:"author,omitempty"`
+	IsBasedOn          string        `json:"isBasedOn,omitempty"`
+	DateCreated        string        `json:"dateCreated,omitempty"`
+	DateModified       string        `json:"dateModified,omitempty"`
+}
+
+// SchemaStep represents a step of a Recipe as schema.org HowToStep.
+type SchemaStep struct {
+	Type string `json:"@type"`
+	Text string `json:"text"`
+}
+
+// SchemaPerson represents a user as schema.org Person.
+type SchemaPerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// Schema returns the Recipe as schema.org Recipe. SourceLink is used as the source the recipe is based on.
+func (r Recipe) Schema() SchemaRecipe {
+	s := SchemaRecipe{
+		Context:            "https://schema.org",
+		Type:               "Recipe",
+		Name:               r.Name,
+		Description:        r.Notes,
+		TotalTime:          formatISODuration(r.Dur),
+		RecipeIngredient:   make([]string, len(r.Ingrs)),
+		RecipeInstructions: make([]SchemaStep, len(r.Steps)),
+		Keywords:           strings.Join(r.Tags, ", "),
+		IsBasedOn:          r.SourceLink,
+	}
+	if r.Portions > 0 {
+		s.RecipeYield = strconv.FormatFloat(r.Portions, 'f', -1, 64)
+	}
+	for i, v := range r.Ingrs {
+		s.RecipeIngredient[i] = v.Text()
+	}
+	for i, v := range r.Steps {
+		s.RecipeInstructions[i] = SchemaStep{"HowToStep", v}
+	}
+	if r.Createdby != "" {
+		s.Author = &SchemaPerson{"Person", r.Createdby}
+	}
+	if !r.Created.IsZero() {
+		s.DateCreated = r.Created.Format(time.RFC3339)
+	}
+	if !r.Updated.IsZero() {
+		s.DateModified = r.Updated.Format(time.RFC3339)
+	}
+	return s
+}
+
+// ExportJSONLD takes one or more Recipes and returns them as schema.org JSON-LD: a single object for one recipe and a
+// list for multiple recipes.
+func ExportJSONLD(rcps ...Recipe) ([]byte, error) {
+	if len(rcps) == 1 {
+		return json.MarshalIndent(rcps[0].Schema(), "", "  ")
+	}
+	xs := make([]SchemaRecipe, len(rcps))
+	for i, r := range rcps {
+		xs[i] = r.Schema()
+	}
+	return json.MarshalIndent(xs, "", "  ")
+}
+
+// formatISODuration takes a time.Duration and returns it as ISO 8601 duration, e.g. "PT1H30M", or an empty string
+// if d is zero.
+func formatISODuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	s := "PT"
+	if h := int(d.Hours()); h > 0 {
+		s += fmt.Sprintf("%dH", h)
+	}
+	if m := int(d.Minutes()) % 60; m > 0 {
+		s += fmt.Sprintf("%dM", m)
+	}
+	if sec := int(d.Seconds()) % 60; sec > 0 {
+		s += fmt.Sprintf("%dS", sec)
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package gocookbook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportHTMLJSONLD(t *testing.T) {
+	doc := `<html><head>
+<script type="application/ld+json">{"@context":"https://schema.org","@type":"WebSite","name":"Kookblog"}</script>
+<script type="application/ld+json">
+{"@context":"https://schema.org","@graph":[
+	{"@type":"WebPage","name":"Pannenkoeken"},
+	{"@type":["Recipe","NewsArticle"],
+	 "name":"Pannenkoeken &amp; stroop",
+	 "description":"<p>Klassieke pannenkoeken.</p>",
+	 "recipeYield":["4","4 personen"],
+	 "prepTime":"PT10M","cookTime":"PT1H5M",
+	 "recipeIngredient":["250 g bloem","500 ml melk","2 eieren"],
+	 "recipeInstructions":[
+		{"@type":"HowToSection","name":"Beslag","itemListElement":[
+			{"@type":"HowToStep","text":"Meng de bloem met de melk."},
+			{"@type":"HowToStep","text":"Klop de eieren erdoor."}]},
+		{"@type":"HowToStep","text":"Bak de pannenkoeken."},
+		"Tip: serveer met stroop."],
+	 "keywords":"ontbijt, zoet, Ontbijt",
+	 "author":{"@type":"Person","name":"Jan"},
+	 "url":"https://example.com/pannenkoeken"}
+]}
+</script></head><body></body></html>`
+	rcp, results, err := ImportHTML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcp.Name != "Pannenkoeken & stroop" || rcp.Portions != 4 || rcp.Dur != 75*time.Minute {
+		t.Errorf("Wrong name, portions or duration. Got: '%v', %v, %v", rcp.Name, rcp.Portions, rcp.Dur)
+	}
+	if len(results) != 3 || len(rcp.Ingrs) != 3 || rcp.Ingrs[0].Amount != 250 || rcp.Ingrs[0].Unit != gram || rcp.Ingrs[0].Item != "bloem" {
+		t.Errorf("Wrong ingredients. Got: %+v", rcp.Ingrs)
+	}
+	wantSteps := []string{"Meng de bloem met de melk.", "Klop de eieren erdoor.", "Bak de pannenkoeken."}
+	if !reflect.DeepEqual(rcp.Steps, wantSteps) {
+		t.Errorf("Wrong steps.\nGot:\t%q\nWant:\t%q", rcp.Steps, wantSteps)
+	}
+	if want := "Klassieke pannenkoeken.\nTip: serveer met stroop."; rcp.Notes != want {
+		t.Errorf("Wrong notes.\nGot:\t%q\nWant:\t%q", rcp.Notes, want)
+	}
+	if want := []string{"ontbijt", "zoet"}; !reflect.DeepEqual(rcp.Tags, want) {
+		t.Errorf("Wrong tags. Got: %q, Want: %q", rcp.Tags, want)
+	}
+	if rcp.Source != "Jan" || rcp.SourceLink != "https://example.com/pannenkoeken" {
+		t.Errorf("Wrong source. Got: '%v', '%v'", rcp.Source, rcp.SourceLink)
+	}
+}
+
+func TestImportHTMLMicrodata(t *testing.T) {
+	doc := `<div itemscope itemtype="http://schema.org/Recipe">
+<h1 itemprop="name">Tomatensoep</h1>
+<meta itemprop="totalTime" content="PT45M">
+<span itemprop="recipeYield">6 porties</span>
+<ul><li itemprop="recipeIngredient">1 kg tomaten</li><li itemprop="recipeIngredient">1 ui</li></ul>
+<div itemprop="recipeInstructions"><p>Snijd de tomaten.</p><p>Kook 30 minuten.</p></div>
+</div>`
+	rcp, _, err := ImportHTML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcp.Name != "Tomatensoep" || rcp.Portions != 6 || rcp.Dur != 45*time.Minute || len(rcp.Ingrs) != 2 {
+		t.Errorf("Wrong recipe. Got: %+v", rcp)
+	}
+	if want := []string{"Snijd de tomaten.", "Kook 30 minuten."}; !reflect.DeepEqual(rcp.Steps, want) {
+		t.Errorf("Wrong steps.\nGot:\t%q\nWant:\t%q", rcp.Steps, want)
+	}
+	if _, _, err := ImportHTML(strings.NewReader("<html><p>Geen recept</p></html>")); err != errorNoRecipe {
+		t.Errorf("Want error '%v', Got: '%v'", errorNoRecipe, err)
+	}
+	large := doc + strings.Repeat(" ", maxPageBytes)
+	if _, _, err := ImportHTML(strings.NewReader(large)); err != errorPageTooLarge {
+		t.Errorf("Want error '%v', Got: '%v'", errorPageTooLarge, err)
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	cases := []struct {
+		s    string
+		want time.Duration
+	}{
+		{"PT1H30M", 90 * time.Minute},
+		{"P0DT0H45M", 45 * time.Minute},
+		{"PT20M30S", 20*time.Minute + 30*time.Second},
+		{"P1D", 24 * time.Hour},
+		{"1 uur", 0},
+	}
+	for _, c := range cases {
+		if got := parseISODuration(c.s); got != c.want {
+			t.Errorf("'%v': Want %v, Got: %v", c.s, c.want, got)
+		}
+	}
+}
+
+func TestExportJSONLD(t *testing.T) {
+	rcp := Recipe{
+		Name:       "Pannenkoeken",
+		Ingrs:      []Ingredient{{Amount: 250, Unit: gram, Item: "bloem"}, {Amount: 2, Unit: pcs, Item: "eieren"}, {Item: "zout", Notes: "naar smaak"}},
+		Steps:      []string{"Meng alles.", "Bak de pannenkoeken."},
+		Tags:       []string{"Ontbijt", "Zoet"},
+		Portions:   4,
+		Dur:        75 * time.Minute,
+		Notes:      "Klassiek.",
+		SourceLink: "https://example.com/pannenkoeken",
+		Createdby:  "jan",
+	}
+	data, err := ExportJSONLD(rcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := rcp.Schema()
+	if s.TotalTime != "PT1H15M" || s.RecipeYield != "4" || s.Keywords != "Ontbijt, Zoet" || s.Author.Name != "jan" {
+		t.Errorf("Wrong schema. Got: %+v", s)
+	}
+	if want := []string{"250 g bloem", "2 eieren", "zout, naar smaak"}; !reflect.DeepEqual(s.RecipeIngredient, want) {
+		t.Errorf("Wrong ingredients.\nGot:\t%q\nWant:\t%q", s.RecipeIngredient, want)
+	}
+	// Importing the export should return the same recipe
+	got, _, err := ImportHTML(strings.NewReader(`<script type="application/ld+json">` + string(data) + `</script>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != rcp.Name || got.Portions != rcp.Portions || got.Dur != rcp.Dur || got.Notes != rcp.Notes || !reflect.DeepEqual(got.Steps, rcp.Steps) || !reflect.DeepEqual(got.Tags, rcp.Tags) {
+		t.Errorf("Import of export differs.\nGot:\t%+v\nWant:\t%+v", got, rcp)
+	}
+	for i := range rcp.Ingrs[:2] {
+		if g, w := got.Ingrs[i], rcp.Ingrs[i]; g.Amount != w.Amount || g.Unit != w.Unit || g.Item != w.Item {
+			t.Errorf("Ingredient %v differs. Got: %+v, Want: %+v", i, g, w)
+		}
+	}
+	data, _ = ExportJSONLD(rcp, rcp)
+	if !strings.HasPrefix(string(data), "[") {
+		t.Errorf("Want a list for multiple recipes, Got: %s", data)
+	}
+}
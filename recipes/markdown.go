@@ -0,0 +1,457 @@
+package gocookbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headings of the sections in a Markdown recipe, in lowercase, including the headings used when writing a recipe by
+// hand in English.
+var (
+	mdIngrdHeadings = []string{"ingrediënten", "ingredienten", "ingredients"}
+	mdStepHeadings  = []string{"bereiding", "stappen", "instructies", "instructions", "directions", "steps", "method"}
+	mdNoteHeadings  = []string{"notities", "opmerkingen", "tips", "notes"}
+)
+
+var (
+	reMdStep    = regexp.MustCompile(`^(\d+)[.)]\s+(.*)$`)                                  // Numbered step, e.g. "1. Meng de bloem".
+	reMdBullet  = regexp.MustCompile(`^[-*+]\s+(.*)$`)                                      // Item of a list, e.g. "- 250 g bloem".
+	reMdComment = regexp.MustCompile(`\s*<!--\s*ingredient\s+(\{.*\})\s*-->\s*$`)           // Exact ingredient, e.g. "<!-- ingredient {"Fixed":true} -->".
+	reMdPan     = regexp.MustCompile(`^(\S+)\s+(\d+(?:\.\d+)?)(?:x(\d+(?:\.\d+)?))?\s*cm$`) // Pan, e.g. "rond 24 cm".
+)
+
+var errorFrontMatter = errors.New("front matter is not closed") // Markdown starts with "---" without closing "---".
+
+// mdIngredient represents the exact values of an Ingredient, stored in a comment behind an ingredient in Markdown if
+// the text of the ingredient does not contain all values.
+type mdIngredient struct {
+	Amount    float64 `json:",omitempty"`
+	AmountMax float64 `json:",omitempty"`
+	Approx    bool    `json:",omitempty"`
+	Unit      Unit    `json:",omitempty"`
+	Item      string  `json:",omitempty"`
+	Notes     string  `json:",omitempty"`
+	Fixed     bool    `json:",omitempty"`
+	Canonical string  `json:",omitempty"`
+}
+
+// Markdown returns the Recipe as Markdown: front matter with the name, tags, portions, duration, pan, source and
+// history, followed by the ingredients as list, the numbered steps and the notes. The result can be parsed again with
+// ParseMarkdown.
+func (r Recipe) Markdown() string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	mdField(&b, "title", r.Name)
+	if len(r.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, t := range r.Tags {
+			fmt.Fprintf(&b, "  - %v\n", mdValue(t))
+		}
+	}
+	mdField(&b, "portions", formatPortions(r.Portions))
+	mdField(&b, "duration", formatDuration(r.Dur))
+	mdField(&b, "pan", r.Pan.String())
+	mdField(&b, "source", r.Source)
+	mdField(&b, "source_link", r.SourceLink)
+	if r.Id != 0 {
+		mdField(&b, "id", strconv.Itoa(r.Id))
+	}
+	mdField(&b, "created_by", r.Createdby)
+	mdField(&b, "created", formatTime(r.Created))
+	mdField(&b, "updated_by", r.Updatedby)
+	mdField(&b, "updated", formatTime(r.Updated))
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# %v\n", r.Name)
+	if len(r.Ingrs) > 0 {
+		b.WriteString("\n## Ingrediënten\n\n")
+		for _, v := range r.Ingrs {
+			fmt.Fprintf(&b, "- %v\n", mdIngredientLine(v))
+		}
+	}
+	if len(r.Steps) > 0 {
+		b.WriteString("\n## Bereiding\n\n")
+		for i, s := range r.Steps {
+			// lines within a step are indented, so they are part of the same item of the list
+			fmt.Fprintf(&b, "%v. %v\n", i+1, strings.Join(textToLines(strings.TrimSpace(s)), "\n   "))
+		}
+	}
+	if notes := strings.TrimSpace(r.Notes); notes != "" {
+		fmt.Fprintf(&b, "\n## Notities\n\n%v\n", notes)
+	}
+	return b.String()
+}
+
+// mdField writes a field with key and value to the front matter in b, if the value is not empty.
+func mdField(b *strings.Builder, key, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "%v: %v\n", key, mdValue(value))
+	}
+}
+
+// mdValue returns s as value in front matter, quoted if it would otherwise be read as another value.
+func mdValue(s string) string {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, "\n\"'#") || strings.Contains(s, ": ") ||
+		strings.ContainsAny(s[:1], "[]{}&*!|>%@`-") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// mdIngredientLine returns the Ingredient as a line of text. If parsing the text does not give the same Ingredient,
+// the exact values are added as a comment, which is not shown when the Markdown is displayed.
+func mdIngredientLine(i Ingredient) string {
+	s := i.Text()
+	exact := toMdIngredient(i)
+	if toMdIngredient(ParseIngredient(s).Ingredient) == exact {
+		return s
+	}
+	data, err := json.Marshal(exact)
+	if err != nil {
+		return s
+	}
+	return fmt.Sprintf("%v <!-- ingredient %s -->", s, data)
+}
+
+// toMdIngredient returns the values of the Ingredient that are stored in Markdown.
+func toMdIngredient(i Ingredient) mdIngredient {
+	return mdIngredient{i.Amount, i.AmountMax, i.Approx, i.Unit, i.Item, i.Notes, i.Fixed, i.Canonical}
+}
+
+// formatDuration returns d as text without trailing zero units, e.g. "1h30m" or "45m", or an empty string if d is
+// zero.
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// formatTime returns t in RFC 3339 format, or an empty string if t is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// ParseMarkdown takes a recipe in Markdown, as written by Markdown, and returns it as Recipe. Recipes written by hand
+// are accepted as well: front matter is optional, the name can be given as heading, ingredients and steps can be any
+// list and text before the first section is added to the notes.
+func ParseMarkdown(s string) (Recipe, error) {
+	return parseMarkdown(s, &ImportReport{})
+}
+
+// parseMarkdown takes a recipe in Markdown and returns it as Recipe, adding ingredients that are not fully
+// recognised and unknown fields in the front matter to the ImportReport.
+func parseMarkdown(s string, ir *ImportReport) (Recipe, error) {
+	rcp := Recipe{Tags: []string{}}
+	lines := strings.Split(normalizeText(s), "\n")
+	title := ""
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		end := -1
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return Recipe{}, errorFrontMatter
+		}
+		title = mdFrontMatter(&rcp, lines[1:end], ir)
+		lines = lines[end+1:]
+	}
+	var ingrds []string
+	var steps, intro, notes []string
+	section := &intro
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case section == &notes:
+			// the notes are the last section and kept as they are
+		case section == &steps && line != strings.TrimLeft(line, " \t"):
+			// indented lines belong to the step above them, even if they look like a heading, e.g. "## Tip"
+		case strings.HasPrefix(trimmed, "# ") && rcp.Name == "" && title == "":
+			rcp.Name = strings.TrimSpace(trimmed[2:])
+			continue
+		case strings.HasPrefix(trimmed, "# "):
+			continue
+		case strings.HasPrefix(trimmed, "## "):
+			heading := strings.ToLower(strings.Trim(trimmed[3:], " #:"))
+			switch {
+			case containsFold(mdIngrdHeadings, heading):
+				section = &ingrds
+			case containsFold(mdStepHeadings, heading):
+				section = &steps
+			case containsFold(mdNoteHeadings, heading):
+				section = &notes
+			}
+			continue
+		case section == &ingrds:
+			if m := reMdBullet.FindStringSubmatch(trimmed); m != nil {
+				ingrds = append(ingrds, m[1])
+			}
+			continue
+		}
+		*section = append(*section, line)
+	}
+	if title != "" {
+		rcp.Name = title
+	}
+	rcp.Ingrs = []Ingredient{}
+	for _, line := range ingrds {
+		if i, ok := mdParseIngredient(line, rcp.Name, ir); ok {
+			rcp.Ingrs = append(rcp.Ingrs, i)
+		}
+	}
+	var tips string
+	rcp.Steps, tips = mdSteps(steps)
+	rcp.Notes = joinNotes(strings.Join(intro, "\n"), strings.Join(notes, "\n"), tips)
+	return rcp, nil
+}
+
+// mdFrontMatter takes the lines of the front matter of a Markdown recipe, stores the fields in rcp and returns the
+// title, if any.
+func mdFrontMatter(rcp *Recipe, lines []string, ir *ImportReport) string {
+	var title, key string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if m := reMdBullet.FindStringSubmatch(trimmed); m != nil && key == "tags" {
+			// item of a list, e.g. "  - Ontbijt"
+			rcp.Tags = append(rcp.Tags, mdUnquote(m[1]))
+			continue
+		}
+		k, v, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key, v = strings.ToLower(strings.TrimSpace(k)), mdUnquote(v)
+		switch key {
+		case "title", "name":
+			title = v
+		case "tags", "tag", "categories":
+			for _, t := range strings.Split(strings.Trim(strings.TrimSpace(v), "[]"), ",") {
+				if t = mdUnquote(t); t != "" {
+					rcp.Tags = append(rcp.Tags, t)
+				}
+			}
+			key = "tags"
+		case "portions", "servings":
+			rcp.Portions = schemaYield(v)
+		case "duration", "time":
+			if d, err := time.ParseDuration(v); err == nil {
+				rcp.Dur = d
+			} else {
+				rcp.Dur = parseTextDuration(v)
+			}
+		case "pan":
+			if m := reMdPan.FindStringSubmatch(v); m != nil {
+				width, _ := strconv.ParseFloat(m[2], 64)
+				length, _ := strconv.ParseFloat(m[3], 64)
+				if p, err := NewPan(Shape(m[1]), width, length); err == nil {
+					rcp.Pan = p
+				}
+			}
+		case "source":
+			rcp.Source = v
+		case "source_link", "url":
+			rcp.SourceLink = v
+		case "id":
+			rcp.Id, _ = strconv.Atoi(v)
+		case "created_by":
+			rcp.Createdby = v
+		case "created":
+			rcp.Created, _ = time.Parse(time.RFC3339Nano, v)
+		case "updated_by":
+			rcp.Updatedby = v
+		case "updated":
+			rcp.Updated, _ = time.Parse(time.RFC3339Nano, v)
+		default:
+			if v != "" {
+				ir.skip(key)
+			}
+		}
+	}
+	return title
+}
+
+// mdUnquote takes a value in front matter and returns it without surrounding spaces and quotes.
+func mdUnquote(s string) string {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, `"`):
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	case len(s) > 1 && strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'"):
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return strings.Trim(s, `"`)
+}
+
+// mdParseIngredient takes an ingredient from a Markdown recipe and returns it as Ingredient and true, using the exact
+// values in the comment if there is one. It returns false if the line does not contain an ingredient.
+func mdParseIngredient(line, name string, ir *ImportReport) (Ingredient, bool) {
+	if m := reMdComment.FindStringSubmatch(line); m != nil {
+		var x mdIngredient
+		if err := json.Unmarshal([]byte(m[1]), &x); err == nil {
+			i := Ingredient{Amount: x.Amount, AmountMax: x.AmountMax, Approx: x.Approx, Unit: x.Unit, Item: x.Item,
+				Notes: x.Notes, Fixed: x.Fixed, Canonical: x.Canonical}
+			i.altUnits()
+			return i, true
+		}
+		ir.warn(name, "invalid values for ingredient '%v'", line)
+		line = line[:len(line)-len(m[0])]
+	}
+	xi := ir.ingredients(name, []string{line})
+	if len(xi) == 0 {
+		return Ingredient{}, false
+	}
+	return xi[0], true
+}
+
+// mdSteps takes the lines of the steps in a Markdown recipe and returns the steps and any trailing notes. Numbered
+// lines start a new step and indented lines are part of the previous step. Without numbered lines the text is
+// split into steps by TextToSteps.
+func mdSteps(lines []string) ([]string, string) {
+	numbered := false
+	for _, line := range lines {
+		if reMdStep.MatchString(strings.TrimSpace(line)) {
+			numbered = true
+			break
+		}
+	}
+	if !numbered {
+		for i, line := range lines {
+			if m := reMdBullet.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				lines[i] = m[1] + "\n"
+			}
+		}
+		return TextToSteps(strings.Join(lines, "\n"))
+	}
+	steps := []string{}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch m := reMdStep.FindStringSubmatch(trimmed); {
+		case m != nil && !strings.HasPrefix(line, " "):
+			steps = append(steps, m[2])
+		case len(steps) == 0:
+			continue
+		case trimmed == "" && strings.HasPrefix(line, "   "):
+			// empty line within a step
+			steps[len(steps)-1] += "\n"
+		case trimmed == "":
+			continue
+		default:
+			steps[len(steps)-1] += "\n" + trimmed
+		}
+	}
+	return steps, ""
+}
+
+// importMarkdown takes a Markdown recipe (.md) or a zip file with Markdown recipes and returns the recipes in it.
+func importMarkdown(r io.Reader, ir *ImportReport) ([]Recipe, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		rcp, err := parseMarkdown(string(data), ir)
+		if err != nil {
+			return nil, err
+		}
+		return []Recipe{rcp}, nil
+	}
+	return importMarkdownFS(zr, ir)
+}
+
+// ImportMarkdownDir takes a directory, e.g. os.DirFS("recepten"), and returns all Markdown recipes (.md) in it and
+// its subdirectories, together with a report of the import. Hidden directories, e.g. ".git", are skipped.
+func ImportMarkdownDir(fsys fs.FS) ([]Recipe, ImportReport, error) {
+	ir := ImportReport{Format: Markdown}
+	rcps, err := importMarkdownFS(fsys, &ir)
+	if err != nil {
+		return nil, ir, fmt.Errorf("unable to import %v: %w", Markdown, err)
+	}
+	ir.Recipes = len(rcps)
+	return rcps, ir, nil
+}
+
+// importMarkdownFS returns all Markdown recipes in the file system fsys. The name of a recipe without title is taken
+// from its file name. Files that cannot be parsed are added as warning to the ImportReport. It returns
+// errorImportTooLarge if a file, or all files together, exceed the limits of an import.
+func importMarkdownFS(fsys fs.FS, ir *ImportReport) ([]Recipe, error) {
+	rcps := []Recipe{}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir() && p != "." && strings.HasPrefix(d.Name(), "."):
+			return fs.SkipDir
+		case d.IsDir() || !containsFold([]string{".md", ".markdown"}, path.Ext(p)):
+			return nil
+		}
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		data, err := ir.unpack(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %w", p, err)
+		}
+		rcp, err := parseMarkdown(string(data), ir)
+		if err != nil {
+			ir.warn(p, "%v", err)
+			return nil
+		}
+		if rcp.Name == "" {
+			rcp.Name = strings.TrimSuffix(path.Base(p), path.Ext(p))
+		}
+		rcps = append(rcps, rcp)
+		return nil
+	})
+	return rcps, err
+}
+
+// exportMarkdown writes the recipes as Markdown to w: a single .md file for one recipe and a zip file with a .md
+// file per recipe for multiple recipes.
+func exportMarkdown(w io.Writer, rcps []Recipe) error {
+	if len(rcps) == 1 {
+		_, err := io.WriteString(w, rcps[0].Markdown())
+		return err
+	}
+	zw := zip.NewWriter(w)
+	names := map[string]int{}
+	for _, rcp := range rcps {
+		f, err := zw.Create(fileName(rcp.Name, names) + ".md")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, rcp.Markdown()); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
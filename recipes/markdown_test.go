@@ -0,0 +1,119 @@
+package gocookbook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	created := time.Date(2023, 3, 1, 12, 30, 15, 500, time.UTC)
+	want := Recipe{
+		Id:   20,
+		Name: "Appeltaart: van oma",
+		Ingrs: []Ingredient{
+			{Amount: 250, Unit: gram, Item: "bloem"},
+			{Amount: 1.5, Unit: tbsp, Item: "boter", Notes: "gesmolten"},
+			{Amount: 2, AmountMax: 3, Unit: pcs, Item: "appels", Canonical: "appel"},
+			{Amount: 200, Approx: true, Unit: gram, Item: "suiker"},
+			{Amount: 1, Unit: pinch, Item: "zout", Fixed: true},
+			{Item: "kaneel", Notes: "Naar smaak"},
+		},
+		Steps:      []string{"Meng de bloem met de boter.", "Schil de appels.\nSnijd ze in partjes.", "Bak 1. uur.", "Laat afkoelen.\n## Tip\nServeer lauw.\n## Notities\n# Variatie"},
+		Tags:       []string{"Gebak", "-Zoet"},
+		Portions:   8,
+		Dur:        90 * time.Minute,
+		Pan:        Pan{Shape: roundPan, Width: 24},
+		Notes:      "Lekker met slagroom.\n\n# Variatie\nMet rozijnen.",
+		Source:     "Oma",
+		SourceLink: "https://example.com/appeltaart",
+		Createdby:  "chef",
+		Created:    created,
+		Updatedby:  "kok",
+		Updated:    created.Add(time.Hour),
+	}
+	got, err := ParseMarkdown(want.Markdown())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Created.Equal(want.Created) || !got.Updated.Equal(want.Updated) {
+		t.Errorf("Wrong dates. Got: %v, %v", got.Created, got.Updated)
+	}
+	got.Created, got.Updated = want.Created, want.Updated
+	// alternative units are determined when parsing
+	for i := range got.Ingrs {
+		got.Ingrs[i].AltUnits = ""
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Round trip failed.\nWant:\t%+v\nGot:\t%+v\nMarkdown:\n%v", want, got, want.Markdown())
+	}
+}
+
+func TestParseMarkdown(t *testing.T) {
+	s := `# Tomatensoep
+
+Snelle soep voor doordeweeks.
+
+## Ingredients
+
+* 1 kg tomaten
+* 1 ui, gesnipperd
+
+## Instructions
+
+- Fruit de ui.
+- Voeg de tomaten toe en kook 20 minuten.
+
+## Notes
+
+Lekker met brood.`
+	got, err := ParseMarkdown(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range got.Ingrs {
+		got.Ingrs[i].AltUnits = ""
+	}
+	want := Recipe{
+		Name: "Tomatensoep",
+		Ingrs: []Ingredient{
+			{Amount: 1, Unit: kilo, Item: "tomaten"},
+			{Amount: 1, Unit: pcs, Item: "ui", Notes: "gesnipperd"},
+		},
+		Steps: []string{"Fruit de ui.", "Voeg de tomaten toe en kook 20 minuten."},
+		Tags:  []string{},
+		Notes: "Snelle soep voor doordeweeks.\n\nLekker met brood.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Want:\t%+v\nGot:\t%+v", want, got)
+	}
+	if _, err := ParseMarkdown("---\ntitle: Soep\n"); err != errorFrontMatter {
+		t.Errorf("Want error %v, Got: %v", errorFrontMatter, err)
+	}
+}
+
+func TestImportMarkdownDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ontbijt/pannenkoeken.md": {Data: []byte(sampleRcp.Markdown())},
+		"diner/soep.md":           {Data: []byte("## Ingrediënten\n\n- 1 l water\n")},
+		"diner/README.txt":        {Data: []byte("geen recept")},
+		".git/recept.md":          {Data: []byte("# Verborgen")},
+		"kapot.md":                {Data: []byte("---\ntitle: Kapot\n")},
+	}
+	rcps, ir, err := ImportMarkdownDir(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, r := range rcps {
+		names = append(names, r.Name)
+	}
+	if want := []string{"soep", "Pannenkoeken"}; !reflect.DeepEqual(names, want) || ir.Recipes != 2 {
+		t.Errorf("Want: %q, Got: %q", want, names)
+	}
+	if len(ir.Warnings) != 1 || !strings.HasPrefix(ir.Warnings[0], "kapot.md") {
+		t.Errorf("Want warning for kapot.md, Got: %q", ir.Warnings)
+	}
+}
@@ -0,0 +1,262 @@
+package gocookbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// mealieRecipe represents a recipe in a Mealie JSON export.
+type mealieRecipe struct {
+	Name               string             `json:"name"`
+	Description        string             `json:"description"`
+	RecipeYield        string             `json:"recipeYield,omitempty"`
+	RecipeServings     float64            `json:"recipeServings,omitempty"`
+	TotalTime          string             `json:"totalTime,omitempty"`
+	RecipeIngredient   []mealieIngredient `json:"recipeIngredient"`
+	RecipeInstructions []mealieStep       `json:"recipeInstructions"`
+	Tags               []mealieName       `json:"tags"`
+	OrgURL             string             `json:"orgURL,omitempty"`
+	DateAdded          string             `json:"dateAdded,omitempty"`
+}
+
+// mealieIngredient represents an ingredient of a recipe in a Mealie JSON export.
+type mealieIngredient struct {
+	Quantity     float64     `json:"quantity"`
+	Unit         *mealieName `json:"unit"`
+	Food         *mealieName `json:"food"`
+	Note         string      `json:"note"`
+	Display      string      `json:"display"`
+	OriginalText string      `json:"originalText"`
+}
+
+// mealieStep represents a step of a recipe in a Mealie JSON export.
+type mealieStep struct {
+	Text string `json:"text"`
+}
+
+// mealieName represents an object in Mealie that is referred to by name, e.g. a unit, food or tag.
+type mealieName struct {
+	Name string `json:"name"`
+}
+
+// importMealie takes a Mealie JSON export, containing a recipe or a list of recipes, or a zip file with such JSON
+// files, and returns the recipes in it.
+func importMealie(r io.Reader, ir *ImportReport) ([]Recipe, error) {
+	xs, err := readJSONObjects(r, ".json", ir)
+	if err != nil {
+		return nil, err
+	}
+	rcps := []Recipe{}
+	for _, m := range xs {
+		rcps = append(rcps, mealieToRecipe(m, ir))
+	}
+	return rcps, nil
+}
+
+// mealieToRecipe takes a Mealie recipe and returns it as Recipe.
+func mealieToRecipe(m map[string]interface{}, ir *ImportReport) Recipe {
+	ir.skipUnknown(m, "id", "slug", "userId", "groupId", "householdId", "name", "description", "recipeYield",
+		"recipeServings", "totalTime", "prepTime", "performTime", "cookTime", "recipeIngredient",
+		"recipeInstructions", "tags", "recipeCategory", "orgURL", "notes", "dateAdded", "dateUpdated", "createdAt",
+		"updatedAt")
+	name := schemaString(m["name"])
+	rcp := Recipe{
+		Name:       name,
+		Portions:   schemaYield(m["recipeServings"]),
+		Dur:        parseTextDuration(schemaString(m["totalTime"])),
+		SourceLink: schemaString(m["orgURL"]),
+		Tags:       []string{},
+	}
+	if rcp.Portions == 0 {
+		rcp.Portions = schemaYield(m["recipeYield"])
+	}
+	if rcp.Dur == 0 {
+		for _, k := range []string{"prepTime", "performTime", "cookTime"} {
+			rcp.Dur += parseTextDuration(schemaString(m[k]))
+		}
+	}
+	rcp.Source = rcp.SourceLink
+	// Ingredients, either as text or as object
+	var lines []string
+	for _, v := range toList(m["recipeIngredient"]) {
+		x, ok := v.(map[string]interface{})
+		if !ok {
+			lines = append(lines, schemaString(v))
+			continue
+		}
+		lines = append(lines, structuredIngredient(x, "quantity", "unit", "food", "note", "originalText", "display"))
+	}
+	rcp.Ingrs = ir.ingredients(name, lines)
+	// Steps
+	var texts []string
+	for _, v := range toList(m["recipeInstructions"]) {
+		if x, ok := v.(map[string]interface{}); ok {
+			texts = append(texts, schemaString(x["text"]))
+			continue
+		}
+		texts = append(texts, schemaString(v))
+	}
+	var notes string
+	rcp.Steps, notes = TextToSteps(strings.Join(texts, "\n\n"))
+	// Notes
+	xs := []string{schemaString(m["description"])}
+	for _, v := range toList(m["notes"]) {
+		if x, ok := v.(map[string]interface{}); ok {
+			xs = append(xs, strings.TrimSpace(schemaString(x["title"])+"\n"+schemaString(x["text"])))
+		}
+	}
+	rcp.Notes = joinNotes(append(xs, notes)...)
+	// Tags and categories
+	for _, k := range []string{"tags", "recipeCategory"} {
+		for _, t := range schemaStrings(m[k]) {
+			if !containsFold(rcp.Tags, t) {
+				rcp.Tags = append(rcp.Tags, t)
+			}
+		}
+	}
+	if t, err := time.Parse("2006-01-02", schemaString(m["dateAdded"])); err == nil {
+		rcp.Created, rcp.Updated = t, t
+	}
+	return rcp
+}
+
+// structuredIngredient takes an ingredient as object with the amount, unit, food and note in the given keys and
+// returns it as a line of text. The original text of the ingredient is used if available.
+func structuredIngredient(x map[string]interface{}, amount, unit, food, note, original, display string) string {
+	if s := schemaString(x[original]); s != "" {
+		return s
+	}
+	f := schemaString(x[food])
+	if f == "" {
+		if s := schemaString(x[display]); s != "" {
+			return s
+		}
+		return schemaString(x[note])
+	}
+	var xs []string
+	if a := schemaString(x[amount]); a != "" && a != "0" {
+		xs = append(xs, a)
+	}
+	if u := schemaString(x[unit]); u != "" {
+		xs = append(xs, u)
+	}
+	s := strings.Join(append(xs, f), " ")
+	if n := schemaString(x[note]); n != "" {
+		s += ", " + n
+	}
+	return s
+}
+
+// exportMealie writes the recipes as Mealie JSON to w: a single object for one recipe and a list for multiple recipes.
+func exportMealie(w io.Writer, rcps []Recipe) error {
+	xs := make([]mealieRecipe, len(rcps))
+	for i, rcp := range rcps {
+		m := mealieRecipe{
+			Name:               rcp.Name,
+			Description:        rcp.Notes,
+			RecipeYield:        formatPortions(rcp.Portions),
+			RecipeServings:     rcp.Portions,
+			TotalTime:          formatTextDuration(rcp.Dur),
+			RecipeIngredient:   make([]mealieIngredient, len(rcp.Ingrs)),
+			RecipeInstructions: make([]mealieStep, len(rcp.Steps)),
+			Tags:               make([]mealieName, len(rcp.Tags)),
+			OrgURL:             rcp.SourceLink,
+		}
+		for j, v := range rcp.Ingrs {
+			mi := mealieIngredient{
+				Quantity:     v.Amount,
+				Food:         &mealieName{v.Item},
+				Note:         v.Notes,
+				Display:      v.Text(),
+				OriginalText: v.Text(),
+			}
+			if v.Unit != pcs && v.Unit != "" {
+				mi.Unit = &mealieName{string(v.Unit)}
+			}
+			m.RecipeIngredient[j] = mi
+		}
+		for j, v := range rcp.Steps {
+			m.RecipeInstructions[j] = mealieStep{v}
+		}
+		for j, v := range rcp.Tags {
+			m.Tags[j] = mealieName{v}
+		}
+		if !rcp.Created.IsZero() {
+			m.DateAdded = rcp.Created.Format("2006-01-02")
+		}
+		xs[i] = m
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if len(xs) == 1 {
+		return enc.Encode(xs[0])
+	}
+	return enc.Encode(xs)
+}
+
+// readJSONObjects takes a JSON file with an object or a list of objects, or a zip file containing such JSON files with
+// extension ext, and returns all objects.
+func readJSONObjects(r io.Reader, ext string, ir *ImportReport) ([]map[string]interface{}, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return decodeJSONObjects(data)
+	}
+	xs := []map[string]interface{}{}
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ext) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ir.unpack(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", f.Name, err)
+		}
+		objs, err := decodeJSONObjects(data)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", f.Name, err)
+		}
+		xs = append(xs, objs...)
+	}
+	return xs, nil
+}
+
+// decodeJSONObjects takes JSON with an object or a list of objects and returns the objects.
+func decodeJSONObjects(data []byte) ([]map[string]interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	xs := []map[string]interface{}{}
+	for _, x := range toList(v) {
+		if m, ok := x.(map[string]interface{}); ok {
+			xs = append(xs, m)
+		}
+	}
+	return xs, nil
+}
+
+// toList takes a JSON value and returns it as list: a list as is, nil as empty list and any other value as list with
+// one element.
+func toList(v interface{}) []interface{} {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return x
+	}
+	return []interface{}{v}
+}
@@ -0,0 +1,247 @@
+package gocookbook
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	reMMHeader = regexp.MustCompile(`(?i)^(?:MMMMM|-----).*meal-?master`)                    // Start of a MealMaster recipe.
+	reMMEnd    = regexp.MustCompile(`^(?:MMMMM|-----)\s*$`)                                  // End of a MealMaster recipe.
+	reMMField  = regexp.MustCompile(`(?i)^\s*(title|categories|yield|servings)\s*:\s*(.*)$`) // Header field, e.g. "Title: Pannenkoeken".
+	reMMIngrd  = regexp.MustCompile(`^([ 0-9./-]{7}) ([A-Za-z ]{2}) (\S.*)$`)                // Ingredient in columns: amount, unit and item.
+	reMMContd  = regexp.MustCompile(`^ {11}-(.*)$`)                                          // Continuation of the previous ingredient.
+)
+
+// mmUnits contains the MealMaster unit codes and the units (or words) they stand for.
+var mmUnits = map[string]string{
+	"":   "",
+	"x":  "",
+	"ea": "",
+	"sm": "small",
+	"md": "medium",
+	"lg": "large",
+	"t":  string(tsp),
+	"ts": string(tsp),
+	"T":  string(tbsp),
+	"tb": string(tbsp),
+	"c":  string(cup),
+	"fl": string(flOz),
+	"pt": string(pint),
+	"qt": string(quart),
+	"oz": string(oz),
+	"lb": string(lb),
+	"ml": string(ml),
+	"cl": string(cl),
+	"dl": string(dl),
+	"l":  string(liter),
+	"mg": string(mg),
+	"g":  string(gram),
+	"kg": string(kilo),
+	"pn": string(pinch),
+	"cn": string(can),
+	"bn": string(bunch),
+}
+
+// mmFractions contains the plain text for the fractions used when printing an amount.
+var mmFractions = map[string]string{
+	"⅛": "1/8", "¼": "1/4", "⅓": "1/3", "⅜": "3/8", "½": "1/2", "⅝": "5/8", "⅔": "2/3", "¾": "3/4", "⅞": "7/8",
+}
+
+// mmCodes contains the MealMaster unit code per Unit, used when exporting.
+var mmCodes = map[Unit]string{
+	tsp: "ts", tbsp: "tb", cup: "c", flOz: "fl", pint: "pt", quart: "qt", oz: "oz", lb: "lb", ml: "ml", cl: "cl",
+	dl: "dl", liter: "l", mg: "mg", gram: "g", kilo: "kg", pinch: "pn", can: "cn", bunch: "bn",
+}
+
+// importMealMaster takes a MealMaster text file and returns the recipes in it.
+func importMealMaster(r io.Reader, ir *ImportReport) ([]Recipe, error) {
+	rcps := []Recipe{}
+	var rcp *Recipe
+	var ingrds, directions []string
+	inIngrds := false
+	finish := func() {
+		if rcp == nil {
+			return
+		}
+		rcp.Ingrs = ir.ingredients(rcp.Name, ingrds)
+		var notes string
+		rcp.Steps, notes = TextToSteps(strings.Join(directions, "\n"))
+		rcp.Notes = joinNotes(rcp.Notes, notes)
+		rcps = append(rcps, *rcp)
+		rcp, ingrds, directions = nil, nil, nil
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(strings.ReplaceAll(sc.Text(), "\t", "        "), " \r")
+		switch {
+		case reMMHeader.MatchString(line):
+			finish()
+			rcp = &Recipe{Tags: []string{}}
+			inIngrds = false
+			continue
+		case rcp == nil:
+			continue
+		case reMMEnd.MatchString(line):
+			finish()
+			continue
+		}
+		if line == "" && len(directions) == 0 {
+			continue
+		}
+		if m := reMMField.FindStringSubmatch(line); m != nil && len(ingrds) == 0 && len(directions) == 0 {
+			switch strings.ToLower(m[1]) {
+			case "title":
+				rcp.Name = m[2]
+			case "categories":
+				for _, t := range strings.Split(m[2], ",") {
+					if t = strings.TrimSpace(t); t != "" && !strings.EqualFold(t, "none") {
+						rcp.Tags = append(rcp.Tags, t)
+					}
+				}
+			default:
+				rcp.Portions = schemaYield(m[2])
+			}
+			inIngrds = true
+			continue
+		}
+		if inIngrds && len(directions) == 0 {
+			if xs, ok := mmIngredients(line, ir, rcp.Name); ok {
+				for _, v := range xs {
+					if strings.HasPrefix(v, "-") && len(ingrds) > 0 {
+						ingrds[len(ingrds)-1] += " " + strings.TrimSpace(v[1:])
+						continue
+					}
+					ingrds = append(ingrds, v)
+				}
+				continue
+			}
+		}
+		directions = append(directions, strings.TrimSpace(line))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if rcp != nil {
+		ir.warn(rcp.Name, "end of recipe not found")
+		finish()
+	}
+	return rcps, nil
+}
+
+// mmIngredients takes a line in the ingredients of a MealMaster recipe and returns the ingredients in it as text and
+// true, or false if the line does not contain ingredients. A line can contain two ingredients (in two columns), a
+// continuation of the previous ingredient (starting with "-") or a section header, which is skipped.
+func mmIngredients(line string, ir *ImportReport, name string) ([]string, bool) {
+	if strings.HasPrefix(line, "MMMMM") || strings.HasPrefix(line, "-----") {
+		// section header, e.g. "-----SAUS-----"
+		return nil, true
+	}
+	if m := reMMContd.FindStringSubmatch(line); m != nil {
+		return []string{"-" + m[1]}, true
+	}
+	var cols []string
+	if len(line) > 41 && (reMMIngrd.MatchString(line[41:]) || reMMContd.MatchString(line[41:])) {
+		cols = []string{strings.TrimRight(line[:41], " "), line[41:]}
+	} else {
+		cols = []string{line}
+	}
+	var xs []string
+	for _, col := range cols {
+		if m := reMMContd.FindStringSubmatch(col); m != nil {
+			xs = append(xs, "-"+m[1])
+			continue
+		}
+		m := reMMIngrd.FindStringSubmatch(col)
+		if m == nil {
+			return nil, false
+		}
+		code := strings.TrimSpace(m[2])
+		unit, ok := mmUnits[code]
+		if !ok {
+			ir.warn(name, "unknown MealMaster unit '%v'", code)
+			unit = code
+		}
+		xs = append(xs, strings.Join(strings.Fields(strings.Join([]string{m[1], unit, m[3]}, " ")), " "))
+	}
+	return xs, true
+}
+
+// exportMealMaster writes the recipes as MealMaster text to w.
+func exportMealMaster(w io.Writer, rcps []Recipe) error {
+	bw := bufio.NewWriter(w)
+	for _, rcp := range rcps {
+		fmt.Fprintf(bw, "MMMMM----- Recipe via Meal-Master (tm) v8.05\n\n")
+		fmt.Fprintf(bw, "      Title: %v\n", rcp.Name)
+		fmt.Fprintf(bw, " Categories: %v\n", strings.Join(rcp.Tags, ", "))
+		if rcp.Portions > 0 {
+			fmt.Fprintf(bw, "      Yield: %v servings\n", formatPortions(rcp.Portions))
+		}
+		bw.WriteString("\n")
+		for _, v := range rcp.Ingrs {
+			code, ok := mmCodes[v.Unit]
+			item := v.Item
+			if !ok && v.Unit != pcs {
+				// unit without MealMaster code is included in the item
+				item = fmt.Sprintf("%v %v", v.Unit, v.Item)
+			}
+			if v.Notes != "" {
+				item = fmt.Sprintf("%v, %v", item, v.Notes)
+			}
+			var amount string
+			if v.Amount > 0 {
+				amount = mmAmount(v)
+			}
+			fmt.Fprintf(bw, "%7s %-2s %v\n", amount, code, item)
+		}
+		bw.WriteString("\n")
+		for _, s := range rcp.Steps {
+			for _, line := range wrapText(s, 74) {
+				fmt.Fprintf(bw, "  %v\n", line)
+			}
+			bw.WriteString("\n")
+		}
+		if rcp.Notes != "" {
+			for _, line := range wrapText("Notes: "+rcp.Notes, 74) {
+				fmt.Fprintf(bw, "  %v\n", line)
+			}
+			bw.WriteString("\n")
+		}
+		bw.WriteString("MMMMM\n\n")
+	}
+	return bw.Flush()
+}
+
+// mmAmount returns the amount of the Ingredient as text for MealMaster, with plain fractions, e.g. "1 1/2" or "2-3".
+func mmAmount(i Ingredient) string {
+	i.Approx = false
+	s := i.printAmount(FormatKitchen)
+	for _, v := range kitchenFractions {
+		s = strings.ReplaceAll(s, v.s, " "+mmFractions[v.s])
+	}
+	s = strings.ReplaceAll(s, "- ", "-")
+	return strings.TrimSpace(s)
+}
+
+// wrapText takes a text and returns it as lines of at most width characters, wrapped at spaces.
+func wrapText(s string, width int) []string {
+	var lines []string
+	for _, p := range strings.Split(s, "\n") {
+		line := ""
+		for _, w := range strings.Fields(p) {
+			if line != "" && len([]rune(line))+1+len([]rune(w)) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += w
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
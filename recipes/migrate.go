@@ -0,0 +1,251 @@
+package gocookbook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Envelope represents the contents of a persisted file: the data together with its kind and the version of its
+// format. Files written before the envelope was introduced contain only the data and are version 0.
+type Envelope struct {
+	Kind    string          // Kind of data, e.g. "recipes".
+	Version int             // Version of the format of the data.
+	Data    json.RawMessage // The data itself.
+}
+
+// Migration upgrades data from one version to the next.
+type Migration struct {
+	Description string                            // Description of the changes, used for logging.
+	Up          func(data []byte) ([]byte, error) // Takes the data in the previous version and returns it in the next.
+}
+
+// Schema represents a kind of persisted data and the migrations to upgrade older versions of it. Migration i upgrades
+// version i to version i+1, so the current version is the number of migrations.
+type Schema struct {
+	Kind       string      // Kind of data, as stored in the Envelope.
+	Migrations []Migration // All migrations, starting with the migration from version 0.
+}
+
+var (
+	errorSchemaVersion = errors.New("file is written by a newer version") // Version is above the current version.
+	errorSchemaKind    = errors.New("file contains a different kind")     // Kind in the Envelope differs.
+)
+
+// RecipesSchema is the Schema of recipes.json.
+var RecipesSchema = Schema{
+	Kind: "recipes",
+	Migrations: []Migration{
+		{"normalise units and items of ingredients and remove stored alternative units", migrateRecipesV1},
+	},
+}
+
+// ConversionSchema is the Schema of conversion.json.
+var ConversionSchema = Schema{
+	Kind: "conversion",
+	Migrations: []Migration{
+		{"replace milliliters per gram by a density per ingredient", migrateConversionV1},
+	},
+}
+
+// Version returns the current version of the Schema.
+func (s Schema) Version() int {
+	return len(s.Migrations)
+}
+
+// Migrate takes the contents of a file of the Schema, with or without Envelope, and returns the data upgraded to the
+// current version together with the version it was stored in. It returns an error if the file contains another kind
+// of data or is written by a newer version.
+func (s Schema) Migrate(data []byte) ([]byte, int, error) {
+	data, version, err := s.open(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	for v := version; v < s.Version(); v++ {
+		if data, err = s.Migrations[v].Up(data); err != nil {
+			return nil, 0, fmt.Errorf("unable to migrate %v from version %v: %w", s.Kind, v, err)
+		}
+	}
+	return data, version, nil
+}
+
+// open takes the contents of a file and returns the data and its version, without checking the version against the
+// current version.
+func (s Schema) open(data []byte) ([]byte, int, error) {
+	m := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &m); err != nil || m["Version"] == nil || m["Data"] == nil || len(m) > 3 {
+		// Not an Envelope, e.g. a list of recipes
+		return data, 0, nil
+	}
+	var e Envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return data, 0, nil
+	}
+	switch {
+	case e.Kind != s.Kind:
+		return nil, 0, fmt.Errorf("%w: '%v' instead of '%v'", errorSchemaKind, e.Kind, s.Kind)
+	case e.Version > s.Version():
+		return nil, 0, fmt.Errorf("%w (%v version %v, supported up to %v)", errorSchemaVersion, s.Kind, e.Version,
+			s.Version())
+	}
+	return e.Data, e.Version, nil
+}
+
+// Marshal takes data of the Schema and returns it in an Envelope of the current version, as indented JSON.
+func (s Schema) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(Envelope{s.Kind, s.Version(), data})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "    "); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal takes the contents of a file of the Schema and stores the data, upgraded to the current version, in v.
+func (s Schema) Unmarshal(data []byte, v interface{}) error {
+	data, _, err := s.Migrate(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Load reads the file fname of the Schema and stores the data in v. If the file has an older version, it is first
+// copied to fname with the extension ".v{version}.bak" and then written again in the current version. It returns the
+// version the file was stored in.
+func (s Schema) Load(fname string, v interface{}) (int, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return 0, err
+	}
+	migrated, version, err := s.Migrate(data)
+	if err != nil {
+		return 0, fmt.Errorf("%s cannot be loaded (%w)", fname, err)
+	}
+	if err := json.Unmarshal(migrated, v); err != nil {
+		return 0, fmt.Errorf("%s is corrupt. Please correct or delete the file (%v)", fname, err)
+	}
+	if version == s.Version() {
+		return version, nil
+	}
+	if err := os.WriteFile(fmt.Sprintf("%v.v%v.bak", fname, version), data, 0644); err != nil {
+		return version, fmt.Errorf("unable to back up %v before migrating: %w", fname, err)
+	}
+	return version, s.Save(fname, v)
+}
+
+// Save writes v in an Envelope of the current version of the Schema to the file fname. The data is written to a
+// temporary file that then replaces fname, so fname contains either the old or the new data, even if writing fails.
+func (s Schema) Save(fname string, v interface{}) error {
+	return SaveAll(SchemaFile{s, fname, v})
+}
+
+// SchemaFile represents data to be written to a file of a Schema by SaveAll.
+type SchemaFile struct {
+	Schema Schema      // Schema of the file.
+	Name   string      // Name of the file.
+	Data   interface{} // Data to write in the file.
+}
+
+// SaveAll writes the data of each SchemaFile like Save, e.g. recipes together with the tags they use, using
+// WriteFiles: if writing fails (e.g. because the disk is full) none of the files is changed.
+func SaveAll(files ...SchemaFile) error {
+	contents := make(map[string][]byte, len(files))
+	for _, f := range files {
+		data, err := f.Schema.Marshal(f.Data)
+		if err != nil {
+			return fmt.Errorf("unable to save %v: %w", f.Name, err)
+		}
+		contents[f.Name] = data
+	}
+	return WriteFiles(contents)
+}
+
+// WriteFiles takes the contents of files by name and writes them. All contents are first written to temporary files,
+// and the files are only replaced once all temporary files are written, so if writing fails none of the files is
+// changed. Replacing the files themselves is not atomic: if renaming a temporary file fails, the files before it (in
+// order of name) are already replaced.
+func WriteFiles(files map[string][]byte) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tmps := make([]string, 0, len(names))
+	defer func() {
+		// temporary files that are not renamed
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}()
+	for _, name := range names {
+		tmp := name + ".tmp"
+		tmps = append(tmps, tmp)
+		if err := os.WriteFile(tmp, files[name], 0644); err != nil {
+			return err
+		}
+	}
+	for i, name := range names {
+		if err := os.Rename(tmps[i], name); err != nil {
+			tmps = tmps[i:]
+			return err
+		}
+	}
+	tmps = nil
+	return nil
+}
+
+// migrateRecipesV1 upgrades recipes to version 1: units are stored as the unit in the Registry instead of the text
+// that was entered (e.g. "gram" becomes "g"), items are stored in lowercase, lists are never null and the alternative
+// units, which are determined when showing a recipe, are no longer stored.
+func migrateRecipesV1(data []byte) ([]byte, error) {
+	var rcps []map[string]interface{}
+	if err := json.Unmarshal(data, &rcps); err != nil {
+		return nil, err
+	}
+	for _, rcp := range rcps {
+		for _, k := range []string{"Ingrs", "Steps", "Tags"} {
+			if rcp[k] == nil {
+				rcp[k] = []interface{}{}
+			}
+		}
+		ingrs, _ := rcp["Ingrs"].([]interface{})
+		for _, v := range ingrs {
+			ingr, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			delete(ingr, "AltUnits")
+			if u, ok := ingr["Unit"].(string); ok {
+				if d, ok := Registry.Lookup(u); ok {
+					ingr["Unit"] = string(d.Unit)
+				}
+			}
+			if item, ok := ingr["Item"].(string); ok {
+				ingr["Item"] = strings.ToLower(strings.TrimSpace(item))
+			}
+		}
+	}
+	return json.Marshal(rcps)
+}
+
+// migrateConversionV1 upgrades the conversion table to version 1: a map of item to milliliters per gram becomes a
+// Density per item. Tables that already contain a Density per item are kept as they are.
+func migrateConversionV1(data []byte) ([]byte, error) {
+	var dt DensityTable
+	if err := json.Unmarshal(data, &dt); err != nil {
+		return nil, err
+	}
+	return json.Marshal(dt)
+}
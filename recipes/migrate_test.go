@@ -0,0 +1,146 @@
+package gocookbook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateRecipesV1(t *testing.T) {
+	old := `[{"Id": 10, "Name": "Pannenkoeken", "Ingrs": [
+		{"Amount": 250, "Unit": "gram", "Item": " Bloem", "Notes": "", "AltUnits": "(2 cup)"},
+		{"Amount": 2, "Unit": "Eetlepels", "Item": "suiker"},
+		{"Amount": 1, "Unit": "onbekend", "Item": "ei"}
+	], "Steps": null, "Tags": null}]`
+	data, err := migrateRecipesV1([]byte(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rcps []Recipe
+	if err := json.Unmarshal(data, &rcps); err != nil {
+		t.Fatal(err)
+	}
+	want := []Ingredient{
+		{Amount: 250, Unit: gram, Item: "bloem"},
+		{Amount: 2, Unit: tbsp, Item: "suiker"},
+		{Amount: 1, Unit: "onbekend", Item: "ei"},
+	}
+	if len(rcps) != 1 || len(rcps[0].Ingrs) != len(want) {
+		t.Fatalf("Want 1 recipe with %v ingredients, Got: %+v", len(want), rcps)
+	}
+	for i, w := range want {
+		if got := rcps[0].Ingrs[i]; got != w {
+			t.Errorf("Ingredient %v failed. Want: %+v, Got: %+v", i, w, got)
+		}
+	}
+	if rcps[0].Steps == nil || rcps[0].Tags == nil {
+		t.Errorf("Want empty steps and tags, Got: %#v, %#v", rcps[0].Steps, rcps[0].Tags)
+	}
+}
+
+func TestMigrateConversionV1(t *testing.T) {
+	cases := []string{
+		`{"bloem": 1.8, "Suiker": 1.2}`,
+		`{"bloem": {"Name": "bloem", "MlPerGram": 1.8}, "suiker": {"Name": "suiker", "MlPerGram": 1.2}}`,
+	}
+	for i, c := range cases {
+		data, err := migrateConversionV1([]byte(c))
+		if err != nil {
+			t.Fatalf("Case %v failed: %v", i, err)
+		}
+		m := map[string]Density{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatalf("Case %v failed: %v", i, err)
+		}
+		if len(m) != 2 || m["bloem"].MlPerGram != 1.8 || m["suiker"].Name != "suiker" {
+			t.Errorf("Case %v failed. Got: %+v", i, m)
+		}
+	}
+}
+
+func TestSchemaMigrate(t *testing.T) {
+	s := Schema{Kind: "test", Migrations: []Migration{
+		{"v1", func(data []byte) ([]byte, error) { return append([]byte("["), append(data, ']')...), nil }},
+		{"v2", func(data []byte) ([]byte, error) { return append([]byte(`{"x":`), append(data, '}')...), nil }},
+	}}
+	cases := []struct {
+		in      string
+		want    string
+		version int
+		err     error
+	}{
+		{`1`, `{"x":[1]}`, 0, nil},
+		{`{"Kind": "test", "Version": 1, "Data": [1]}`, `{"x":[1]}`, 1, nil},
+		{`{"Kind": "test", "Version": 2, "Data": {"x":[1]}}`, `{"x":[1]}`, 2, nil},
+		{`{"Kind": "test", "Version": 3, "Data": 1}`, "", 0, errorSchemaVersion},
+		{`{"Kind": "other", "Version": 1, "Data": 1}`, "", 0, errorSchemaKind},
+	}
+	for i, c := range cases {
+		got, version, err := s.Migrate([]byte(c.in))
+		switch {
+		case !errors.Is(err, c.err):
+			t.Errorf("Case %v failed. Want error: %v, Got: %v", i, c.err, err)
+		case c.err == nil && (string(got) != c.want || version != c.version):
+			t.Errorf("Case %v failed. Want: %v (version %v), Got: %s (version %v)", i, c.want, c.version, got, version)
+		}
+	}
+}
+
+func TestSchemaLoad(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "conversion.json")
+	old := []byte(`{"bloem": 1.8}`)
+	if err := os.WriteFile(fname, old, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var dt DensityTable
+	version, err := ConversionSchema.Load(fname, &dt)
+	if err != nil || version != 0 || dt["bloem"].MlPerGram != 1.8 {
+		t.Fatalf("Want version 0 with bloem, Got: %v, %+v (%v)", version, dt, err)
+	}
+	// Original file is backed up before migrating
+	if b, err := os.ReadFile(fname + ".v0.bak"); err != nil || string(b) != string(old) {
+		t.Errorf("Want backup %s, Got: %s (%v)", old, b, err)
+	}
+	// File is stored in the current version
+	dt = nil
+	version, err = ConversionSchema.Load(fname, &dt)
+	if err != nil || version != ConversionSchema.Version() || dt["bloem"].MlPerGram != 1.8 {
+		t.Errorf("Want version %v with bloem, Got: %v, %+v (%v)", ConversionSchema.Version(), version, dt, err)
+	}
+	// No temporary file is left after saving
+	if _, err := os.Stat(fname + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Want no temporary file, Got: %v", err)
+	}
+}
+
+func TestSaveAll(t *testing.T) {
+	dir := t.TempDir()
+	rname, tname := filepath.Join(dir, "recipes.json"), filepath.Join(dir, "tags.json")
+	rcps, tags := []Recipe{{Id: 1, Name: "Soep", Tags: []string{"Soep"}}}, TagTable{"Soep": {Color: "#ff0000"}}
+	if err := SaveAll(SchemaFile{RecipesSchema, rname, rcps}, SchemaFile{TagsSchema, tname, tags}); err != nil {
+		t.Fatal(err)
+	}
+	var gotRcps []Recipe
+	var gotTags TagTable
+	if _, err := RecipesSchema.Load(rname, &gotRcps); err != nil || len(gotRcps) != 1 {
+		t.Errorf("Want 1 recipe, Got: %+v (%v)", gotRcps, err)
+	}
+	if _, err := TagsSchema.Load(tname, &gotTags); err != nil || gotTags["Soep"].Color != "#ff0000" {
+		t.Errorf("Want tag Soep, Got: %+v (%v)", gotTags, err)
+	}
+	// If a file cannot be written, none of the files is changed
+	old, _ := os.ReadFile(rname)
+	err := SaveAll(SchemaFile{RecipesSchema, rname, []Recipe{}}, SchemaFile{TagsSchema, filepath.Join(dir, "geen", "tags.json"), tags})
+	if err == nil {
+		t.Errorf("Want error for a file in a folder that does not exist, Got: nil")
+	}
+	if b, _ := os.ReadFile(rname); string(b) != string(old) {
+		t.Errorf("Want recipes unchanged, Got: %s", b)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(files) != 0 {
+		t.Errorf("Want no temporary files, Got: %v", files)
+	}
+}
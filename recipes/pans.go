@@ -0,0 +1,79 @@
+package gocookbook
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+type Shape string // Shape represents the shape of a baking pan or mould.
+
+// Pan represents the baking pan or mould a recipe is written for.
+type Pan struct {
+	Shape  Shape   // Shape of the pan, e.g. round.
+	Width  float64 // Width in cm. For a round pan this is the diameter.
+	Length float64 // Length in cm. Only used for rectangular pans.
+}
+
+// Different shapes of pans. Note: don't change the actual string without changing the existing data.
+const (
+	roundPan  = Shape("rond")
+	squarePan = Shape("vierkant")
+	rectPan   = Shape("rechthoek")
+)
+
+// Shapes contains all shapes that can be selected for a Pan.
+var Shapes = []Shape{roundPan, squarePan, rectPan}
+
+var errorInvalidPan = errors.New("pan has no valid size") // Pan cannot be used for scaling.
+
+// NewPan takes a shape, width and length, creates a Pan and returns it with an error if the Pan has no valid size.
+func NewPan(shape Shape, width, length float64) (Pan, error) {
+	p := Pan{Shape: shape, Width: width, Length: length}
+	if p.Area() == 0 {
+		return Pan{}, errorInvalidPan
+	}
+	return p, nil
+}
+
+// Area returns the surface of the bottom of the Pan in cm². It returns 0 if the Pan is not (fully) specified, or if
+// its width or length is not a finite number above zero (e.g. NaN).
+func (p Pan) Area() float64 {
+	var a float64
+	switch {
+	case !positive(p.Width):
+		return 0
+	case p.Shape == roundPan:
+		a = math.Pi * p.Width * p.Width / 4
+	case p.Shape == squarePan:
+		a = p.Width * p.Width
+	case p.Shape == rectPan && positive(p.Length):
+		a = p.Width * p.Length
+	}
+	if !positive(a) {
+		return 0
+	}
+	return a
+}
+
+// String returns the Pan as a readable string, e.g. "rond 24 cm" or "rechthoek 20x30 cm".
+func (p Pan) String() string {
+	switch {
+	case p.Area() == 0:
+		return ""
+	case p.Shape == rectPan:
+		return fmt.Sprintf("%v %vx%v cm", p.Shape, p.Width, p.Length)
+	}
+	return fmt.Sprintf("%v %v cm", p.Shape, p.Width)
+}
+
+// adjustRcpByPan adjusts the Recipe r from the Pan it is written for to the Pan p, based on the ratio between the
+// areas of both pans, and returns the adjusted Recipe.
+func adjustRcpByPan(r Recipe, p Pan) (Recipe, error) {
+	if r.Pan.Area() == 0 || p.Area() == 0 {
+		return Recipe{}, errorInvalidPan
+	}
+	newRcp := scaleRcp(r, p.Area()/r.Pan.Area())
+	newRcp.Pan = p
+	return newRcp, nil
+}
@@ -0,0 +1,81 @@
+package gocookbook
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPanArea(t *testing.T) {
+	cases := []struct {
+		p    Pan
+		want float64
+	}{
+		{Pan{roundPan, 20, 0}, math.Pi * 100},
+		{Pan{squarePan, 20, 0}, 400},
+		{Pan{rectPan, 20, 30}, 600},
+		{Pan{rectPan, 20, 0}, 0},
+		{Pan{"onbekend", 20, 30}, 0},
+		{Pan{}, 0},
+		{Pan{roundPan, math.NaN(), 0}, 0},
+		{Pan{squarePan, math.Inf(1), 0}, 0},
+		{Pan{rectPan, 20, math.NaN()}, 0},
+		{Pan{rectPan, 20, math.Inf(1)}, 0},
+		{Pan{squarePan, 1e200, 0}, 0},
+	}
+	for i, c := range cases {
+		if got := c.p.Area(); got != c.want {
+			t.Errorf("Case %v failed. Want: %v, Got: %v", i, c.want, got)
+		}
+	}
+}
+
+func TestNewPan(t *testing.T) {
+	cases := []struct {
+		shape         Shape
+		width, length float64
+		err           error
+	}{
+		{roundPan, 24, 0, nil},
+		{rectPan, 20, 30, nil},
+		{roundPan, 0, 0, errorInvalidPan},
+		{roundPan, -24, 0, errorInvalidPan},
+		{roundPan, math.NaN(), 0, errorInvalidPan},
+		{roundPan, math.Inf(1), 0, errorInvalidPan},
+		{rectPan, 20, math.NaN(), errorInvalidPan},
+		{rectPan, 20, math.Inf(1), errorInvalidPan},
+	}
+	for i, c := range cases {
+		if _, err := NewPan(c.shape, c.width, c.length); !errors.Is(err, c.err) {
+			t.Errorf("Case %v failed. Want error: %v, Got: %v", i, c.err, err)
+		}
+	}
+}
+
+func TestAdjustRcpByPan(t *testing.T) {
+	r := Recipe{
+		Portions: 12,
+		Pan:      Pan{roundPan, 20, 0},
+		Ingrs: []Ingredient{
+			{Amount: 200, Unit: gram, Item: "bloem"},
+			{Amount: 1, Unit: pcs, Item: "vanillestokje", Fixed: true},
+		},
+	}
+	got, err := adjustRcpByPan(r, Pan{roundPan, 24, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 288.0; math.Abs(got.Ingrs[0].Amount-want) > 1e-9 {
+		t.Errorf("Want: %v, Got: %v", want, got.Ingrs[0].Amount)
+	}
+	if got.Ingrs[1].Amount != 1 {
+		t.Errorf("fixed ingredient scaled. Want: %v, Got: %v", 1, got.Ingrs[1].Amount)
+	}
+	if got.Pan.Width != 24 {
+		t.Errorf("pan not updated. Want: %v, Got: %v", 24, got.Pan.Width)
+	}
+	r.Pan = Pan{}
+	if _, err := adjustRcpByPan(r, Pan{roundPan, 24, 0}); !errors.Is(err, errorInvalidPan) {
+		t.Errorf("Want: %v, Got: %v", errorInvalidPan, err)
+	}
+}
@@ -0,0 +1,173 @@
+package gocookbook
+
+import (
+	"sort"
+	"strings"
+)
+
+// Pantry represents the ingredients a user has at home. The amount of an Ingredient is optional: without amount
+// there is assumed to be enough of it for any recipe.
+type Pantry []Ingredient
+
+// PantryMatch represents how well the ingredients of a recipe are covered by a Pantry.
+type PantryMatch struct {
+	Recipe   Recipe       // Recipe, adjusted to the requested portions.
+	Have     []Ingredient // Ingredients of the recipe that are in the pantry, excluding staples.
+	Short    []Ingredient // Ingredients of the recipe that are in the pantry, but not enough of them.
+	Missing  []Ingredient // Ingredients of the recipe that are not in the pantry.
+	Coverage float64      // Share of the ingredients (excluding staples) that are in the pantry in sufficient amount.
+}
+
+// Staples contains the items that are assumed to always be available, so they are never missing from a recipe. An
+// ingredient is a staple if all words of its item are staples, e.g. "zout en peper" but not "rode peper".
+var Staples = []string{"zout", "peper", "olie", "olijfolie", "zonnebloemolie", "water", "salt", "pepper", "oil",
+	"olive oil"}
+
+// ParsePantry takes text with one ingredient per line, e.g. "500 g kipfilet" or "eieren", and returns it as Pantry.
+// Empty lines are skipped.
+func ParsePantry(s string) Pantry {
+	var p Pantry
+	for _, r := range ParseIngrds(s) {
+		i := r.Ingredient
+		i.Item = strings.ToLower(strings.TrimSpace(i.Item))
+		if i.Item != "" {
+			p = append(p, i)
+		}
+	}
+	return p
+}
+
+// Text returns the Pantry as text with one ingredient per line, which can be parsed again by ParsePantry.
+func (p Pantry) Text() string {
+	lines := make([]string, len(p))
+	for i, ingr := range p {
+		lines[i] = ingr.Text()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Match takes a Cookbook and a number of portions and returns the recipes that contain at least one ingredient
+// from the Pantry, ranked by the share of their ingredients that are covered (and then by the fewest missing
+// ingredients). Recipes are first adjusted to the portions, unless portions is 0 or the recipe has no portions.
+// Staples are always available.
+func (p Pantry) Match(cb Cookbook, portions float64) []PantryMatch {
+	var matches []PantryMatch
+	for _, r := range cb {
+		if a, err := adjustRcp(r, portions); err == nil {
+			r = a
+		}
+		m := PantryMatch{Recipe: r}
+		for _, ingr := range r.Ingrs {
+			switch have, found := p.find(ingr); {
+			case isStaple(ingr):
+			case !found:
+				m.Missing = append(m.Missing, ingr)
+			case !enough(have, ingr):
+				m.Short = append(m.Short, ingr)
+			default:
+				m.Have = append(m.Have, ingr)
+			}
+		}
+		total := len(m.Have) + len(m.Short) + len(m.Missing)
+		if len(m.Have)+len(m.Short) == 0 {
+			continue
+		}
+		m.Coverage = float64(len(m.Have)) / float64(total)
+		matches = append(matches, m)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case a.Coverage != b.Coverage:
+			return a.Coverage > b.Coverage
+		case len(a.Missing)+len(a.Short) != len(b.Missing)+len(b.Short):
+			return len(a.Missing)+len(a.Short) < len(b.Missing)+len(b.Short)
+		}
+		return a.Recipe.Name < b.Recipe.Name
+	})
+	return matches
+}
+
+// find takes an Ingredient of a recipe and returns the item in the Pantry that matches it and true, or false if the
+// Pantry does not contain it.
+func (p Pantry) find(ingr Ingredient) (Ingredient, bool) {
+	d, known := Densities.Find(ingr.key())
+	for _, have := range p {
+		if known {
+			if c, ok := Densities.Find(have.key()); ok && c.Name == d.Name {
+				return have, true
+			}
+		}
+		if pantryItemMatch(have.Item, ingr.Item) {
+			return have, true
+		}
+	}
+	return Ingredient{}, false
+}
+
+// pantryItemMatch returns true if every word of the item in the pantry is a word of the item in the recipe, in
+// singular or plural, e.g. "ui" matches "rode uien". Parts of compound words do not match ("room" is not
+// "roomboter"); compounds that are the same ingredient, e.g. "kip" and "kipfilet", are matched by find through the
+// synonyms in the catalogue.
+func pantryItemMatch(have, item string) bool {
+	words := strings.Fields(fold(item))
+	haveWords := strings.Fields(fold(have))
+	for _, hw := range haveWords {
+		found := false
+		for _, w := range words {
+			if sameWord(hw, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(haveWords) > 0
+}
+
+// isStaple returns true if all words of the item of the Ingredient are Staples (ignoring stop words like "en").
+func isStaple(ingr Ingredient) bool {
+	item := fold(ingr.Item)
+	for _, s := range Staples {
+		if item == s {
+			return true
+		}
+	}
+	words := 0
+	for _, w := range strings.Fields(item) {
+		if stopWords[w] {
+			continue
+		}
+		words++
+		found := false
+		for _, s := range Staples {
+			if sameWord(w, s) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return words > 0
+}
+
+// enough returns true if the amount in the pantry (have) is at least the amount needed for the Ingredient. If either
+// amount is unknown or the amounts cannot be compared, there is assumed to be enough.
+func enough(have, need Ingredient) bool {
+	if have.Amount == 0 || need.Amount == 0 {
+		return true
+	}
+	if f, err := Registry.Convert(have.Amount, have.Unit, need.Unit); err == nil {
+		return f >= need.Amount
+	}
+	gHave, ok1 := have.Grams()
+	gNeed, ok2 := need.Grams()
+	if ok1 && ok2 {
+		return gHave >= gNeed
+	}
+	return true
+}
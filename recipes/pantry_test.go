@@ -0,0 +1,93 @@
+package gocookbook
+
+import (
+	"testing"
+)
+
+func TestParsePantry(t *testing.T) {
+	p := ParsePantry("500 g Kipfilet\n\neieren\n2 el olijfolie\n")
+	if len(p) != 3 {
+		t.Fatalf("Want 3 items, Got: %+v", p)
+	}
+	if p[0].Item != "kipfilet" || p[0].Amount != 500 || p[0].Unit != gram || p[1].Item != "eieren" || p[1].Amount != 0 {
+		t.Errorf("Want kipfilet and eieren, Got: %+v", p)
+	}
+	if got := ParsePantry(p.Text()); len(got) != len(p) || got[0] != p[0] || got[1] != p[1] || got[2] != p[2] {
+		t.Errorf("Want %+v after parsing text again, Got: %+v", p, got)
+	}
+}
+
+func TestPantryItemMatch(t *testing.T) {
+	cases := []struct {
+		have, item string
+		want       bool
+	}{
+		{"ui", "rode uien", true},
+		{"kaas", "geraspte kaas", true},
+		{"kip", "kipfilet", false},
+		{"room", "roomboter", false},
+		{"kaas", "kaasstengels", false},
+		{"tomaat", "tomaten", true},
+		{"Crème fraîche", "creme fraiche", true},
+		{"rode ui", "ui", false},
+		{"kip", "pasta", false},
+		{"", "pasta", false},
+	}
+	for _, c := range cases {
+		if got := pantryItemMatch(c.have, c.item); got != c.want {
+			t.Errorf("Match %q with %q failed. Want: %v, Got: %v", c.have, c.item, c.want, got)
+		}
+	}
+}
+
+func TestIsStaple(t *testing.T) {
+	cases := map[string]bool{"zout": true, "Zout en peper": true, "olijfolie": true, "water": true,
+		"rode peper": false, "waterkers": false, "boter": false}
+	for item, want := range cases {
+		if got := isStaple(Ingredient{Item: item}); got != want {
+			t.Errorf("Staple %q failed. Want: %v, Got: %v", item, want, got)
+		}
+	}
+}
+
+func TestPantryMatch(t *testing.T) {
+	// kip and kipfilet are the same ingredient in the catalogue
+	Densities = DensityTable{}
+	defer func() { Densities = DensityTable{} }()
+	Densities.Add(Density{Name: "kip", Synonyms: []string{"kipfilet"}})
+	cb := Cookbook{
+		{Id: 10, Name: "Omelet", Portions: 1, Ingrs: []Ingredient{
+			{Amount: 3, Unit: pcs, Item: "eieren"}, {Amount: 1, Unit: tbsp, Item: "boter"}, {Item: "zout en peper"}}},
+		{Id: 20, Name: "Kip met rijst", Portions: 2, Ingrs: []Ingredient{
+			{Amount: 300, Unit: gram, Item: "kipfilet"}, {Amount: 200, Unit: gram, Item: "rijst"},
+			{Amount: 1, Unit: pcs, Item: "ui"}, {Amount: 2, Unit: tbsp, Item: "olie"}}},
+		{Id: 30, Name: "Pasta pesto", Portions: 2, Ingrs: []Ingredient{
+			{Amount: 250, Unit: gram, Item: "pasta"}, {Amount: 100, Unit: gram, Item: "pesto"}}},
+		{Id: 40, Name: "Water", Ingrs: []Ingredient{{Amount: 1, Unit: liter, Item: "water"}}},
+	}
+	p := Pantry{{Item: "eieren"}, {Item: "boter"}, {Amount: 0.5, Unit: kilo, Item: "kip"}, {Item: "uien"},
+		{Amount: 100, Unit: gram, Item: "rijst"}}
+	matches := p.Match(cb, 0)
+	if len(matches) != 2 {
+		t.Fatalf("Want 2 recipes (pasta and water have nothing from the pantry), Got: %+v", matches)
+	}
+	if m := matches[0]; m.Recipe.Id != 10 || m.Coverage != 1 || len(m.Have) != 2 || len(m.Missing) != 0 {
+		t.Errorf("Want omelet fully covered without staples, Got: %+v", m)
+	}
+	m := matches[1]
+	if m.Recipe.Id != 20 || len(m.Have) != 2 || len(m.Short) != 1 || m.Short[0].Item != "rijst" || len(m.Missing) != 0 {
+		t.Errorf("Want kip met rijst with not enough rijst, Got: %+v", m)
+	}
+	if m.Coverage != 2.0/3.0 {
+		t.Errorf("Want coverage 2/3, Got: %v", m.Coverage)
+	}
+	// For 1 portion there is enough rijst, for 4 portions not enough kip
+	matches = p.Match(cb, 1)
+	if m := matches[0]; m.Recipe.Id != 20 || m.Coverage != 1 {
+		t.Errorf("Want kip met rijst first, fully covered for 1 portion, Got: %+v", m)
+	}
+	matches = p.Match(cb, 4)
+	if m := matches[1]; m.Recipe.Id != 20 || len(m.Short) != 2 {
+		t.Errorf("Want not enough kip and rijst for 4 portions, Got: %+v", m)
+	}
+}
@@ -0,0 +1,209 @@
+package gocookbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const paprikaTime = "2006-01-02 15:04:05" // Layout of dates in Paprika.
+
+// paprikaRecipe represents a recipe in a Paprika archive.
+type paprikaRecipe struct {
+	UID         string   `json:"uid"`
+	Name        string   `json:"name"`
+	Ingredients string   `json:"ingredients"`
+	Directions  string   `json:"directions"`
+	Notes       string   `json:"notes"`
+	Servings    string   `json:"servings"`
+	TotalTime   string   `json:"total_time"`
+	Categories  []string `json:"categories"`
+	Source      string   `json:"source"`
+	SourceURL   string   `json:"source_url"`
+	Created     string   `json:"created"`
+	Hash        string   `json:"hash"`
+}
+
+// importPaprika takes a Paprika archive (.paprikarecipes), or a single gzipped recipe (.paprikarecipe), and returns
+// the recipes in it.
+func importPaprika(r io.Reader, ir *ImportReport) ([]Recipe, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		// not an archive, but a single recipe
+		rcp, err := readPaprikaRecipe(bytes.NewReader(data), ir)
+		if err != nil {
+			return nil, err
+		}
+		return []Recipe{rcp}, nil
+	}
+	rcps := []Recipe{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		rcp, err := readPaprikaRecipe(rc, ir)
+		rc.Close()
+		if errors.Is(err, errorImportTooLarge) {
+			return nil, fmt.Errorf("%v: %w", f.Name, err)
+		}
+		if err != nil {
+			ir.warn(f.Name, "skipped (%v)", err)
+			continue
+		}
+		rcps = append(rcps, rcp)
+	}
+	return rcps, nil
+}
+
+// readPaprikaRecipe takes a gzipped Paprika recipe and returns it as Recipe.
+func readPaprikaRecipe(r io.Reader, ir *ImportReport) (Recipe, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return Recipe{}, err
+	}
+	defer gr.Close()
+	data, err := ir.unpack(gr)
+	if err != nil {
+		return Recipe{}, err
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Recipe{}, err
+	}
+	ir.skipUnknown(m, "uid", "name", "ingredients", "directions", "notes", "description", "servings", "total_time",
+		"prep_time", "cook_time", "categories", "source", "source_url", "created", "hash", "photo_hash")
+	name := schemaString(m["name"])
+	rcp := Recipe{
+		Name:       name,
+		Ingrs:      ir.ingredients(name, textLines(schemaString(m["ingredients"]))),
+		Portions:   schemaYield(m["servings"]),
+		Dur:        parseTextDuration(schemaString(m["total_time"])),
+		Source:     schemaString(m["source"]),
+		SourceLink: schemaString(m["source_url"]),
+		Tags:       schemaStrings(m["categories"]),
+	}
+	if rcp.Dur == 0 {
+		rcp.Dur = parseTextDuration(schemaString(m["prep_time"])) + parseTextDuration(schemaString(m["cook_time"]))
+	}
+	var notes string
+	rcp.Steps, notes = TextToSteps(schemaString(m["directions"]))
+	rcp.Notes = joinNotes(schemaString(m["description"]), schemaString(m["notes"]), notes)
+	if t, err := time.ParseInLocation(paprikaTime, schemaString(m["created"]), time.Local); err == nil {
+		rcp.Created, rcp.Updated = t, t
+	}
+	return rcp, nil
+}
+
+// exportPaprika writes the recipes as Paprika archive to w.
+func exportPaprika(w io.Writer, rcps []Recipe) error {
+	zw := zip.NewWriter(w)
+	names := map[string]int{}
+	for _, rcp := range rcps {
+		p := paprikaRecipe{
+			UID:         fmt.Sprintf("GOCOOKBOOK-%v", rcp.Id),
+			Name:        rcp.Name,
+			Ingredients: ingredientText(rcp.Ingrs),
+			Directions:  strings.Join(rcp.Steps, "\n\n"),
+			Notes:       rcp.Notes,
+			Servings:    formatPortions(rcp.Portions),
+			TotalTime:   formatTextDuration(rcp.Dur),
+			Categories:  rcp.Tags,
+			Source:      rcp.Source,
+			SourceURL:   rcp.SourceLink,
+		}
+		if !rcp.Created.IsZero() {
+			p.Created = rcp.Created.Format(paprikaTime)
+		}
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		p.Hash = fmt.Sprintf("%X", sha256.Sum256(data))
+		if data, err = json.Marshal(p); err != nil {
+			return err
+		}
+		f, err := zw.Create(fileName(rcp.Name, names) + ".paprikarecipe")
+		if err != nil {
+			return err
+		}
+		gw := gzip.NewWriter(f)
+		if _, err := gw.Write(data); err != nil {
+			return err
+		}
+		if err := gw.Close(); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ingredientText returns the ingredients as text, with each ingredient on a separate line.
+func ingredientText(xi []Ingredient) string {
+	lines := make([]string, len(xi))
+	for i, v := range xi {
+		lines[i] = v.Text()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatPortions returns the number of portions as text, or an empty string if it is unknown.
+func formatPortions(f float64) string {
+	if f <= 0 {
+		return ""
+	}
+	return fmt.Sprint(f)
+}
+
+// formatTextDuration returns a duration as text, e.g. "1 hr 30 mins", or an empty string if d is zero.
+func formatTextDuration(d time.Duration) string {
+	var xs []string
+	if h := int(d.Hours()); h > 0 {
+		xs = append(xs, fmt.Sprintf("%v hr", h))
+	}
+	if m := int(d.Minutes()) % 60; m > 0 {
+		xs = append(xs, fmt.Sprintf("%v mins", m))
+	}
+	return strings.Join(xs, " ")
+}
+
+// joinNotes takes several notes and returns the notes that are not empty, separated by an empty line.
+func joinNotes(notes ...string) string {
+	var xs []string
+	for _, n := range notes {
+		if n = strings.TrimSpace(n); n != "" {
+			xs = append(xs, n)
+		}
+	}
+	return strings.Join(xs, "\n\n")
+}
+
+// fileName takes the name of a recipe and returns a name that can be used as file name in an archive, unique within
+// names.
+func fileName(name string, names map[string]int) string {
+	s := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if s == "" {
+		s = "recept"
+	}
+	names[s]++
+	if n := names[s]; n > 1 {
+		s = fmt.Sprintf("%v (%v)", s, n)
+	}
+	return s
+}
@@ -0,0 +1,343 @@
+package gocookbook
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseResult represents the result of parsing one line of text into an Ingredient.
+type ParseResult struct {
+	Line       string     // Line as entered.
+	Ingredient Ingredient // Ingredient found in the line.
+	Confidence float64    // Confidence between 0 and 1 that the line is parsed correctly.
+	Remainder  string     // Part of the line that could not be interpreted, if any.
+}
+
+// approxWords contains the words (and signs) that indicate an amount is approximate, e.g. "ca. 200 g".
+var approxWords = map[string]bool{
+	"ca.":      true,
+	"ca":       true,
+	"circa":    true,
+	"ongeveer": true,
+	"about":    true,
+	"approx.":  true,
+	"±":        true,
+	"~":        true,
+	"+/-":      true,
+}
+
+// rangeWords contains the words (and signs) that separate the minimum and maximum of a range, e.g. "2 to 3".
+var rangeWords = map[string]bool{
+	"-":   true,
+	"–":   true,
+	"to":  true,
+	"tot": true,
+}
+
+// multiplyWords contains the words (and signs) that indicate a number of packages, e.g. "2x 400 g".
+var multiplyWords = map[string]bool{
+	"x": true,
+	"×": true,
+}
+
+// amountText represents an amount found in a line of text.
+type amountText struct {
+	amount    float64 // Amount, or minimum amount for a range.
+	amountMax float64 // Maximum amount for a range, zero if not a range.
+	approx    bool    // True if the amount is approximate.
+	start     int     // Index of the first element of the amount (including any approximate word).
+	end       int     // Index of the element following the amount.
+}
+
+// Deductions of the confidence of a ParseResult.
+const (
+	noAmount     = 0.7 // No amount found, the complete line is used as item.
+	noItem       = 0.5 // No item found.
+	noUnit       = 0.1 // No unit found, pieces are assumed.
+	otherOrder   = 0.1 // Unit or item before the amount.
+	hasRemainder = 0.3 // Part of the line could not be interpreted.
+	numberInItem = 0.2 // Item contains a number.
+)
+
+var (
+	reParentheses  = regexp.MustCompile(`\(([^()]*)\)`)                                                            // Text between parentheses, e.g. "(ca. 400 g)".
+	reDecimalComma = regexp.MustCompile(`(\d),(\d)`)                                                               // Decimal comma, e.g. "1,5".
+	reThousands    = regexp.MustCompile(`(?:^|[^\d.])([1-9]\d{0,2}(?:\.\d{3})+)(?:$|[^\d.])`)                      // Number with thousands separators, e.g. "1.000" or "1.000.000".
+	reNumberPrefix = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?[½⅓⅔¼¾⅕⅖⅗⅘⅙⅚⅐⅛⅜⅝⅞⅑⅒]?|[½⅓⅔¼¾⅕⅖⅗⅘⅙⅚⅐⅛⅜⅝⅞⅑⅒])(\D.*)$`) // Number directly followed by text, e.g. "200g" or "2x".
+)
+
+// ParseIngrds takes a string containing multiple lines of ingredients and returns a ParseResult for each line.
+func ParseIngrds(s string) []ParseResult {
+	lines := textToLines(s)
+	results := []ParseResult{}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		results = append(results, ParseIngredient(line))
+	}
+	return results
+}
+
+// PreviewIngrds takes a function that returns the value of a field of the form in which the results of ParseIngrds
+// are checked, e.g. Request.PostFormValue, and the number of rows in the form, and returns the ingredients in the
+// order entered. The fields of row i are Amount{i}, AmountMax{i}, Approx{i}, Unit{i}, Item{i}, Notes{i} and Id{i} (the
+// position). Rows with an item but without amount, e.g. "zout en peper", are kept like TextToIngrds does, without
+// unit; empty rows are skipped. Amounts that are not a finite number above zero (e.g. NaN) are taken as no amount.
+func PreviewIngrds(value func(key string) string, n int) []Ingredient {
+	type row struct {
+		pos  float64
+		ingr Ingredient
+	}
+	var rows []row
+	for i := 0; i < n; i++ {
+		field := func(name string) string { return strings.TrimSpace(value(fmt.Sprintf("%v%v", name, i))) }
+		var ingr Ingredient
+		ingr.Amount, _ = strconv.ParseFloat(field("Amount"), 64)
+		ingr.AmountMax, _ = strconv.ParseFloat(field("AmountMax"), 64)
+		ingr.Approx, _ = strconv.ParseBool(field("Approx"))
+		ingr.Item = strings.ToLower(field("Item")) // All items are stored in lowercase.
+		ingr.Notes = field("Notes")
+		if !positive(ingr.Amount) {
+			ingr.Amount = 0
+		}
+		if ingr.Item == "" && ingr.Amount == 0 {
+			continue
+		}
+		if !positive(ingr.AmountMax) || ingr.AmountMax <= ingr.Amount {
+			ingr.AmountMax = 0
+		}
+		if ingr.Amount != 0 {
+			// a unit without amount, e.g. the first unit of the list, is not stored
+			ingr.Unit = Unit(field("Unit"))
+		}
+		pos, _ := strconv.ParseFloat(field("Id"), 64)
+		rows = append(rows, row{pos, ingr})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].pos < rows[j].pos })
+	ingrs := make([]Ingredient, len(rows))
+	for i, r := range rows {
+		ingrs[i] = r.ingr
+	}
+	return ingrs
+}
+
+// ParseIngredient takes one line of text, e.g. "2x 400 g tomaten (in blik), uitgelekt", and returns the Ingredient
+// it describes, together with the confidence of the result and any part of the line that could not be interpreted.
+func ParseIngredient(line string) ParseResult {
+	r := ParseResult{Line: line, Confidence: 1}
+	xs, notes := tokenize(line)
+
+	// Find the first amount in the line
+	var a amountText
+	found := false
+	for j := range xs {
+		if a, found = parseAmount(xs, j); found {
+			break
+		}
+	}
+	if !found {
+		r.Ingredient = Ingredient{Item: strings.Join(xs, " "), Notes: strings.Join(notes, ", ")}
+		r.Confidence -= noAmount
+		if r.Ingredient.Item == "" {
+			r.Ingredient.Item = strings.TrimSpace(line)
+		}
+		r.Remainder = strings.TrimSpace(line)
+		return r
+	}
+	start, end := a.start, a.end
+
+	// Number of packages, e.g. "2x 400 g" or "2 x 400 g"
+	multiplied := false
+	if end+1 < len(xs) && multiplyWords[xs[end]] {
+		if b, ok := parseAmount(xs, end+1); ok {
+			b.amount *= a.amount
+			b.amountMax *= a.amount
+			b.start, b.approx = start, a.approx || b.approx
+			a, end, multiplied = b, b.end, true
+		}
+	}
+
+	// Unit behind the amount, or unit before the amount (e.g. "el 2 olie")
+	unit, n := lookupUnit(xs, end)
+	end += n
+	if n == 0 && start > 0 {
+		if u, m := lookupUnit(xs[start-1:start], 0); m == 1 {
+			unit, start = u, start-1
+			r.Confidence -= otherOrder
+		}
+	}
+	if unit == "" {
+		unit = pcs // default unit if not identified
+		r.Confidence -= noUnit
+	}
+	// Packages of a multiplied amount are kept in the notes, including the word for the package if any, e.g.
+	// "2x 400 g blikken tomaten" results in 800 g tomaten with notes "2x 400 g blikken"
+	if multiplied {
+		if end < len(xs) {
+			if d, ok := Registry.Lookup(xs[end]); ok && d.Dim == Custom {
+				end++
+			}
+		}
+		notes = append(notes, strings.Join(xs[a.start:end], " "))
+	}
+
+	// Item directly after the amount, with anything behind a comma as notes
+	item := strings.Join(xs[end:], " ")
+	if x := strings.Index(item, ","); x != -1 {
+		notes = append(notes, strings.Trim(item[x+1:], " ,"))
+		item = strings.Trim(item[:x], " ")
+	}
+	// Text before the amount is the item if there is no item after the amount (e.g. "bloem: 200 g"), otherwise notes
+	if prefix := strings.Trim(strings.Join(xs[:start], " "), " :"); prefix != "" {
+		if item == "" {
+			item = prefix
+			r.Confidence -= otherOrder
+		} else {
+			notes = append([]string{prefix}, notes...)
+		}
+	}
+	// Another number in the item cannot be interpreted, e.g. "1 ui of 2 sjalotten"
+	words := strings.Fields(item)
+	for k, w := range words {
+		if _, _, ok := parseRange(strings.Trim(w, ",.")); ok && k > 0 {
+			r.Remainder = strings.Join(words[k-1:], " ")
+			item = strings.Join(words[:k-1], " ")
+			r.Confidence -= hasRemainder
+			break
+		}
+	}
+	if strings.ContainsAny(item, "0123456789") {
+		r.Confidence -= numberInItem
+	}
+	if item == "" {
+		r.Confidence -= noItem
+	}
+
+	xn := []string{}
+	for _, v := range notes {
+		if v != "" {
+			xn = append(xn, v)
+		}
+	}
+	in := NewIngredient(a.amount, unit, item, strings.Join(xn, ", "))
+	in.AmountMax, in.Approx = a.amountMax, a.approx
+	r.Ingredient = in
+	if r.Confidence < 0 {
+		r.Confidence = 0
+	}
+	return r
+}
+
+// tokenize takes a line and returns the words in the line and the text between parentheses as notes. Decimal commas
+// are replaced by dots, thousands separators are removed and numbers directly followed by a unit (e.g. "200g") or
+// multiplier ("2x") are split.
+func tokenize(line string) ([]string, []string) {
+	var notes []string
+	for _, m := range reParentheses.FindAllStringSubmatch(line, -1) {
+		if s := strings.TrimSpace(m[1]); s != "" {
+			notes = append(notes, s)
+		}
+	}
+	line = reParentheses.ReplaceAllString(line, " ")
+	line = reDecimalComma.ReplaceAllString(line, "$1.$2")
+	line = removeThousands(line)
+	var xs []string
+	for _, w := range strings.Fields(line) {
+		if m := reNumberPrefix.FindStringSubmatch(w); m != nil {
+			if _, ok := Registry.Lookup(m[2]); ok || multiplyWords[m[2]] {
+				xs = append(xs, m[1], m[2])
+				continue
+			}
+		}
+		xs = append(xs, w)
+	}
+	return xs, notes
+}
+
+// removeThousands removes the thousands separators from the numbers in a line in which decimal commas are already
+// replaced by dots, e.g. "1.000 g" (or "1,000 g") becomes "1000 g". A dot followed by exactly three digits is taken as
+// thousands separator, unless the number starts with 0, e.g. "0.125 l".
+func removeThousands(line string) string {
+	for {
+		// each match removes at least one dot, matches next to each other are found in the next loop
+		m := reThousands.FindStringSubmatchIndex(line)
+		if m == nil {
+			return line
+		}
+		line = line[:m[2]] + strings.ReplaceAll(line[m[2]:m[3]], ".", "") + line[m[3]:]
+	}
+}
+
+// lookupUnit takes the elements of a line and an index j and checks if a unit of one or two words starts at
+// element j. It returns the Unit and the number of elements used, or 0 if no unit is found.
+func lookupUnit(xs []string, j int) (Unit, int) {
+	for n := 2; n > 0; n-- {
+		if j+n > len(xs) {
+			continue
+		}
+		if d, ok := Registry.Lookup(strings.Join(xs[j:j+n], " ")); ok {
+			return d.Unit, n
+		}
+	}
+	return "", 0
+}
+
+// parseAmount takes the elements of a line and an index j and checks if an amount starts at element j. An amount
+// can be preceded by an approximate word ("ca. 200"), can be a mixed number ("1 1/2") or a range ("2-3", "2 to 3").
+// It returns the amount and true if found, or false if no amount starts at element j.
+func parseAmount(xs []string, j int) (amountText, bool) {
+	a := amountText{start: j}
+	if approxWords[xs[j]] && j+1 < len(xs) {
+		a.approx = true
+		j++
+	}
+	s := xs[j]
+	if t := strings.TrimLeft(s, "±~"); t != s && t != "" {
+		a.approx = true
+		s = t
+	}
+	min, max, ok := parseRange(s)
+	if !ok {
+		return amountText{}, false
+	}
+	a.amount, a.amountMax, a.end = min, max, j+1
+	if max != 0 {
+		return a, true
+	}
+	// check if a fraction follows the number, e.g. "1 1/2"
+	if a.end < len(xs) && !isFraction(s) && isFraction(xs[a.end]) {
+		frac, _ := parseNumber(xs[a.end])
+		a.amount += frac
+		a.end++
+	}
+	// check if a range follows the number, e.g. "2 to 3"
+	if a.end+1 < len(xs) && rangeWords[xs[a.end]] {
+		if f, err := parseNumber(xs[a.end+1]); err == nil && f > a.amount {
+			a.amountMax = f
+			a.end += 2
+		}
+	}
+	return a, true
+}
+
+// parseRange takes a string and returns the amount, or the minimum and maximum amount if the string contains a range
+// like "2-3". If it is not a range, the maximum is zero. It returns false if the string does not contain an amount.
+func parseRange(s string) (float64, float64, bool) {
+	if f, err := parseNumber(s); err == nil {
+		return f, 0, true
+	}
+	for _, sep := range []string{"-", "–"} {
+		if x := strings.Index(s, sep); x > 0 {
+			min, err1 := parseNumber(s[:x])
+			max, err2 := parseNumber(s[x+len(sep):])
+			if err1 == nil && err2 == nil && max > min {
+				return min, max, true
+			}
+		}
+	}
+	return 0, 0, false
+}
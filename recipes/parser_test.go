@@ -0,0 +1,153 @@
+package gocookbook
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestParseIngredient(t *testing.T) {
+	cases := []struct {
+		line      string
+		amount    float64
+		amountMax float64
+		approx    bool
+		unit      Unit
+		item      string
+		notes     string
+		remainder string
+	}{
+		// Amount, unit and item
+		{"200 g bloem", 200, 0, false, gram, "bloem", "", ""},
+		{"200g bloem", 200, 0, false, gram, "bloem", "", ""},
+		{"200 gram bloem", 200, 0, false, gram, "bloem", "", ""},
+		{"1 kg aardappels", 1, 0, false, kilo, "aardappels", "", ""},
+		{"1 tablespoon extra-virgin olive oil", 1, 0, false, tbsp, "extra-virgin olive oil", "", ""},
+		{"2 tablespoons chopped fresh parsley", 2, 0, false, tbsp, "chopped fresh parsley", "", ""},
+		{"8 ounces button mushrooms, sliced", 8, 0, false, oz, "button mushrooms", "sliced", ""},
+		{"3 fl oz cream", 3, 0, false, flOz, "cream", "", ""},
+		{"1 EL olie", 1, 0, false, tbsp, "olie", "", ""},
+		{"1 el. olie", 1, 0, false, tbsp, "olie", "", ""},
+		{"2 eieren", 2, 0, false, pcs, "eieren", "", ""},
+		{"2 teentjes knoflook, geperst", 2, 0, false, clove, "knoflook", "geperst", ""},
+		{"  3   uien  ", 3, 0, false, pcs, "uien", "", ""},
+		// Fractions and decimals
+		{"½ cup heavy cream", 0.5, 0, false, cup, "heavy cream", "", ""},
+		{"¼ cup all-purpose flour", 0.25, 0, false, cup, "all-purpose flour", "", ""},
+		{"⅓ cup suiker", 1.0 / 3, 0, false, cup, "suiker", "", ""},
+		{"1½ el suiker", 1.5, 0, false, tbsp, "suiker", "", ""},
+		{"1½el suiker", 1.5, 0, false, tbsp, "suiker", "", ""},
+		{"1 1/2 cups milk", 1.5, 0, false, cup, "milk", "", ""},
+		{"1,5 kg kip", 1.5, 0, false, kilo, "kip", "", ""},
+		{"0.5 l melk", 0.5, 0, false, liter, "melk", "", ""},
+		// Thousands separators
+		{"1,000 g bloem", 1000, 0, false, gram, "bloem", "", ""},
+		{"1.000 g bloem", 1000, 0, false, gram, "bloem", "", ""},
+		{"1.000g bloem", 1000, 0, false, gram, "bloem", "", ""},
+		{"1.000.000 g zand", 1000000, 0, false, gram, "zand", "", ""},
+		{"1.000-1.500 g aardappels", 1000, 1500, false, gram, "aardappels", "", ""},
+		{"2,500 ml water", 2500, 0, false, ml, "water", "", ""},
+		{"0,125 l room", 0.125, 0, false, liter, "room", "", ""},
+		{"1,25 l bouillon", 1.25, 0, false, liter, "bouillon", "", ""},
+		{"12.5 g gist", 12.5, 0, false, gram, "gist", "", ""},
+		// Ranges and approximate amounts
+		{"2-3 el water", 2, 3, false, tbsp, "water", "", ""},
+		{"2 to 3 cups stock", 2, 3, false, cup, "stock", "", ""},
+		{"ca. 200 g kaas", 200, 0, true, gram, "kaas", "", ""},
+		{"±200 g kaas", 200, 0, true, gram, "kaas", "", ""},
+		// Packages
+		{"2x 400 g blikken tomaten", 800, 0, false, gram, "tomaten", "2 x 400 g blikken", ""},
+		{"2 x 400 g cans chopped tomatoes", 800, 0, false, gram, "chopped tomatoes", "2 x 400 g cans", ""},
+		{"1 blik tomaten (400 g)", 1, 0, false, can, "tomaten", "400 g", ""},
+		// Notes in parentheses and before the amount
+		{"1 ui (groot), gesnipperd", 1, 0, false, pcs, "ui", "groot, gesnipperd", ""},
+		{"gesnipperde 1 ui", 1, 0, false, pcs, "ui", "gesnipperde", ""},
+		{"100 g boter (op kamertemperatuur)", 100, 0, false, gram, "boter", "op kamertemperatuur", ""},
+		// Other orders
+		{"el 2 olijfolie", 2, 0, false, tbsp, "olijfolie", "", ""},
+		{"bloem: 200 g", 200, 0, false, gram, "bloem", "", ""},
+		{"Zout 1 tl", 1, 0, false, tsp, "Zout", "", ""},
+		// Remainders and lines without amount
+		{"1 ui of 2 sjalotten", 1, 0, false, pcs, "ui", "", "of 2 sjalotten"},
+		{"zout en peper", 0, 0, false, "", "zout en peper", "", "zout en peper"},
+		{"3", 3, 0, false, pcs, "", "", ""},
+		{"Inf g bloem", 0, 0, false, "", "Inf g bloem", "", "Inf g bloem"},
+	}
+	for i, c := range cases {
+		r := ParseIngredient(c.line)
+		in := r.Ingredient
+		if math.Abs(in.Amount-c.amount) > 1e-9 || in.AmountMax != c.amountMax || in.Approx != c.approx || in.Unit != c.unit || in.Item != c.item || in.Notes != c.notes || r.Remainder != c.remainder {
+			t.Errorf("Case %v failed for '%v'.\nGot:\t%v %v %v %v '%v' '%v' '%v'\nWant:\t%v %v %v %v '%v' '%v' '%v'", i, c.line,
+				in.Amount, in.AmountMax, in.Approx, in.Unit, in.Item, in.Notes, r.Remainder,
+				c.amount, c.amountMax, c.approx, c.unit, c.item, c.notes, c.remainder)
+		}
+		if r.Confidence < 0 || r.Confidence > 1 {
+			t.Errorf("Case %v failed for '%v'. Confidence out of range: %v", i, c.line, r.Confidence)
+		}
+	}
+}
+
+func TestParseConfidence(t *testing.T) {
+	cases := []struct {
+		better, worse string
+	}{
+		{"200 g bloem", "200 bloem"},
+		{"200 g bloem", "bloem: 200 g"},
+		{"2 uien", "2"},
+		{"1 ui", "1 ui of 2 sjalotten"},
+		{"2 uien", "zout en peper"},
+	}
+	for i, c := range cases {
+		b, w := ParseIngredient(c.better), ParseIngredient(c.worse)
+		if b.Confidence <= w.Confidence {
+			t.Errorf("Case %v failed. Want confidence of '%v' (%v) above '%v' (%v)", i, c.better, b.Confidence, c.worse, w.Confidence)
+		}
+	}
+	if r := ParseIngredient("200 g bloem"); r.Confidence != 1 {
+		t.Errorf("Want: %v, Got: %v", 1, r.Confidence)
+	}
+}
+
+func TestParseIngrds(t *testing.T) {
+	got := ParseIngrds("200 g bloem\r\n\r\n\t2 eieren\n")
+	if len(got) != 2 || got[1].Ingredient.Item != "eieren" {
+		t.Errorf("Want 2 results, Got: %+v", got)
+	}
+}
+
+func TestPreviewIngrds(t *testing.T) {
+	// Fill the form like the preview does, with an empty amount for ingredients without amount
+	form := map[string]string{}
+	for i, r := range ParseIngrds("200 g bloem\nzout en peper\n2-3 eieren") {
+		in := r.Ingredient
+		if in.Amount != 0 {
+			form[fmt.Sprintf("Amount%v", i)] = fmt.Sprint(in.Amount)
+		}
+		if in.AmountMax != 0 {
+			form[fmt.Sprintf("AmountMax%v", i)] = fmt.Sprint(in.AmountMax)
+		}
+		form[fmt.Sprintf("Unit%v", i)] = string(in.Unit)
+		form[fmt.Sprintf("Item%v", i)] = in.Item
+		form[fmt.Sprintf("Id%v", i)] = fmt.Sprint(i + 1)
+	}
+	form["Id0"] = "3.5"    // move bloem to the end
+	form["Item5"] = " "    // empty row
+	form["Unit1"] = "blik" // unit posted for zout en peper, which has no amount
+	form["Amount6"], form["Item6"], form["Id6"] = "NaN", "suiker", "3.2"
+	got := PreviewIngrds(func(key string) string { return form[key] }, 10)
+	if len(got) != 4 {
+		t.Fatalf("Want 4 ingredients, Got: %+v", got)
+	}
+	if got[0].Item != "zout en peper" || got[0].Amount != 0 || got[0].Unit != "" {
+		t.Errorf("Want zout en peper without amount and unit, Got: %+v", got[0])
+	}
+	if got[1].Item != "eieren" || got[1].Amount != 2 || got[1].AmountMax != 3 {
+		t.Errorf("Want 2-3 eieren, Got: %+v", got[1])
+	}
+	if got[2].Item != "suiker" || got[2].Amount != 0 {
+		t.Errorf("Want suiker without amount, Got: %+v", got[2])
+	}
+	if got[3].Item != "bloem" || got[3].Amount != 200 || got[3].Unit != gram {
+		t.Errorf("Want 200 g bloem last, Got: %+v", got[3])
+	}
+}
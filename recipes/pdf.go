@@ -0,0 +1,417 @@
+package gocookbook
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Size of an A4 page and its margins, in points (1/72 inch).
+const (
+	pdfWidth  = 595.28
+	pdfHeight = 841.89
+	pdfMargin = 56.69 // 2 cm.
+)
+
+type pdfFont int // pdfFont represents one of the standard PDF fonts used in a document.
+
+// Fonts used in a document, in the order they are added to the document.
+const (
+	fontRegular = pdfFont(iota) // Helvetica.
+	fontBold                    // Helvetica-Bold.
+	fontItalic                  // Helvetica-Oblique, which has the same widths as Helvetica.
+)
+
+// pdfFontNames contains the name of each pdfFont.
+var pdfFontNames = []string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique"}
+
+// Widths of the printable ASCII characters (from space to tilde) in 1/1000 of the font size.
+var (
+	helveticaWidths = []int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, 556, 556, 556, 556, 556, 556,
+		556, 556, 556, 556, 278, 278, 584, 584, 584, 556, 1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667,
+		556, 833, 722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, 333, 556,
+		556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, 556, 556, 333, 500, 278, 556, 500, 722,
+		500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = []int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278, 556, 556, 556, 556, 556, 556,
+		556, 556, 556, 556, 333, 333, 584, 584, 584, 611, 975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722,
+		611, 833, 722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556, 333, 556,
+		611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611, 611, 611, 389, 556, 333, 611, 556, 778,
+		556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// pdfSymbolWidths contains the width of characters outside ASCII that are not a letter with a diacritic.
+var pdfSymbolWidths = map[rune]int{
+	'–': 556, '—': 1000, '•': 350, '…': 1000, '‘': 222, '’': 222, '“': 333, '”': 333, '€': 556, '°': 400, '×': 584,
+	'½': 834, '¼': 834, '¾': 834, '·': 278, '«': 556, '»': 556,
+}
+
+// pdfDoc represents a PDF document with text in the standard fonts, on A4 pages.
+type pdfDoc struct {
+	pages  []*bytes.Buffer // Content stream of each page.
+	y      float64         // Position of the next line on the last page, from the top.
+	footer map[int]bool    // Pages that get a page number at the bottom.
+}
+
+// newPDF creates an empty pdfDoc and returns it.
+func newPDF() *pdfDoc {
+	return &pdfDoc{footer: map[int]bool{}}
+}
+
+// newPage adds a page to the document, with a page number at the bottom if numbered is true.
+func (d *pdfDoc) newPage(numbered bool) {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfMargin
+	d.footer[len(d.pages)] = numbered
+}
+
+// page returns the number of the last page, starting at 1.
+func (d *pdfDoc) page() int {
+	return len(d.pages)
+}
+
+// space adds h points of vertical space, or starts a new page if the space does not fit.
+func (d *pdfDoc) space(h float64) {
+	d.y += h
+	if d.y > pdfHeight-pdfMargin {
+		d.newPage(true)
+	}
+}
+
+// ensure starts a new page if less than h points are left on the current page.
+func (d *pdfDoc) ensure(h float64) {
+	if len(d.pages) == 0 || d.y+h > pdfHeight-pdfMargin {
+		d.newPage(true)
+	}
+}
+
+// textAt writes s in font f with the given size at x (from the left) and y (the baseline, from the top) on the last
+// page.
+func (d *pdfDoc) textAt(f pdfFont, size, x, y float64, s string) {
+	fmt.Fprintf(d.pages[len(d.pages)-1], "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", f+1, pdfNumber(size), pdfNumber(x),
+		pdfNumber(pdfHeight-y), pdfEscape(s))
+}
+
+// paragraph writes s in font f with the given size, wrapped within the margins and starting at indent points from the
+// left margin. The first line starts with prefix, e.g. "1.", which is placed left of the indent. A line break in s
+// starts a new line.
+func (d *pdfDoc) paragraph(f pdfFont, size, indent float64, prefix, s string) {
+	leading := size * 1.35
+	lines := pdfWrap(f, size, pdfWidth-2*pdfMargin-indent, s)
+	for i, line := range lines {
+		d.ensure(leading)
+		d.y += leading
+		if i == 0 && prefix != "" {
+			d.textAt(f, size, pdfMargin+indent-pdfTextWidth(f, size, prefix+" "), d.y, prefix)
+		}
+		d.textAt(f, size, pdfMargin+indent, d.y, line)
+	}
+}
+
+// heading writes s as heading in bold with the given size, on a new page if there is no room for the heading and
+// at least two lines of text below it.
+func (d *pdfDoc) heading(size float64, s string) {
+	d.ensure(size*1.35 + 40)
+	d.paragraph(fontBold, size, 0, "", s)
+	d.y += size * 0.4
+}
+
+// write writes the document as PDF to w, with the title in the document information.
+func (d *pdfDoc) write(w io.Writer, title string) error {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(format string, a ...interface{}) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&buf, format, a...)
+		buf.WriteString("\nendobj\n")
+	}
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1 to 3 are the catalog, pages and document information, followed by the fonts, then a page and its
+	// contents for each page
+	fonts := 4
+	first := fonts + len(pdfFontNames)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", first+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj("<< /Type /Pages /Kids [%v] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+	obj("<< /Title (%s) /Producer (gocookbook) >>", pdfEscape(title))
+	var res []string
+	for i, name := range pdfFontNames {
+		obj("<< /Type /Font /Subtype /Type1 /BaseFont /%v /Encoding /WinAnsiEncoding >>", name)
+		res = append(res, fmt.Sprintf("/F%d %d 0 R", i+1, fonts+i))
+	}
+	for i, p := range d.pages {
+		if d.footer[i+1] {
+			n := strconv.Itoa(i + 1)
+			fmt.Fprintf(p, "BT /F1 9 Tf %s %s Td (%s) Tj ET\n", pdfNumber((pdfWidth-pdfTextWidth(fontRegular, 9, n))/2),
+				pdfNumber(pdfMargin/2), n)
+		}
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(p.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		obj("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %v >> >> /Contents %d 0 R >>",
+			pdfNumber(pdfWidth), pdfNumber(pdfHeight), strings.Join(res, " "), first+2*i+1)
+		obj("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.Bytes())
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfNumber returns f as number in a PDF, with at most two decimals.
+func pdfNumber(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// pdfEscape returns s as content of a PDF string in WinAnsiEncoding. Fractions that are not in the encoding are
+// written as plain fractions, e.g. "1/3", and other characters that are not in the encoding as "?".
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFC.String(s) {
+		if f, ok := pdfFraction(r); ok {
+			b.WriteString(f)
+			continue
+		}
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		switch {
+		case !ok:
+			b.WriteByte('?')
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// pdfFraction returns the vulgar fraction r as plain fraction, e.g. "1/3", and true if it is not in WinAnsiEncoding.
+func pdfFraction(r rune) (string, bool) {
+	if _, ok := vulgarFractions[r]; !ok || strings.ContainsRune("½¼¾", r) {
+		return "", false
+	}
+	return strings.ReplaceAll(norm.NFKD.String(string(r)), "⁄", "/"), true
+}
+
+// pdfTextWidth returns the width of s in font f with the given size, in points.
+func pdfTextWidth(f pdfFont, size float64, s string) float64 {
+	widths := helveticaWidths
+	if f == fontBold {
+		widths = helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range s {
+		if x, ok := pdfFraction(r); ok {
+			total += int(pdfTextWidth(f, 1000, x))
+			continue
+		}
+		if w, ok := pdfSymbolWidths[r]; ok {
+			total += w
+			continue
+		}
+		// letters with a diacritic have the width of the letter itself, e.g. "é"
+		if base := []rune(norm.NFD.String(string(r)))[0]; base >= ' ' && base <= '~' {
+			total += widths[base-' ']
+			continue
+		}
+		total += 556
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfWrap takes a text and returns it as lines that fit within width points in font f with the given size. Words that
+// do not fit on a line are placed on a line of their own.
+func pdfWrap(f pdfFont, size, width float64, s string) []string {
+	var lines []string
+	for _, p := range strings.Split(normalizeText(s), "\n") {
+		line := ""
+		for _, w := range strings.FieldsFunc(p, unicode.IsSpace) {
+			if line != "" && pdfTextWidth(f, size, line+" "+w) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += w
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfRecipe writes the Recipe on a new page of the document, with amounts in AmountFormat af.
+func pdfRecipe(d *pdfDoc, r Recipe, af AmountFormat) {
+	d.newPage(true)
+	d.paragraph(fontBold, 20, 0, "", r.Name)
+	if len(r.Tags) > 0 {
+		d.paragraph(fontItalic, 10, 0, "", strings.Join(r.Tags, ", "))
+	}
+	var info []string
+	if r.Portions > 0 {
+		info = append(info, fmt.Sprintf("%v porties", formatAmount(r.Portions, pcs, af)))
+	}
+	if r.Dur > 0 {
+		info = append(info, fmt.Sprintf("Kooktijd: %v", formatTextDuration(r.Dur)))
+	}
+	if p := r.Pan.String(); p != "" {
+		info = append(info, fmt.Sprintf("Bakvorm: %v", p))
+	}
+	if len(info) > 0 {
+		d.paragraph(fontRegular, 10, 0, "", strings.Join(info, " · "))
+	}
+	if r.Notes != "" {
+		d.space(6)
+		d.paragraph(fontItalic, 10, 0, "", r.Notes)
+	}
+	if len(r.Ingrs) > 0 {
+		d.space(12)
+		d.heading(14, "Ingrediënten")
+		for _, v := range r.Ingrs {
+			d.paragraph(fontRegular, 11, 14, "•", v.PrintAs(af))
+		}
+	}
+	if len(r.Steps) > 0 {
+		d.space(12)
+		d.heading(14, "Bereiding")
+		for i, s := range r.Steps {
+			d.paragraph(fontRegular, 11, 20, fmt.Sprintf("%d.", i+1), s)
+			d.space(4)
+		}
+	}
+	if source := joinNotes(r.Source, r.SourceLink); source != "" {
+		d.space(12)
+		d.paragraph(fontItalic, 9, 0, "", "Bron: "+strings.ReplaceAll(source, "\n\n", " - "))
+	}
+}
+
+// RecipePDF writes the Recipe as PDF to w, with amounts in AmountFormat af. The Recipe is printed as it is, so it
+// should be scaled and converted beforehand.
+func RecipePDF(w io.Writer, r Recipe, af AmountFormat) error {
+	d := newPDF()
+	pdfRecipe(d, r, af)
+	return d.write(w, r.Name)
+}
+
+// CookbookPDF writes the recipes as a cookbook in PDF to w, with amounts in AmountFormat af. The cookbook has a title
+// page, a table of contents, a page per recipe and an index of the ingredients and tags.
+func CookbookPDF(w io.Writer, title string, rcps []Recipe, af AmountFormat) error {
+	// The table of contents is written with the page numbers of the recipes, so the number of pages it takes is
+	// determined first. Each recipe in the table of contents takes one line, whatever its page number.
+	toc := newPDF()
+	pdfContents(toc, rcps, make([]int, len(rcps)))
+	offset := 1 + toc.page()
+	body := newPDF()
+	start := make([]int, len(rcps))
+	index := map[string]map[int]bool{}
+	for i, r := range rcps {
+		// each recipe starts on a new page
+		start[i] = offset + body.page() + 1
+		pdfRecipe(body, r, af)
+		for _, v := range r.Ingrs {
+			pdfIndex(index, v.Item, start[i])
+		}
+		for _, t := range r.Tags {
+			pdfIndex(index, t, start[i])
+		}
+	}
+	d := newPDF()
+	d.newPage(false)
+	d.y = pdfHeight / 3
+	for _, line := range pdfWrap(fontBold, 32, pdfWidth-2*pdfMargin, title) {
+		d.y += 32 * 1.35
+		d.textAt(fontBold, 32, (pdfWidth-pdfTextWidth(fontBold, 32, line))/2, d.y, line)
+	}
+	sub := fmt.Sprintf("%d recepten", len(rcps))
+	d.y += 30
+	d.textAt(fontItalic, 14, (pdfWidth-pdfTextWidth(fontItalic, 14, sub))/2, d.y, sub)
+	pdfContents(d, rcps, start)
+	for _, p := range body.pages {
+		d.pages = append(d.pages, p)
+		d.footer[d.page()] = true
+	}
+	pdfIndexPages(d, index)
+	return d.write(w, title)
+}
+
+// pdfContents writes the table of contents, with the name and first page of each recipe, on new pages.
+func pdfContents(d *pdfDoc, rcps []Recipe, start []int) {
+	d.newPage(true)
+	d.heading(20, "Inhoud")
+	width := pdfWidth - 2*pdfMargin
+	for i, r := range rcps {
+		n := strconv.Itoa(start[i])
+		name := r.Name
+		max := width - pdfTextWidth(fontRegular, 11, "  "+n)
+		for name != "" && pdfTextWidth(fontRegular, 11, name) > max {
+			name = string([]rune(name)[:len([]rune(name))-1])
+		}
+		d.ensure(11 * 1.35)
+		d.y += 11 * 1.35
+		d.textAt(fontRegular, 11, pdfMargin, d.y, name)
+		d.textAt(fontRegular, 11, pdfWidth-pdfMargin-pdfTextWidth(fontRegular, 11, n), d.y, n)
+	}
+}
+
+// pdfIndex adds the page to the entry s of the index.
+func pdfIndex(index map[string]map[int]bool, s string, page int) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return
+	}
+	if index[s] == nil {
+		index[s] = map[int]bool{}
+	}
+	index[s][page] = true
+}
+
+// pdfIndexPages writes the index, with the pages of each entry, on new pages.
+func pdfIndexPages(d *pdfDoc, index map[string]map[int]bool) {
+	d.newPage(true)
+	d.heading(20, "Index")
+	entries := make([]string, 0, len(index))
+	for s := range index {
+		entries = append(entries, s)
+	}
+	sort.Slice(entries, func(i, j int) bool { return fold(entries[i]) < fold(entries[j]) })
+	for _, s := range entries {
+		var pages []int
+		for p := range index[s] {
+			pages = append(pages, p)
+		}
+		sort.Ints(pages)
+		xs := make([]string, len(pages))
+		for i, p := range pages {
+			xs[i] = strconv.Itoa(p)
+		}
+		d.paragraph(fontRegular, 10, 0, "", fmt.Sprintf("%v, %v", s, strings.Join(xs, ", ")))
+	}
+}
@@ -0,0 +1,147 @@
+package gocookbook
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pdfPages takes a PDF written by pdfDoc and returns the decompressed content of each page.
+func pdfPages(t *testing.T, data []byte) []string {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("Not a PDF: %q", data[:20])
+	}
+	// All offsets in the cross-reference table point to an object
+	xref := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(data, -1)
+	for i, m := range xref {
+		o, _ := strconv.Atoi(string(m[1]))
+		if !bytes.HasPrefix(data[o:], []byte(fmt.Sprintf("%d 0 obj", i+1))) {
+			t.Errorf("Offset of object %v is wrong", i+1)
+		}
+	}
+	var pages []string
+	for _, part := range bytes.Split(data, []byte(">>\nstream\n"))[1:] {
+		i := bytes.Index(part, []byte("\nendstream"))
+		if i < 0 {
+			continue
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(part[:i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, string(b))
+	}
+	return pages
+}
+
+func TestPDFWidths(t *testing.T) {
+	if len(helveticaWidths) != 95 || len(helveticaBoldWidths) != 95 {
+		t.Fatalf("Want 95 widths, Got: %v and %v", len(helveticaWidths), len(helveticaBoldWidths))
+	}
+	cases := []struct {
+		f    pdfFont
+		s    string
+		want float64
+	}{
+		{fontRegular, "Hallo", 10 * (722 + 556 + 222 + 222 + 556) / 1000.0},
+		{fontBold, "Hallo", 10 * (722 + 556 + 278 + 278 + 611) / 1000.0},
+		{fontRegular, "crème", 10 * (500 + 333 + 556 + 833 + 556) / 1000.0},
+		{fontRegular, "⅓", 10 * (556 + 278 + 556) / 1000.0},
+	}
+	for i, c := range cases {
+		if got := pdfTextWidth(c.f, 10, c.s); got != c.want {
+			t.Errorf("Case %v failed for '%v'. Want: %v, Got: %v", i, c.s, c.want, got)
+		}
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	cases := []struct {
+		s    string
+		want string
+	}{
+		{"Appeltaart (oma)", `Appeltaart \(oma\)`},
+		{`a\b`, `a\\b`},
+		{"crème ½", `cr\350me \275`},
+		{"⅓ kopje", "1/3 kopje"},
+		{"日本", "??"},
+	}
+	for i, c := range cases {
+		if got := pdfEscape(c.s); got != c.want {
+			t.Errorf("Case %v failed. Want: %v, Got: %v", i, c.want, got)
+		}
+	}
+}
+
+func TestPDFWrap(t *testing.T) {
+	s := strings.Repeat("woord ", 40) + "\nnieuwe regel"
+	lines := pdfWrap(fontRegular, 10, 200, s)
+	if len(lines) < 3 || lines[len(lines)-1] != "nieuwe regel" {
+		t.Fatalf("Want wrapped lines ending with the new line, Got: %q", lines)
+	}
+	for _, line := range lines {
+		if w := pdfTextWidth(fontRegular, 10, line); w > 200 {
+			t.Errorf("Line '%v' is too wide: %v", line, w)
+		}
+	}
+}
+
+func TestRecipePDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RecipePDF(&buf, sampleRcp, FormatKitchen); err != nil {
+		t.Fatal(err)
+	}
+	pages := pdfPages(t, buf.Bytes())
+	if len(pages) != 1 {
+		t.Fatalf("Want 1 page, Got: %v", len(pages))
+	}
+	for _, want := range []string{"(Pannenkoeken)", "(250 g bloem", "(1.)", "(Bak dunne pannenkoeken.)", "(1)"} {
+		if !strings.Contains(pages[0], want) {
+			t.Errorf("Want '%v' in page:\n%v", want, pages[0])
+		}
+	}
+}
+
+func TestCookbookPDF(t *testing.T) {
+	long := sampleRcp
+	long.Name = "Lange soep"
+	long.Tags = []string{"Soep"}
+	for i := 0; i < 60; i++ {
+		long.Steps = append(long.Steps, fmt.Sprintf("Stap %v met een beschrijving.", i))
+	}
+	var buf bytes.Buffer
+	if err := CookbookPDF(&buf, "Familiekookboek", []Recipe{long, sampleRcp}, FormatKitchen); err != nil {
+		t.Fatal(err)
+	}
+	pages := pdfPages(t, buf.Bytes())
+	// title page, contents, 2 pages for the long recipe, 1 page for the other recipe and the index
+	if len(pages) != 6 {
+		t.Fatalf("Want 6 pages, Got: %v", len(pages))
+	}
+	if !strings.Contains(pages[0], "(Familiekookboek)") || strings.Contains(pages[0], "(1)") {
+		t.Errorf("Wrong title page:\n%v", pages[0])
+	}
+	for _, want := range []string{"(Lange soep)", "(3)", "(Pannenkoeken)", "(5)"} {
+		if !strings.Contains(pages[1], want) {
+			t.Errorf("Want '%v' in contents:\n%v", want, pages[1])
+		}
+	}
+	if !strings.Contains(pages[4], "(Pannenkoeken)") {
+		t.Errorf("Want Pannenkoeken on page 5:\n%v", pages[4])
+	}
+	for _, want := range []string{"(bloem, 3, 5)", "(soep, 3)", "(ontbijt, 5)"} {
+		if !strings.Contains(pages[5], want) {
+			t.Errorf("Want '%v' in index:\n%v", want, pages[5])
+		}
+	}
+}
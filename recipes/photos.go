@@ -0,0 +1,324 @@
+package gocookbook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // GIF photos can be uploaded.
+	"image/jpeg"
+	_ "image/png" // PNG photos can be uploaded.
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Photo represents a photo of a recipe, or of one of its steps. The photo is stored by a PhotoStore in all PhotoSizes.
+type Photo struct {
+	Name   string // Name of the photo, without size and extension, e.g. "20-1a2b3c4d5e6f".
+	Step   int    // Number of the step the photo belongs to, starting at 1, or 0 for a photo of the recipe itself.
+	Width  int    // Width in pixels of the largest size.
+	Height int    // Height in pixels of the largest size.
+}
+
+// PhotoSize represents a size in which each photo is stored.
+type PhotoSize struct {
+	Name   string // Name of the size, used in the file name, e.g. "thumb".
+	Max    int    // Maximum width and height in pixels. Smaller photos are not enlarged.
+	Square bool   // Photo is cropped to a square around the center.
+}
+
+// PhotoSizes contains all sizes in which a photo is stored, from small to large.
+var PhotoSizes = []PhotoSize{
+	{"thumb", 240, true},
+	{"medium", 800, false},
+	{"large", 1600, false},
+}
+
+// PhotoStore stores photos of recipes on disk.
+type PhotoStore struct {
+	Dir       string // Folder where the photos are stored.
+	MaxBytes  int64  // Maximum size of an uploaded photo in bytes.
+	MaxPixels int    // Maximum number of pixels (width times height) of an uploaded photo.
+	Quality   int    // JPEG quality of the stored photos, from 1 to 100.
+}
+
+var (
+	errorPhotoType   = errors.New("photo must be a JPEG, PNG or GIF image")   // Upload is not a supported image.
+	errorPhotoBytes  = errors.New("photo file is too large")                  // Upload exceeds MaxBytes.
+	errorPhotoPixels = errors.New("photo has too many pixels")                // Image exceeds MaxPixels.
+	errorPhotoName   = errors.New("photo does not exist or has invalid name") // File name is not a stored photo.
+)
+
+var rePhotoFile = regexp.MustCompile(`^\d+-[0-9a-f]{12}-([a-z]+)\.jpg$`) // File name of a stored photo, e.g. "20-1a2b3c4d5e6f-thumb.jpg".
+
+// NewPhotoStore takes a folder and returns a PhotoStore for that folder that accepts photos up to 10 MB and 40
+// megapixels.
+func NewPhotoStore(dir string) *PhotoStore {
+	return &PhotoStore{Dir: dir, MaxBytes: 10 << 20, MaxPixels: 40e6, Quality: 85}
+}
+
+// Add takes the Id of a recipe, the number of the step (0 for the recipe itself) and an uploaded photo, and stores the
+// photo in all PhotoSizes as JPEG. The photo is rotated according to its EXIF orientation, after which all metadata
+// (including the location and camera) is removed. It returns an error if the photo is too large or not a JPEG, PNG or
+// GIF image.
+func (ps *PhotoStore) Add(id, step int, r io.Reader) (Photo, error) {
+	data, err := io.ReadAll(io.LimitReader(r, ps.MaxBytes+1))
+	switch {
+	case err != nil:
+		return Photo{}, err
+	case int64(len(data)) > ps.MaxBytes:
+		return Photo{}, fmt.Errorf("%w (maximum %v MB)", errorPhotoBytes, ps.MaxBytes>>20)
+	}
+	img, o, err := decodePhoto(data, ps.MaxPixels)
+	if err != nil {
+		return Photo{}, err
+	}
+	// scaled down to the largest size first, so only that smaller copy is rotated and the other sizes are made from it
+	img = orientPhoto(resizePhoto(img, PhotoSize{Max: PhotoSizes[len(PhotoSizes)-1].Max}), o)
+	sum := sha256.Sum256(data)
+	p := Photo{Name: fmt.Sprintf("%d-%s", id, hex.EncodeToString(sum[:6])), Step: step}
+	if err := os.MkdirAll(ps.Dir, 0755); err != nil {
+		return Photo{}, err
+	}
+	for _, s := range PhotoSizes {
+		resized := resizePhoto(img, s)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: ps.Quality}); err != nil {
+			return Photo{}, err
+		}
+		if err := os.WriteFile(filepath.Join(ps.Dir, p.File(s.Name)), buf.Bytes(), 0644); err != nil {
+			return Photo{}, err
+		}
+		p.Width, p.Height = resized.Bounds().Dx(), resized.Bounds().Dy()
+	}
+	return p, nil
+}
+
+// Remove deletes all sizes of the Photo from disk. Sizes that do not exist are ignored.
+func (ps *PhotoStore) Remove(p Photo) error {
+	for _, s := range PhotoSizes {
+		if err := os.Remove(filepath.Join(ps.Dir, p.File(s.Name))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Path takes the file name of a stored photo, e.g. "20-1a2b3c4d5e6f-thumb.jpg", and returns the path of the file. It
+// returns an error if the name is not the name of a photo in one of the PhotoSizes.
+func (ps *PhotoStore) Path(file string) (string, error) {
+	m := rePhotoFile.FindStringSubmatch(file)
+	if m == nil {
+		return "", errorPhotoName
+	}
+	for _, s := range PhotoSizes {
+		if s.Name == m[1] {
+			return filepath.Join(ps.Dir, file), nil
+		}
+	}
+	return "", errorPhotoName
+}
+
+// File returns the file name of the Photo in the given size, e.g. "20-1a2b3c4d5e6f-thumb.jpg".
+func (p Photo) File(size string) string {
+	return fmt.Sprintf("%v-%v.jpg", p.Name, size)
+}
+
+// MainPhoto returns the first photo of the Recipe itself, or an empty Photo if it has none.
+func (r Recipe) MainPhoto() Photo {
+	for _, p := range r.Photos {
+		if p.Step == 0 {
+			return p
+		}
+	}
+	return Photo{}
+}
+
+// StepPhotos returns the photos of step n of the Recipe, starting at 1. Step 0 returns the photos of the recipe
+// itself.
+func (r Recipe) StepPhotos(n int) []Photo {
+	var xs []Photo
+	for _, p := range r.Photos {
+		if p.Step == n {
+			xs = append(xs, p)
+		}
+	}
+	return xs
+}
+
+// decodePhoto takes an uploaded photo and returns it as image, together with the EXIF orientation it should be shown
+// in (1 if the photo is not a JPEG image). It returns an error if the photo is not a supported image or has more than
+// maxPixels pixels.
+func decodePhoto(data []byte, maxPixels int) (image.Image, int, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	switch {
+	case err != nil:
+		return nil, 0, errorPhotoType
+	case cfg.Width*cfg.Height > maxPixels:
+		return nil, 0, fmt.Errorf("%w (%vx%v)", errorPhotoPixels, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", errorPhotoType, err)
+	}
+	if format != "jpeg" {
+		return img, 1, nil
+	}
+	return img, exifOrientation(data), nil
+}
+
+// exifOrientation returns the orientation in the EXIF metadata of a JPEG image, from 1 (normal) to 8. It returns 1
+// if the image has no (valid) orientation.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker, n := data[i+1], int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || i+2+n > len(data) {
+			// start of the image data, metadata comes before it
+			return 1
+		}
+		if seg := data[i+4 : i+2+n]; marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + n
+	}
+	return 1
+}
+
+// tiffOrientation returns the orientation in the first directory of TIFF data (the EXIF metadata), or 1 if it has no
+// valid orientation.
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	off := int(bo.Uint32(b[4:]))
+	if off+2 > len(b) {
+		return 1
+	}
+	count := int(bo.Uint16(b[off:]))
+	for j := 0; j < count; j++ {
+		e := off + 2 + 12*j
+		if e+12 > len(b) {
+			return 1
+		}
+		if bo.Uint16(b[e:]) == 0x0112 {
+			if o := int(bo.Uint16(b[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orientPhoto takes an image and its EXIF orientation and returns the image rotated and/or mirrored so it is shown
+// upright.
+func orientPhoto(img *image.RGBA, o int) *image.RGBA {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if o >= 5 {
+		// orientations 5 to 8 swap width and height
+		w, h = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sx, sy int
+			switch o {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, w-1-x
+			case 7:
+				sx, sy = h-1-y, w-1-x
+			case 8:
+				sx, sy = h-1-y, x
+			}
+			i, j := img.PixOffset(b.Min.X+sx, b.Min.Y+sy), dst.PixOffset(x, y)
+			copy(dst.Pix[j:j+4], img.Pix[i:i+4])
+		}
+	}
+	return dst
+}
+
+// resizePhoto returns the image in PhotoSize s: cropped to a square if s is square and scaled down to fit within the
+// maximum size, by averaging the pixels that make up each new pixel. Transparent parts are made white. The image is
+// read a strip of rows at a time, so a large photo is not copied as a whole.
+func resizePhoto(img image.Image, s PhotoSize) *image.RGBA {
+	b := img.Bounds()
+	if s.Square {
+		side := b.Dx()
+		if b.Dy() < side {
+			side = b.Dy()
+		}
+		x, y := b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2
+		b = image.Rect(x, y, x+side, y+side)
+	}
+	sw, sh := b.Dx(), b.Dy()
+	w, h := sw, sh
+	if w > s.Max || h > s.Max {
+		if w >= h {
+			w, h = s.Max, max(1, sh*s.Max/sw)
+		} else {
+			w, h = max(1, sw*s.Max/sh), s.Max
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if w == sw && h == sh {
+		draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+		return dst
+	}
+	src := image.NewRGBA(image.Rect(0, 0, sw, (sh+h-1)/h+1))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		strip := image.Rect(0, 0, sw, y1-y0)
+		draw.Draw(src, strip, &image.Uniform{color.White}, image.Point{}, draw.Src)
+		draw.Draw(src, strip, img, image.Pt(b.Min.X, b.Min.Y+y0), draw.Over)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy-y0)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					bl += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			j := dst.PixOffset(x, y)
+			dst.Pix[j], dst.Pix[j+1], dst.Pix[j+2], dst.Pix[j+3] = uint8(r/n), uint8(g/n), uint8(bl/n), uint8(a/n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,192 @@
+package gocookbook
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testImage returns an image of w by h pixels that is red on the left half and blue on the right half.
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= w/2 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// withExif takes a JPEG image and returns it with an EXIF segment containing the orientation o.
+func withExif(data []byte, o int) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = append(tiff, 0, 3, 0, 0, 0, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(o))
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xFF, 0xE1}, binary.BigEndian.AppendUint16(nil, uint16(len(seg)+2))...)
+	return append(append(append([]byte{}, data[:2]...), append(app1, seg...)...), data[2:]...)
+}
+
+func TestPhotoStoreAdd(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(2000, 1000), nil); err != nil {
+		t.Fatal(err)
+	}
+	rotated := withExif(buf.Bytes(), 6)
+	if o := exifOrientation(rotated); o != 6 {
+		t.Fatalf("Want orientation 6, Got: %v", o)
+	}
+	ps := NewPhotoStore(t.TempDir())
+	p, err := ps.Add(20, 2, bytes.NewReader(rotated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Largest size is limited to 1600 pixels and rotated a quarter turn
+	if p.Step != 2 || p.Width != 800 || p.Height != 1600 {
+		t.Errorf("Want step 2 with 800x1600, Got: %+v", p)
+	}
+	wants := map[string][2]int{"thumb": {240, 240}, "medium": {400, 800}, "large": {800, 1600}}
+	for size, want := range wants {
+		path, err := ps.Path(p.File(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("Exif")) {
+			t.Errorf("Size %v still contains EXIF metadata", size)
+		}
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil || cfg.Width != want[0] || cfg.Height != want[1] {
+			t.Errorf("Size %v failed. Want: %vx%v, Got: %vx%v (%v)", size, want[0], want[1], cfg.Width, cfg.Height, err)
+		}
+	}
+	if err := ps.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(ps.Dir, "*")); len(files) != 0 {
+		t.Errorf("Want all sizes removed, Got: %v", files)
+	}
+}
+
+func TestPhotoStoreAddInvalid(t *testing.T) {
+	var small, large bytes.Buffer
+	png.Encode(&small, testImage(10, 10))
+	png.Encode(&large, testImage(200, 100))
+	ps := NewPhotoStore(t.TempDir())
+	ps.MaxPixels = 10000
+	cases := []struct {
+		data []byte
+		err  error
+	}{
+		{[]byte("geen foto"), errorPhotoType},
+		{[]byte("<svg></svg>"), errorPhotoType},
+		{large.Bytes(), errorPhotoPixels},
+		{small.Bytes(), nil},
+	}
+	for i, c := range cases {
+		if _, err := ps.Add(10, 0, bytes.NewReader(c.data)); !errors.Is(err, c.err) {
+			t.Errorf("Case %v failed. Want: %v, Got: %v", i, c.err, err)
+		}
+	}
+	ps.MaxBytes = 10
+	if _, err := ps.Add(10, 0, bytes.NewReader(small.Bytes())); !errors.Is(err, errorPhotoBytes) {
+		t.Errorf("Want: %v, Got: %v", errorPhotoBytes, err)
+	}
+}
+
+func TestOrientPhoto(t *testing.T) {
+	img := testImage(4, 2) // red left, blue right
+	cases := []struct {
+		o      int
+		w, h   int
+		redAt  image.Point
+		blueAt image.Point
+	}{
+		{1, 4, 2, image.Pt(0, 0), image.Pt(3, 0)},
+		{2, 4, 2, image.Pt(3, 0), image.Pt(0, 0)},
+		{3, 4, 2, image.Pt(3, 1), image.Pt(0, 1)},
+		{6, 2, 4, image.Pt(0, 0), image.Pt(0, 3)},
+		{8, 2, 4, image.Pt(0, 3), image.Pt(0, 0)},
+	}
+	for _, c := range cases {
+		got := orientPhoto(img, c.o)
+		r, _, _, _ := got.At(c.redAt.X, c.redAt.Y).RGBA()
+		_, _, b, _ := got.At(c.blueAt.X, c.blueAt.Y).RGBA()
+		if got.Bounds().Dx() != c.w || got.Bounds().Dy() != c.h || r == 0 || b == 0 {
+			t.Errorf("Orientation %v failed. Got: %v", c.o, got.Bounds())
+		}
+	}
+}
+
+func TestResizePhoto(t *testing.T) {
+	img := testImage(400, 200) // red left, blue right
+	img.Set(0, 199, color.RGBA{})
+	cases := []struct {
+		s    PhotoSize
+		w, h int
+	}{
+		{PhotoSize{Max: 100}, 100, 50},
+		{PhotoSize{Max: 100, Square: true}, 100, 100},
+		{PhotoSize{Max: 1000}, 400, 200},
+	}
+	for _, c := range cases {
+		got := resizePhoto(img, c.s)
+		w, h := got.Bounds().Dx(), got.Bounds().Dy()
+		left, right := got.RGBAAt(0, 0), got.RGBAAt(w-1, 0)
+		if w != c.w || h != c.h || left.R != 255 || left.B != 0 || right.B != 255 || right.R != 0 {
+			t.Errorf("Size %+v failed. Want: %vx%v, Got: %vx%v with %v and %v", c.s, c.w, c.h, w, h, left, right)
+		}
+	}
+	// Transparent parts are made white
+	if got := resizePhoto(img, PhotoSize{Max: 1000}).RGBAAt(0, 199); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("Want white, Got: %v", got)
+	}
+}
+
+func TestPhotoStorePath(t *testing.T) {
+	ps := NewPhotoStore("photos")
+	cases := []struct {
+		file string
+		ok   bool
+	}{
+		{"20-1a2b3c4d5e6f-thumb.jpg", true},
+		{"20-1a2b3c4d5e6f-large.jpg", true},
+		{"20-1a2b3c4d5e6f-huge.jpg", false},
+		{"../users.json", false},
+		{"20-1a2b3c4d5e6f-thumb.jpg/../../users.json", false},
+	}
+	for _, c := range cases {
+		if _, err := ps.Path(c.file); (err == nil) != c.ok {
+			t.Errorf("Case %v failed. Want valid: %v, Got: %v", c.file, c.ok, err)
+		}
+	}
+}
+
+func TestRecipePhotos(t *testing.T) {
+	r := Recipe{Photos: []Photo{{Name: "a", Step: 1}, {Name: "b"}, {Name: "c", Step: 1}, {Name: "d"}}}
+	if got := r.MainPhoto().Name; got != "b" {
+		t.Errorf("Want main photo b, Got: %v", got)
+	}
+	if got := r.StepPhotos(1); len(got) != 2 || got[0].Name != "a" || got[1].Name != "c" {
+		t.Errorf("Want photos a and c, Got: %+v", got)
+	}
+	if got := (Recipe{}).MainPhoto(); got.Name != "" {
+		t.Errorf("Want no main photo, Got: %+v", got)
+	}
+}
@@ -0,0 +1,587 @@
+package gocookbook
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is an expression in a parsed search query, see ParseQuery.
+type Expr interface {
+	String() string // String returns the expression as text that ParseQuery parses into the same expression.
+}
+
+// AndExpr matches recipes that match all its expressions. An empty AndExpr matches all recipes.
+type AndExpr []Expr
+
+// OrExpr matches recipes that match at least one of its expressions.
+type OrExpr []Expr
+
+// NotExpr matches recipes that do not match its expression.
+type NotExpr struct {
+	Expr Expr // Expression that must not match.
+}
+
+// TextExpr matches recipes that contain the text in any field, in the same way as Index.Search.
+type TextExpr string
+
+// FieldExpr matches recipes on a specific field, e.g. "tag:vegetarisch" or "time:<30m".
+type FieldExpr struct {
+	Field string  // Field, one of the keys of queryFields, e.g. "ingredient".
+	Op    string  // Comparison for numeric fields: "<", "<=", "=", ">=" or ">". Empty for the default comparison.
+	Value string  // Value as entered, e.g. "kip" or "30m".
+	n     float64 // Value of numeric fields, in minutes for time.
+}
+
+// queryFields contains the fields that can be used in a query, with the default comparison for numeric fields.
+var queryFields = map[string]string{
+	"tag":        "",
+	"ingredient": "",
+	"name":       "",
+	"source":     "",
+	"by":         "",
+	"time":       "<=",
+	"portions":   "=",
+}
+
+// queryAliases contains the Dutch (and other) names of the fields in queryFields.
+var queryAliases = map[string]string{
+	"tags":        "tag",
+	"ingr":        "ingredient",
+	"ingredients": "ingredient",
+	"naam":        "name",
+	"bron":        "source",
+	"door":        "by",
+	"tijd":        "time",
+	"duur":        "time",
+	"porties":     "portions",
+}
+
+var (
+	errorQuerySyntax = errors.New("invalid search query")          // Unbalanced parentheses or quotes.
+	errorQueryField  = errors.New("unknown field in search query") // Field is not in queryFields.
+	errorQueryValue  = errors.New("invalid value in search query") // Value of a field is empty or not a number.
+)
+
+// reQueryDuration matches the first part of a duration in a query: a number followed by a unit, e.g. "1h" or "30 min".
+var reQueryDuration = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)\s*(\pL*)\s*`)
+
+// queryDurationUnits contains the length in minutes of all units of a duration in a query, in English and Dutch. A
+// number without unit is in minutes.
+var queryDurationUnits = map[string]float64{
+	"": 1, "m": 1, "min": 1, "mins": 1, "minute": 1, "minutes": 1, "minuut": 1, "minuten": 1,
+	"h": 60, "hr": 60, "hrs": 60, "hour": 60, "hours": 60, "u": 60, "uur": 60, "uren": 60,
+	"d": 24 * 60, "day": 24 * 60, "days": 24 * 60, "dag": 24 * 60, "dagen": 24 * 60,
+}
+
+// ParseQuery takes a search query and returns it as expression. A query consists of words, which must all match:
+//   - a word or "quoted text" matches recipes that contain it in any field, e.g. creme or "rode ui";
+//   - field:value matches a specific field, e.g. tag:vegetarisch, ingredient:kip, name:soep, source:ah or by:chef;
+//   - time and portions are compared with <, <=, =, >= or >, e.g. time:<30m, time:<=1h30m, portions:>=4 (time
+//     without comparison is a maximum, portions without comparison must be equal);
+//   - a word starting with - excludes recipes, e.g. -ingredient:pinda;
+//   - OR between words matches recipes that match either side, and parentheses group words, e.g.
+//     (tag:soep OR tag:salade) -tag:vlees.
+//
+// Field names are also recognized in Dutch, e.g. ingrediënt, tijd, porties and door.
+func ParseQuery(s string) (Expr, error) {
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected '%v'", errorQuerySyntax, p.tokens[p.pos])
+	}
+	if e == nil {
+		return AndExpr{}, nil
+	}
+	return e, nil
+}
+
+// lexQuery splits a search query into words, parentheses and "-" before a parenthesis. Quoted text, also after a
+// field, is kept together including the quotes.
+func lexQuery(s string) ([]string, error) {
+	var tokens []string
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		switch r := rs[i]; {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+			i++
+			continue
+		}
+		start := i
+		for i < len(rs) && !unicode.IsSpace(rs[i]) && rs[i] != '(' && rs[i] != ')' {
+			if rs[i] == '"' {
+				end := i + 1
+				for end < len(rs) && rs[end] != '"' {
+					end++
+				}
+				if end == len(rs) {
+					return nil, fmt.Errorf("%w: missing closing quote", errorQuerySyntax)
+				}
+				i = end
+			}
+			i++
+		}
+		tokens = append(tokens, string(rs[start:i]))
+	}
+	return tokens, nil
+}
+
+// queryParser parses the words of a search query into an expression.
+type queryParser struct {
+	tokens []string // Words of the query.
+	pos    int      // Position of the next word.
+}
+
+// peek returns the next word, or an empty string at the end of the query.
+func (p *queryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// or parses words separated by OR.
+func (p *queryParser) or() (Expr, error) {
+	var or OrExpr
+	for {
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != "OR" {
+			if or == nil {
+				return e, nil
+			}
+			return append(or, e), nil
+		}
+		if e == nil {
+			return nil, fmt.Errorf("%w: nothing before OR", errorQuerySyntax)
+		}
+		p.pos++
+		or = append(or, e)
+	}
+}
+
+// and parses words until OR, a closing parenthesis or the end of the query. It returns nil if there are no words
+// (or only stop words).
+func (p *queryParser) and() (Expr, error) {
+	var and AndExpr
+	for p.peek() != "" && p.peek() != "OR" && p.peek() != ")" {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			and = append(and, e)
+		}
+	}
+	switch len(and) {
+	case 0:
+		if p.pos > 0 && p.tokens[p.pos-1] == "OR" {
+			return nil, fmt.Errorf("%w: nothing after OR", errorQuerySyntax)
+		}
+		return nil, nil
+	case 1:
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// unary parses a negated word, a group in parentheses or a single word.
+func (p *queryParser) unary() (Expr, error) {
+	tok := p.peek()
+	p.pos++
+	switch {
+	case tok == "-":
+		if p.peek() != "(" {
+			return nil, fmt.Errorf("%w: '-' without word", errorQuerySyntax)
+		}
+		e, err := p.unary()
+		if err != nil || e == nil {
+			return nil, err
+		}
+		return NotExpr{e}, nil
+	case tok == "(":
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", errorQuerySyntax)
+		}
+		p.pos++
+		return e, nil
+	case strings.HasPrefix(tok, "-"):
+		e, err := parseTerm(tok[1:])
+		if err != nil || e == nil {
+			return nil, err
+		}
+		return NotExpr{e}, nil
+	}
+	return parseTerm(tok)
+}
+
+// parseTerm takes a single word of a query and returns it as FieldExpr or TextExpr. It returns nil for stop words.
+func parseTerm(tok string) (Expr, error) {
+	i := strings.Index(tok, ":")
+	if i <= 0 || strings.HasPrefix(tok, `"`) {
+		text := strings.Trim(tok, `"`)
+		if len(terms(text)) == 0 {
+			return nil, nil
+		}
+		return TextExpr(text), nil
+	}
+	field := fold(tok[:i])
+	if f, ok := queryAliases[field]; ok {
+		field = f
+	}
+	def, ok := queryFields[field]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%v'", errorQueryField, tok[:i])
+	}
+	fe := FieldExpr{Field: field, Value: tok[i+1:]}
+	if def != "" {
+		for _, op := range []string{"<=", ">=", "<", ">", "="} {
+			if strings.HasPrefix(fe.Value, op) {
+				fe.Op, fe.Value = op, fe.Value[len(op):]
+				break
+			}
+		}
+	}
+	fe.Value = strings.TrimSpace(strings.Trim(fe.Value, `"`))
+	if fe.Value == "" {
+		return nil, fmt.Errorf("%w: %v without value", errorQueryValue, field)
+	}
+	var err error
+	switch field {
+	case "time":
+		fe.n, err = parseQueryDuration(fe.Value)
+	case "portions":
+		fe.n, err = strconv.ParseFloat(strings.Replace(fe.Value, ",", ".", 1), 64)
+		if err == nil && (math.IsNaN(fe.n) || math.IsInf(fe.n, 0) || fe.n < 0) {
+			err = errorQueryValue
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v:%v", errorQueryValue, field, fe.Value)
+	}
+	return fe, nil
+}
+
+// parseQueryDuration takes a duration in a query, e.g. "30m", "30mins", "1h30m", "1u30", "1 uur" or "45", and returns
+// it in minutes. A number without unit is in minutes. It returns an error for units below a minute (e.g. seconds) and
+// for values that are not a number, such as NaN or negative numbers.
+func parseQueryDuration(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, errorQueryValue
+	}
+	var minutes float64
+	for s != "" {
+		// e.g. "1h30" is 1 hour and 30 minutes
+		m := reQueryDuration.FindStringSubmatch(s)
+		if m == nil {
+			return 0, errorQueryValue
+		}
+		f, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		unit, ok := queryDurationUnits[m[2]]
+		if err != nil || !ok {
+			return 0, errorQueryValue
+		}
+		minutes += f * unit
+		s = s[len(m[0]):]
+	}
+	return minutes, nil
+}
+
+// String returns the expressions separated by spaces.
+func (e AndExpr) String() string {
+	xs := make([]string, len(e))
+	for i, x := range e {
+		xs[i] = x.String()
+		if _, ok := x.(OrExpr); ok {
+			xs[i] = "(" + xs[i] + ")"
+		}
+	}
+	return strings.Join(xs, " ")
+}
+
+// String returns the expressions separated by OR.
+func (e OrExpr) String() string {
+	xs := make([]string, len(e))
+	for i, x := range e {
+		xs[i] = x.String()
+	}
+	return strings.Join(xs, " OR ")
+}
+
+// String returns the expression preceded by a minus.
+func (e NotExpr) String() string {
+	switch e.Expr.(type) {
+	case AndExpr, OrExpr:
+		return "-(" + e.Expr.String() + ")"
+	}
+	return "-" + e.Expr.String()
+}
+
+// String returns the text, quoted if it contains more than one word.
+func (e TextExpr) String() string {
+	return quoteQuery(string(e))
+}
+
+// String returns the field and value, e.g. "time:<30m".
+func (e FieldExpr) String() string {
+	return e.Field + ":" + e.Op + quoteQuery(e.Value)
+}
+
+// quoteQuery returns s in quotes if it contains spaces or parentheses.
+func quoteQuery(s string) string {
+	if strings.ContainsAny(s, " ()") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// Query takes a search query (see ParseQuery) and returns all recipes in the Cookbook that match, in the same order.
+func (cb Cookbook) Query(q string) (Cookbook, error) {
+	e, err := ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	match := compileQuery(e)
+	var output Cookbook
+	for _, r := range cb {
+		ts := map[string]bool{}
+		for _, field := range searchFields {
+			for _, text := range field.texts(r) {
+				for _, t := range terms(text) {
+					ts[t] = true
+				}
+			}
+		}
+		text := func(q string) bool {
+			if ts[q] {
+				return true
+			}
+			for t := range ts {
+				if len(q) >= minStem && strings.HasPrefix(t, q) {
+					return true
+				}
+			}
+			return false
+		}
+		if match(r, text) {
+			output = append(output, r)
+		}
+	}
+	return output, nil
+}
+
+// Query takes a search query (see ParseQuery) and returns all recipes in the Index that match. Recipes are ranked
+// by the words in the query that are not excluded, like Index.Search, or by name if the query contains no such words.
+func (ix *Index) Query(q string) ([]SearchResult, error) {
+	e, err := ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	match := compileQuery(e)
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	scores := map[string]map[int]float64{} // relevance per recipe per term in the query
+	matched := map[string]bool{}           // terms in the index that match words that are not excluded
+	var positive []string
+	walkText(e, false, func(text string, negated bool) {
+		for _, t := range terms(text) {
+			if _, ok := scores[t]; !ok {
+				scores[t] = ix.scores(t, map[string]bool{})
+			}
+			if !negated {
+				for m := range ix.expand(t) {
+					matched[m] = true
+				}
+				positive = append(positive, t)
+			}
+		}
+	})
+	positive = dedupe(positive)
+	found := map[int]float64{}
+	for id, doc := range ix.docs {
+		text := func(t string) bool {
+			_, ok := scores[t][id]
+			return ok
+		}
+		if !match(doc.rcp, text) {
+			continue
+		}
+		found[id] = 0
+		for _, t := range positive {
+			found[id] += scores[t][id]
+		}
+	}
+	return ix.results(found, matched), nil
+}
+
+// walkText calls f for each TextExpr in expression e, with true if the text is excluded from the results.
+func walkText(e Expr, negated bool, f func(text string, negated bool)) {
+	switch e := e.(type) {
+	case AndExpr:
+		for _, x := range e {
+			walkText(x, negated, f)
+		}
+	case OrExpr:
+		for _, x := range e {
+			walkText(x, negated, f)
+		}
+	case NotExpr:
+		walkText(e.Expr, !negated, f)
+	case TextExpr:
+		f(string(e), negated)
+	}
+}
+
+// matchFunc returns true if Recipe r matches. The function text returns true if a term (see terms) occurs in r.
+type matchFunc func(r Recipe, text func(term string) bool) bool
+
+// compileQuery takes an expression and returns a function that matches recipes against it.
+func compileQuery(e Expr) matchFunc {
+	switch e := e.(type) {
+	case AndExpr:
+		fs := make([]matchFunc, len(e))
+		for i, x := range e {
+			fs[i] = compileQuery(x)
+		}
+		return func(r Recipe, text func(string) bool) bool {
+			for _, f := range fs {
+				if !f(r, text) {
+					return false
+				}
+			}
+			return true
+		}
+	case OrExpr:
+		fs := make([]matchFunc, len(e))
+		for i, x := range e {
+			fs[i] = compileQuery(x)
+		}
+		return func(r Recipe, text func(string) bool) bool {
+			for _, f := range fs {
+				if f(r, text) {
+					return true
+				}
+			}
+			return false
+		}
+	case NotExpr:
+		f := compileQuery(e.Expr)
+		return func(r Recipe, text func(string) bool) bool { return !f(r, text) }
+	case TextExpr:
+		ts := terms(string(e))
+		return func(r Recipe, text func(string) bool) bool {
+			for _, t := range ts {
+				if !text(t) {
+					return false
+				}
+			}
+			return true
+		}
+	case FieldExpr:
+		f := e.compile()
+		return func(r Recipe, _ func(string) bool) bool { return f(r) }
+	}
+	return func(Recipe, func(string) bool) bool { return true }
+}
+
+// compile returns a function that returns true if a Recipe matches the FieldExpr.
+func (e FieldExpr) compile() func(r Recipe) bool {
+	v := fold(e.Value)
+	op := e.Op
+	if op == "" {
+		op = queryFields[e.Field]
+	}
+	switch e.Field {
+	case "tag":
+		return func(r Recipe) bool {
+			for _, t := range r.Tags {
+				if TagTree.is(t, v) {
+					return true
+				}
+			}
+			return false
+		}
+	case "ingredient":
+		// like findIngr, or all words of the value occur in the item, e.g. "tomaat" matches "tomaten"
+		hasIngr, ts := ingredientMatcher(e.Value), terms(e.Value)
+		return func(r Recipe) bool {
+			if hasIngr(r) {
+				return true
+			}
+			for _, i := range r.Ingrs {
+				if containsTerms(terms(i.Item), ts) {
+					return true
+				}
+			}
+			return false
+		}
+	case "name":
+		return func(r Recipe) bool { return strings.Contains(fold(r.Name), v) }
+	case "source":
+		return func(r Recipe) bool {
+			return strings.Contains(fold(r.Source), v) || strings.Contains(fold(r.SourceLink), v)
+		}
+	case "by":
+		return func(r Recipe) bool { return fold(r.Createdby) == v || fold(r.Updatedby) == v }
+	case "time":
+		// recipes without cooking time never match
+		return func(r Recipe) bool { return r.Dur > 0 && compareQuery(r.Dur.Minutes(), op, e.n) }
+	case "portions":
+		return func(r Recipe) bool { return compareQuery(r.Portions, op, e.n) }
+	}
+	return func(Recipe) bool { return false }
+}
+
+// containsTerms returns true if xs contains all terms in ys.
+func containsTerms(xs, ys []string) bool {
+	for _, y := range ys {
+		found := false
+		for _, x := range xs {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(ys) > 0
+}
+
+// compareQuery returns the result of comparing x with y using op, e.g. "<=".
+func compareQuery(x float64, op string, y float64) bool {
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return x == y
+}
@@ -0,0 +1,173 @@
+package gocookbook
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		query string
+		want  string // String of the parsed expression
+		err   error
+	}{
+		{"", "", nil},
+		{"kip", "kip", nil},
+		{`tag:vegetarisch ingredient:kip -ingredient:pinda time:<30m portions:>=4 by:chef`,
+			"tag:vegetarisch ingredient:kip -ingredient:pinda time:<30m portions:>=4 by:chef", nil},
+		{`Ingrediënt:"rode ui" TIJD:1u30 porties:4 door:Chef`,
+			`ingredient:"rode ui" time:1u30 portions:4 by:Chef`, nil},
+		{`(tag:soep OR tag:salade) -tag:vlees`, "(tag:soep OR tag:salade) -tag:vlees", nil},
+		{`tag:soep OR tag:salade creme`, "tag:soep OR tag:salade creme", nil},
+		{`-(tag:soep OR tag:salade)`, "-(tag:soep OR tag:salade)", nil},
+		{`"crème fraîche" de`, `"crème fraîche"`, nil},
+		{`(kip`, "", errorQuerySyntax},
+		{`kip)`, "", errorQuerySyntax},
+		{`"kip`, "", errorQuerySyntax},
+		{`OR kip`, "", errorQuerySyntax},
+		{`kip OR`, "", errorQuerySyntax},
+		{`kleur:rood`, "", errorQueryField},
+		{`tag:`, "", errorQueryValue},
+		{`time:<snel`, "", errorQueryValue},
+		{`portions:>=veel`, "", errorQueryValue},
+		{`time:<30s`, "", errorQueryValue},
+		{`time:NaN`, "", errorQueryValue},
+		{`portions:NaN`, "", errorQueryValue},
+		{`portions:>=-2`, "", errorQueryValue},
+		{`portions:Inf`, "", errorQueryValue},
+	}
+	for _, c := range cases {
+		e, err := ParseQuery(c.query)
+		switch {
+		case !errors.Is(err, c.err):
+			t.Errorf("Query %q failed. Want error: %v, Got: %v", c.query, c.err, err)
+		case err == nil && e.String() != c.want:
+			t.Errorf("Query %q failed. Want: %q, Got: %q", c.query, c.want, e.String())
+		}
+	}
+}
+
+func TestParseQueryDuration(t *testing.T) {
+	cases := map[string]float64{"30m": 30, "45": 45, "1h30m": 90, "1u30": 90, "2u": 120, "20min": 20, "30mins": 30,
+		"1h30mins": 90, "45minuten": 45, "1 uur": 60, "1uur30": 90, "1,5u": 90, "2hrs": 120, "1dag": 1440}
+	for s, want := range cases {
+		if got, err := parseQueryDuration(s); err != nil || got != want {
+			t.Errorf("Duration %v failed. Want: %v, Got: %v (%v)", s, want, got, err)
+		}
+	}
+	for _, s := range []string{"30s", "30ms", "1h30s", "NaN", "Inf", "-5", "-1h", "snel", "30m snel"} {
+		if got, err := parseQueryDuration(s); !errors.Is(err, errorQueryValue) {
+			t.Errorf("Duration %v failed. Want error: %v, Got: %v (%v)", s, errorQueryValue, got, err)
+		}
+	}
+}
+
+// queryCookbook returns a Cookbook to test queries.
+func queryCookbook() Cookbook {
+	cb := searchCookbook()
+	cb[0].Dur, cb[0].Portions, cb[0].Createdby = 60*time.Minute, 4, "chef"
+	cb[1].Dur, cb[1].Portions, cb[1].Createdby = 25*time.Minute, 4, "bob"
+	cb[2].Dur, cb[2].Portions, cb[2].Createdby, cb[2].Updatedby = 20*time.Minute, 2, "bob", "chef"
+	cb[2].Ingrs = append(cb[2].Ingrs, Ingredient{Amount: 50, Unit: gram, Item: "pinda's"})
+	return cb
+}
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		query string
+		want  []int // Ids of the matching recipes, ordered by name for Cookbook.Query
+	}{
+		{"", []int{10, 30, 20}},
+		{"tag:vegetarisch", []int{20}},
+		{"tag:VEGETARISCH ingredient:tomaat", []int{20}},
+		{"ingredient:tomaat -ingredient:pinda", []int{20}},
+		{"time:<30m", []int{30, 20}},
+		{"time:<25", []int{30}},
+		{"time:60m", []int{10, 30, 20}},
+		{"portions:>=4", []int{10, 20}},
+		{"portions:2", []int{30}},
+		{"by:chef", []int{10, 30}},
+		{"by:chef -tag:dessert", []int{30}},
+		{"tag:soep OR tag:dessert", []int{10, 20}},
+		{"(tag:soep OR tag:pasta) tomaten", []int{30, 20}},
+		{"creme -name:brûlée", []int{30}},
+		{"source:onbekend", nil},
+	}
+	cb := queryCookbook()
+	byName(cb)
+	ix := NewIndex(cb)
+	for _, c := range cases {
+		got, err := cb.Query(c.query)
+		if err != nil {
+			t.Fatalf("Query %q failed: %v", c.query, err)
+		}
+		if !sameIds(got, c.want) {
+			t.Errorf("Cookbook query %q failed. Want: %v, Got: %v", c.query, c.want, ids(got))
+		}
+		results, err := ix.Query(c.query)
+		if err != nil {
+			t.Fatalf("Query %q failed: %v", c.query, err)
+		}
+		var rcps Cookbook
+		for _, r := range results {
+			rcps = append(rcps, r.Recipe)
+		}
+		byName(rcps)
+		if !sameIds(rcps, c.want) {
+			t.Errorf("Index query %q failed. Want: %v, Got: %v", c.query, c.want, ids(rcps))
+		}
+	}
+	if _, err := cb.Query("kleur:rood"); !errors.Is(err, errorQueryField) {
+		t.Errorf("Want error %v, Got: %v", errorQueryField, err)
+	}
+}
+
+func TestIndexQueryRanking(t *testing.T) {
+	ix := NewIndex(queryCookbook())
+	results, err := ix.Query("tomaten -tag:dessert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Recipe.Id != 20 || results[0].Score <= 0 || results[1].Snippet == nil {
+		t.Errorf("Want Tomatensoep first and a snippet for both results, Got: %+v", results)
+	}
+	// Excluded words also exclude recipes where they only occur in the text
+	results, _ = ix.Query("pasta -pinda")
+	if len(results) != 0 {
+		t.Errorf("Want no results, Got: %v", len(results))
+	}
+	results, _ = ix.Query("tag:dessert")
+	if len(results) != 1 || results[0].Score != 0 {
+		t.Errorf("Want 1 result without relevance, Got: %+v", results)
+	}
+}
+
+// byName sorts the recipes in the Cookbook by name.
+func byName(cb Cookbook) {
+	sort.Slice(cb, func(i, j int) bool { return cb[i].Name < cb[j].Name })
+}
+
+// ids returns the Ids of the recipes in the Cookbook.
+func ids(cb Cookbook) []int {
+	var xs []int
+	for _, r := range cb {
+		xs = append(xs, r.Id)
+	}
+	return xs
+}
+
+// sameIds returns true if the Cookbook contains the recipes with the given Ids, in the same order.
+func sameIds(cb Cookbook, want []int) bool {
+	got := ids(cb)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
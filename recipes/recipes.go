@@ -2,6 +2,7 @@ package gocookbook
 
 import (
 	"errors"
+	"math"
 	"sort"
 	"strings"
 	"time"
@@ -19,6 +20,7 @@ type Recipe struct {
 	Tags       []string      // Tags for a recipe.
 	Portions   float64       // Default number of portions for recipe.
 	Dur        time.Duration // Cooking time
+	Pan        Pan           // Baking pan or mould the recipe is written for (optional).
 	Notes      string        // Notes and/or description on recipes.
 	Source     string        // Source of the recipe.
 	SourceLink string        // Hyperlink to the source.
@@ -26,12 +28,17 @@ type Recipe struct {
 	Created    time.Time     // Datetime when created.
 	Updatedby  string        // User that last updated the recipe.
 	Updated    time.Time     // Datetime when last updated.
+	Photos     []Photo       `json:",omitempty"` // Photos of the recipe and its steps; the first photo of the recipe itself is the main photo.
 }
 
 const idSteps = 10 // idSteps is the increment that is used for each new Recipe ID. E.g. if idSteps is 10, then IDs will be 10, 20, 30. If it is 12, then: 12, 24, 36.
 
 var (
-	errorUnknownRecipe = errors.New("recipe not found") // Not Found Error.
+	errorUnknownRecipe     = errors.New("recipe not found")                      // Not Found Error.
+	errorUnknownIngredient = errors.New("ingredient not found")                  // Ingredient index does not exist.
+	errorInvalidAmount     = errors.New("amount must be a number above zero")    // Amount cannot be used for scaling.
+	errorInvalidPortions   = errors.New("portions must be a number above zero")  // Portions cannot be used for scaling.
+	errorNoPortions        = errors.New("recipe has no portions to adjust from") // Recipe cannot be scaled by portions.
 )
 
 // NewRecipe takes all parameters for a Recipe, creates a new Recipe and returns it.
@@ -121,23 +128,58 @@ func newRecipeId(ckb Cookbook) int {
 }
 
 // adjustRcp adjusts the amount of all Ingredients in the Recipe r to the desired portions and returns the adjusted Recipe.
-func adjustRcp(r Recipe, portions float64) Recipe {
-	newIngrs := make([]Ingredient, len(r.Ingrs))
-	copy(newIngrs, r.Ingrs)
-	newRcp := Recipe{
-		Id:         r.Id,
-		Name:       r.Name,
-		Ingrs:      newIngrs,
-		Steps:      r.Steps,
-		Tags:       r.Tags,
-		Portions:   portions,
-		Dur:        r.Dur,
-		Source:     r.Source,
-		SourceLink: r.SourceLink,
+// It returns an error if the portions are not a finite number above zero, or if the recipe has no portions.
+func adjustRcp(r Recipe, portions float64) (Recipe, error) {
+	switch {
+	case !positive(portions):
+		return Recipe{}, errorInvalidPortions
+	case r.Portions <= 0:
+		return Recipe{}, errorNoPortions
+	}
+	return scaleRcp(r, portions/r.Portions), nil
+}
+
+// adjustRcpByIngrd adjusts the Recipe r so that the Ingredient on index i has the desired amount and returns the
+// adjusted Recipe. The amount is in the unit the ingredient is shown in for System s, e.g. in ml for a metric user if
+// the recipe is written in cups. All other ingredients and the portions are scaled by the same factor.
+func adjustRcpByIngrd(r Recipe, i int, amount float64, s System) (Recipe, error) {
+	if i < 0 || i >= len(r.Ingrs) {
+		return Recipe{}, errorUnknownIngredient
+	}
+	shown := r.Ingrs[i].ConvertTo(s).Amount
+	if !positive(shown) || !positive(amount) {
+		return Recipe{}, errorInvalidAmount
+	}
+	return scaleRcp(r, amount/shown), nil
+}
+
+// positive returns true if x is a finite number above zero, so it can be used for scaling. NaN and infinity, which
+// strconv.ParseFloat accepts as "NaN" and "Inf", are not.
+func positive(x float64) bool {
+	return x > 0 && !math.IsInf(x, 1)
+}
+
+// scaleRcp takes a Recipe and a factor x, multiplies the portions and the amount of all Ingredients that are not
+// fixed with x and returns the scaled Recipe. Amounts are not rounded, as rounding is only done when printing.
+func scaleRcp(r Recipe, x float64) Recipe {
+	newRcp := r
+	newRcp.Ingrs = make([]Ingredient, len(r.Ingrs))
+	copy(newRcp.Ingrs, r.Ingrs)
+	if x == 1 {
+		return newRcp
 	}
-	x := round(portions / r.Portions)
+	newRcp.Portions = r.Portions * x
 	for i, v := range newRcp.Ingrs {
-		newRcp.Ingrs[i].Amount = round(v.Amount * x)
+		if v.Fixed {
+			continue
+		}
+		f, u := promote(v.Amount*x, v.Unit)
+		conv := 1.0 // conversion from the original to the promoted unit, also used for the maximum of a range
+		if v.Amount != 0 {
+			conv = f / (v.Amount * x)
+		}
+		newRcp.Ingrs[i].Amount, newRcp.Ingrs[i].Unit = f, u
+		newRcp.Ingrs[i].AmountMax = v.AmountMax * x * conv
 	}
 	return newRcp
 }
@@ -151,22 +193,32 @@ func (cb Cookbook) FindIngredient(item string) Cookbook {
 
 // FindIngr takes a slice of recipes and an item. It returns all recipes that
 // have an ingredient that (partially) matches the item and/or recipe name.
+// If the item is in the catalogue, recipes with other spellings of the same ingredient (e.g. synonyms) are included.
 func findIngr(rcps Cookbook, item string) Cookbook {
 	item = strings.ToLower(item)
+	hasIngr := ingredientMatcher(item)
 	var output Cookbook
 	for _, rcp := range rcps {
-		if strings.Contains(strings.ToLower(rcp.Name), item) {
+		if strings.Contains(strings.ToLower(rcp.Name), item) || hasIngr(rcp) {
 			output = append(output, rcp)
-			continue
 		}
-		for _, ingrd := range rcp.Ingrs {
-			if strings.Contains(strings.ToLower(ingrd.Item), item) {
-				output = append(output, rcp)
-				break
+	}
+	return output
+}
+
+// ingredientMatcher takes an item and returns a function that returns true if a Recipe has an ingredient that
+// (partially) matches the item or, if the item is in the catalogue, is the same ingredient under another name.
+func ingredientMatcher(item string) func(r Recipe) bool {
+	item = strings.ToLower(item)
+	d, known := Densities.Find(item)
+	return func(r Recipe) bool {
+		for _, ingrd := range r.Ingrs {
+			if c, ok := Densities.Find(ingrd.key()); strings.Contains(strings.ToLower(ingrd.Item), item) || known && ok && c.Name == d.Name {
+				return true
 			}
 		}
+		return false
 	}
-	return output
 }
 
 // Remove takes an Recipe id. The recipe that matches the id is removed
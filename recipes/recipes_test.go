@@ -2,6 +2,7 @@ package gocookbook
 
 import (
 	"errors"
+	"math"
 	"reflect"
 	"testing"
 	"time"
@@ -182,10 +183,121 @@ func TestAdjustRecipe(t *testing.T) {
 		"Tester 1",
 	)
 	portionsNew := 8.0
-	result := adjustRcp(r, portionsNew)
+	result, err := adjustRcp(r, portionsNew)
+	if err != nil {
+		t.Fatal(err)
+	}
 	for i := range result.Ingrs {
 		if got, want := result.Ingrs[i].Amount, (r.Ingrs[i].Amount * portionsNew / r.Portions); got != want {
 			t.Errorf("Want: %v, Got: %v for %+v ", want, got, result.Ingrs[i])
 		}
 	}
 }
+
+func TestAdjustRecipeExact(t *testing.T) {
+	r := Recipe{
+		Name:      "Test1",
+		Portions:  3,
+		Notes:     "Notitie",
+		Createdby: "Tester1",
+		Ingrs: []Ingredient{
+			{Amount: 300, Unit: gram, Item: "bloem"},
+			{Amount: 1, Unit: pcs, Item: "laurierblad", Fixed: true},
+			{Amount: 4, Unit: tsp, Item: "suiker"},
+			{Amount: 900, Unit: gram, Item: "aardappels"},
+		},
+	}
+	got, _ := adjustRcp(r, 4)
+	if got.Notes != r.Notes || got.Createdby != r.Createdby || got.Portions != 4 {
+		t.Errorf("recipe fields not copied: %+v", got)
+	}
+	cases := []struct {
+		amount float64
+		unit   Unit
+	}{
+		{400, gram},
+		{1, pcs},
+		{16.0 / 3 * tspToMl / tbspToMl, tbsp},
+		{1.2, kilo},
+	}
+	for i, c := range cases {
+		if math.Abs(got.Ingrs[i].Amount-c.amount) > 1e-9 || got.Ingrs[i].Unit != c.unit {
+			t.Errorf("Case %v failed. Want: %v %v, Got: %v %v", i, c.amount, c.unit, got.Ingrs[i].Amount, got.Ingrs[i].Unit)
+		}
+	}
+	if r.Ingrs[0].Amount != 300 {
+		t.Errorf("original recipe has been changed: %+v", r.Ingrs[0])
+	}
+	t.Run("range", func(t *testing.T) {
+		r := Recipe{Portions: 2, Ingrs: []Ingredient{{Amount: 600, AmountMax: 800, Unit: gram, Item: "aardappels"}}}
+		got, _ := adjustRcp(r, 4)
+		if in := got.Ingrs[0]; in.Amount != 1.2 || in.AmountMax != 1.6 || in.Unit != kilo {
+			t.Errorf("Want: 1.2-1.6 kg, Got: %v-%v %v", in.Amount, in.AmountMax, in.Unit)
+		}
+	})
+	t.Run("invalid portions", func(t *testing.T) {
+		for _, p := range []float64{0, -2, math.NaN(), math.Inf(1), math.Inf(-1)} {
+			if _, err := adjustRcp(r, p); !errors.Is(err, errorInvalidPortions) {
+				t.Errorf("Portions %v failed. Want error: %v, Got: %v", p, errorInvalidPortions, err)
+			}
+		}
+	})
+	t.Run("zero portions", func(t *testing.T) {
+		r.Portions = 0
+		if _, err := adjustRcp(r, 4); !errors.Is(err, errorNoPortions) {
+			t.Errorf("Want error: %v, Got: %v", errorNoPortions, err)
+		}
+	})
+	t.Run("by ingredient", func(t *testing.T) {
+		r.Portions = 3
+		got, err := adjustRcpByIngrd(r, 0, 450, AsWritten)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Portions != 4.5 || got.Ingrs[0].Amount != 450 {
+			t.Errorf("Want: %v portions with %v g, Got: %v portions with %v g", 4.5, 450, got.Portions, got.Ingrs[0].Amount)
+		}
+		if _, err := adjustRcpByIngrd(r, 10, 450, AsWritten); !errors.Is(err, errorUnknownIngredient) {
+			t.Errorf("Want: %v, Got: %v", errorUnknownIngredient, err)
+		}
+		for _, amount := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+			if _, err := adjustRcpByIngrd(r, 0, amount, AsWritten); !errors.Is(err, errorInvalidAmount) {
+				t.Errorf("Amount %v failed. Want error: %v, Got: %v", amount, errorInvalidAmount, err)
+			}
+		}
+	})
+	t.Run("by converted ingredient", func(t *testing.T) {
+		// a metric user sees 1 cup as 236.6 ml and enters the amount in ml
+		r := Recipe{Portions: 4, Ingrs: []Ingredient{{Amount: 1, Unit: cup, Item: "melk"}, {Amount: 100, Unit: gram, Item: "suiker"}}}
+		ml, _ := Registry.Convert(2, cup, ml)
+		got, err := adjustRcpByIngrd(r, 0, ml, Metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Portions != 8 || got.Ingrs[0].Amount != 2 || got.Ingrs[0].Unit != cup || got.Ingrs[1].Amount != 200 {
+			t.Errorf("Want 8 portions with 2 cup and 200 g, Got: %v portions with %+v", got.Portions, got.Ingrs)
+		}
+	})
+}
+
+func TestPromote(t *testing.T) {
+	cases := []struct {
+		amount float64
+		unit   Unit
+		want   Unit
+	}{
+		{2, tsp, tsp},
+		{3, tsp, tbsp},
+		{16, tsp, ml},
+		{4, tbsp, tbsp},
+		{999, gram, gram},
+		{1200, gram, kilo},
+		{1500, ml, liter},
+		{10, cup, cup},
+	}
+	for i, c := range cases {
+		if _, got := promote(c.amount, c.unit); got != c.want {
+			t.Errorf("Case %v failed. Want: %v, Got: %v", i, c.want, got)
+		}
+	}
+}
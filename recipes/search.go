@@ -0,0 +1,395 @@
+package gocookbook
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index is an inverted index of the words in the recipes of a Cookbook, used for full-text search. Words are folded
+// (lowercase, without diacritics) and stemmed, so "Crème" is found with "creme" and "tomaten" with "tomaat". The
+// Index is safe for concurrent use and is updated per recipe with Add and Remove.
+type Index struct {
+	mu       sync.RWMutex
+	postings map[string]map[int][]int // Term to recipe Id to the number of occurrences per searchField.
+	docs     map[int]indexDoc         // Indexed recipes by Id.
+}
+
+// indexDoc represents a recipe in the Index.
+type indexDoc struct {
+	rcp   Recipe   // Recipe as it was indexed.
+	terms []string // Distinct terms of the recipe, used to remove it from the postings.
+}
+
+// SearchResult represents a recipe that matches a search, with the part of its text that matches.
+type SearchResult struct {
+	Recipe  Recipe        // Recipe that matches.
+	Score   float64       // Relevance of the recipe, higher is more relevant.
+	Field   string        // Name of the field of the snippet, e.g. "bereiding".
+	Snippet []SnippetPart // Text around the first match, empty if only the name of the recipe matches.
+}
+
+// SnippetPart represents a part of the text of a snippet, which is highlighted if it matches the search.
+type SnippetPart struct {
+	Text  string // Text of the part.
+	Match bool   // Text matches a word that is searched for.
+}
+
+// searchField represents a part of a recipe that is indexed. Matches in fields with a higher weight are more
+// relevant, e.g. a match in the name counts more than a match in the steps.
+type searchField struct {
+	name   string                  // Name of the field, as shown with a snippet.
+	weight float64                 // Weight of a match in the field.
+	texts  func(r Recipe) []string // Texts of the field in Recipe r.
+}
+
+// searchFields contains all fields that are indexed, in the order in which they are used for snippets.
+var searchFields = []searchField{
+	{"naam", 5, func(r Recipe) []string { return []string{r.Name} }},
+	{"tags", 4, func(r Recipe) []string { return r.Tags }},
+	{"ingrediënten", 3, ingredientTexts},
+	{"notities", 1.5, func(r Recipe) []string { return []string{r.Notes} }},
+	{"bereiding", 1, func(r Recipe) []string { return r.Steps }},
+	{"bron", 1, func(r Recipe) []string { return []string{r.Source} }},
+}
+
+// stopWords contains frequent Dutch and English words that are neither indexed nor searched for.
+var stopWords = map[string]bool{
+	"de": true, "het": true, "een": true, "en": true, "of": true, "in": true, "op": true, "te": true, "met": true,
+	"van": true, "voor": true, "aan": true, "tot": true, "dan": true, "die": true, "dat": true, "je": true,
+	"the": true, "a": true, "an": true, "and": true, "or": true, "to": true, "with": true, "for": true, "on": true,
+}
+
+// stemSuffixes contains the Dutch and English suffixes that are replaced when stemming, longest first. Only the first
+// suffix that matches is replaced.
+var stemSuffixes = []struct{ suffix, replace string }{
+	{"ches", "ch"}, {"shes", "sh"}, {"sses", "ss"}, {"jes", ""}, {"ies", "y"}, {"oes", "o"},
+	{"xes", "x"}, {"ing", ""}, {"en", ""}, {"ed", ""}, {"ss", "ss"}, {"s", ""},
+}
+
+const minStem = 3 // Minimum length of a stem, shorter words are not stemmed.
+
+const (
+	snippetBefore = 6  // Number of words before the first match in a snippet.
+	snippetAfter  = 12 // Number of words from the first match in a snippet.
+)
+
+// NewIndex takes a Cookbook and returns an Index of all its recipes.
+func NewIndex(cb Cookbook) *Index {
+	ix := &Index{}
+	ix.Reset(cb)
+	return ix
+}
+
+// Reset replaces all recipes in the Index with the recipes in Cookbook cb.
+func (ix *Index) Reset(cb Cookbook) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.postings = map[string]map[int][]int{}
+	ix.docs = map[int]indexDoc{}
+	for _, r := range cb {
+		ix.add(r)
+	}
+}
+
+// Add takes a Recipe and adds it to the Index. If a recipe with the same Id is already indexed, it is replaced.
+func (ix *Index) Add(r Recipe) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(r.Id)
+	ix.add(r)
+}
+
+// Remove takes the Id of a recipe and removes the recipe from the Index.
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// Len returns the number of recipes in the Index.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// add adds Recipe r to the Index, which must be locked and must not contain r.
+func (ix *Index) add(r Recipe) {
+	counts := map[string][]int{}
+	for f, field := range searchFields {
+		for _, text := range field.texts(r) {
+			for _, t := range terms(text) {
+				if counts[t] == nil {
+					counts[t] = make([]int, len(searchFields))
+				}
+				counts[t][f]++
+			}
+		}
+	}
+	doc := indexDoc{rcp: r}
+	for t, c := range counts {
+		if ix.postings[t] == nil {
+			ix.postings[t] = map[int][]int{}
+		}
+		ix.postings[t][r.Id] = c
+		doc.terms = append(doc.terms, t)
+	}
+	ix.docs[r.Id] = doc
+}
+
+// remove removes the recipe with the given id from the Index, which must be locked.
+func (ix *Index) remove(id int) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, t := range doc.terms {
+		delete(ix.postings[t], id)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Search takes a text and returns all recipes that contain every word in the text, the most relevant first. Words
+// match other forms of the same word (e.g. "tomaat" and "tomaten") and words that start with them (e.g. "paddestoel"
+// and "paddestoelenrisotto"). Each result contains a snippet of the text where the recipe matches.
+func (ix *Index) Search(text string) []SearchResult {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	qs := dedupe(terms(text))
+	if len(qs) == 0 {
+		return nil
+	}
+	var scores map[int]float64
+	matched := map[string]bool{} // all terms in the index that match a word of the search
+	for _, q := range qs {
+		got := ix.scores(q, matched)
+		if scores == nil {
+			scores = got
+			continue
+		}
+		for id := range scores {
+			if s, ok := got[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return ix.results(scores, matched)
+}
+
+// scores takes a term of a search and returns the relevance of each recipe that matches the term. All terms in the
+// Index that match are added to matched.
+func (ix *Index) scores(q string, matched map[string]bool) map[int]float64 {
+	got := map[int]float64{}
+	for t, factor := range ix.expand(q) {
+		matched[t] = true
+		idf := math.Log(1 + float64(len(ix.docs))/float64(len(ix.postings[t])))
+		for id, counts := range ix.postings[t] {
+			var s float64
+			for f, n := range counts {
+				s += searchFields[f].weight * float64(n) / float64(n+1)
+			}
+			got[id] += idf * factor * s
+		}
+	}
+	return got
+}
+
+// results takes the relevance per recipe Id and the matched terms, and returns the results with a snippet, the most
+// relevant first and otherwise by name.
+func (ix *Index) results(scores map[int]float64, matched map[string]bool) []SearchResult {
+	results := make([]SearchResult, 0, len(scores))
+	for id, s := range scores {
+		r := ix.docs[id].rcp
+		field, snippet := makeSnippet(r, matched)
+		results = append(results, SearchResult{Recipe: r, Score: s, Field: field, Snippet: snippet})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Recipe.Name < results[j].Recipe.Name
+	})
+	return results
+}
+
+// expand takes a term of a search and returns the terms in the Index that match it, with the factor by which a
+// match counts: 1 for the term itself and 0.5 for longer terms that start with it.
+func (ix *Index) expand(q string) map[string]float64 {
+	matches := map[string]float64{}
+	if ix.postings[q] != nil {
+		matches[q] = 1
+	}
+	if len(q) < minStem {
+		return matches
+	}
+	for t := range ix.postings {
+		if t != q && strings.HasPrefix(t, q) {
+			matches[t] = 0.5
+		}
+	}
+	return matches
+}
+
+// makeSnippet takes a Recipe and the matched terms and returns the name of the first field (after the name) that
+// contains a match, together with the text around that match.
+func makeSnippet(r Recipe, matched map[string]bool) (string, []SnippetPart) {
+	for _, field := range searchFields[1:] {
+		for _, text := range field.texts(r) {
+			if snippet := snippetOf(text, matched); snippet != nil {
+				return field.name, snippet
+			}
+		}
+	}
+	return "", nil
+}
+
+// snippetOf takes a text and the matched terms and returns the words around the first matching word in the text,
+// with all matching words highlighted. It returns nil if no word matches.
+func snippetOf(text string, matched map[string]bool) []SnippetPart {
+	spans := wordSpans(text)
+	first := -1
+	for i, sp := range spans {
+		if matched[stem(fold(text[sp[0]:sp[1]]))] {
+			first = i
+			break
+		}
+	}
+	if first == -1 {
+		return nil
+	}
+	from, to := max(0, first-snippetBefore), min(len(spans), first+snippetAfter)
+	var parts []SnippetPart
+	if from > 0 {
+		parts = append(parts, SnippetPart{Text: "… "})
+	}
+	prev := spans[from][0]
+	for _, sp := range spans[from:to] {
+		word := text[sp[0]:sp[1]]
+		if matched[stem(fold(word))] {
+			parts = appendPart(parts, text[prev:sp[0]], false)
+			parts = appendPart(parts, word, true)
+		} else {
+			parts = appendPart(parts, text[prev:sp[1]], false)
+		}
+		prev = sp[1]
+	}
+	if to < len(spans) {
+		parts = appendPart(parts, " …", false)
+	} else {
+		parts = appendPart(parts, text[prev:], false)
+	}
+	return parts
+}
+
+// appendPart appends text to the parts of a snippet, merged with the last part if both are (not) highlighted.
+func appendPart(parts []SnippetPart, text string, match bool) []SnippetPart {
+	switch {
+	case text == "":
+		return parts
+	case len(parts) > 0 && parts[len(parts)-1].Match == match:
+		parts[len(parts)-1].Text += text
+		return parts
+	}
+	return append(parts, SnippetPart{text, match})
+}
+
+// ingredientTexts returns the item and notes of each Ingredient of Recipe r. If the item is in the catalogue under
+// another name, that name is added, so e.g. recipes with "zucchini" are found with "courgette".
+func ingredientTexts(r Recipe) []string {
+	xs := make([]string, 0, len(r.Ingrs))
+	for _, i := range r.Ingrs {
+		s := strings.TrimSpace(i.Item + " " + i.Notes)
+		if d, ok := Densities.Find(i.key()); ok && fold(d.Name) != fold(i.Item) {
+			s += " (" + d.Name + ")"
+		}
+		xs = append(xs, s)
+	}
+	return xs
+}
+
+// wordSpans returns the start and end of each word (a sequence of letters and digits) in s.
+func wordSpans(s string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		switch {
+		case word && start == -1:
+			start = i
+		case !word && start != -1:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return spans
+}
+
+// terms takes a text and returns the stem of each word in the text that is not a stop word.
+func terms(s string) []string {
+	var ts []string
+	for _, sp := range wordSpans(s) {
+		w := fold(s[sp[0]:sp[1]])
+		if w == "" || stopWords[w] {
+			continue
+		}
+		ts = append(ts, stem(w))
+	}
+	return ts
+}
+
+// stem takes a folded word and returns its stem, so that singular, plural and diminutive forms of Dutch and English
+// words (e.g. "tomaat", "tomaten" and "tomaatjes", or "onion" and "onions") have the same stem. It is a simple
+// approximation of a stemmer: the stem is not always a real word and some different words share a stem.
+func stem(w string) string {
+	if len(w) <= minStem || strings.IndexFunc(w, unicode.IsDigit) != -1 {
+		return w
+	}
+	for _, s := range stemSuffixes {
+		if strings.HasSuffix(w, s.suffix) && len(w)-len(s.suffix)+len(s.replace) >= minStem {
+			w = w[:len(w)-len(s.suffix)] + s.replace
+			break
+		}
+	}
+	if len(w) > minStem && w[len(w)-1] == 'e' {
+		// e.g. "bake" like "bak" from "baking"
+		w = w[:len(w)-1]
+	}
+	n := len(w)
+	if n <= minStem || strings.IndexFunc(w, func(r rune) bool { return r > unicode.MaxASCII }) != -1 {
+		return w
+	}
+	last, prev := rune(w[n-1]), rune(w[n-2])
+	switch {
+	case last == prev && !isVowel(last):
+		// double consonant at the end, e.g. "kipp" from "kippen"
+		w = w[:n-1]
+	case !isVowel(last) && prev == rune(w[n-3]) && isVowel(prev):
+		// double vowel before the last consonant, e.g. "tomaat" like "tomat" from "tomaten"
+		w = w[:n-2] + w[n-1:]
+	}
+	return w
+}
+
+// dedupe returns the strings in xs without duplicates, in their original order.
+func dedupe(xs []string) []string {
+	seen := map[string]bool{}
+	var ys []string
+	for _, x := range xs {
+		if !seen[x] {
+			seen[x] = true
+			ys = append(ys, x)
+		}
+	}
+	return ys
+}
@@ -0,0 +1,146 @@
+package gocookbook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	cases := [][]string{
+		{"tomaat", "tomaten", "tomaatjes"},
+		{"kip", "kippen"},
+		{"aardappel", "aardappels", "aardappelen"},
+		{"boon", "bonen"},
+		{"onion", "onions"},
+		{"berry", "berries"},
+		{"bake", "baking"},
+		{"cheese", "cheeses"},
+	}
+	for _, c := range cases {
+		want := stem(c[0])
+		for _, w := range c[1:] {
+			if got := stem(w); got != want {
+				t.Errorf("Stem of %v failed. Want: %v (like %v), Got: %v", w, want, c[0], got)
+			}
+		}
+	}
+	// Short words and numbers are not stemmed
+	for _, w := range []string{"ui", "ei", "oven", "250"} {
+		if got := stem(w); got != w {
+			t.Errorf("Want %v unchanged, Got: %v", w, got)
+		}
+	}
+}
+
+// searchCookbook returns a Cookbook to test searching.
+func searchCookbook() Cookbook {
+	return Cookbook{
+		{Id: 10, Name: "Crème brûlée", Tags: []string{"Dessert"}, Ingrs: []Ingredient{
+			{Amount: 500, Unit: ml, Item: "slagroom"}, {Amount: 4, Item: "eidooiers"}},
+			Steps: []string{"Verwarm de oven voor op 150 graden.", "Karamelliseer de suiker met een brander."}},
+		{Id: 20, Name: "Tomatensoep", Tags: []string{"Soep", "Vegetarisch"}, Ingrs: []Ingredient{
+			{Amount: 1000, Unit: gram, Item: "tomaten"}, {Amount: 1, Item: "ui"}},
+			Steps: []string{"Snijd de ui en fruit deze in olie.", "Voeg de tomaten toe en laat 20 minuten koken."}},
+		{Id: 30, Name: "Pasta met kip", Tags: []string{"Pasta"}, Ingrs: []Ingredient{
+			{Amount: 2, Item: "kipfilets"}, {Amount: 250, Unit: gram, Item: "tomaat", Notes: "in blokjes"}},
+			Notes: "Lekker met crème fraîche.",
+			Steps: []string{"Kook de pasta.", "Bak de kip en voeg de tomaat toe."}},
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := NewIndex(searchCookbook())
+	cases := []struct {
+		query string
+		want  []int // Ids in order of relevance
+	}{
+		{"creme", []int{10, 30}},      // diacritics are folded, name ranks above notes
+		{"tomaten", []int{20, 30}},    // plural finds singular
+		{"TOMAAT kip", []int{30}},     // all words must match
+		{"kip", []int{30}},            // also matches kipfilets
+		{"oven suiker", []int{10}},    // words in steps
+		{"vegetarisch", []int{20}},    // tags
+		{"de en met", nil},            // only stop words
+		{"lasagne", nil},              // unknown word
+		{"soep", []int{20}},           // prefix of tomatensoep does not match, tag does
+		{"brûlée dessert", []int{10}}, // name and tag
+		{"tomatensoep vegetarisch", []int{20}},
+	}
+	for _, c := range cases {
+		results := ix.Search(c.query)
+		var got []int
+		for _, r := range results {
+			got = append(got, r.Recipe.Id)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("Search %q failed. Want: %v, Got: %v", c.query, c.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("Search %q failed. Want: %v, Got: %v", c.query, c.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestIndexSnippet(t *testing.T) {
+	ix := NewIndex(searchCookbook())
+	results := ix.Search("tomaat")
+	if len(results) != 2 {
+		t.Fatalf("Want 2 results, Got: %v", len(results))
+	}
+	var b strings.Builder
+	for _, p := range results[1].Snippet {
+		if p.Match {
+			b.WriteString("[" + p.Text + "]")
+		} else {
+			b.WriteString(p.Text)
+		}
+	}
+	if want := "[tomaat] in blokjes"; results[1].Field != "ingrediënten" || b.String() != want {
+		t.Errorf("Want snippet %q in ingrediënten, Got: %q in %v", want, b.String(), results[1].Field)
+	}
+	// Long texts are shortened around the first match
+	parts := snippetOf("een twee drie vier vijf zes zeven acht negen tien elf twaalf dertien veertien vijftien "+
+		"zestien zeventien achttien negentien twintig", map[string]bool{stem("tien"): true})
+	var got strings.Builder
+	for _, p := range parts {
+		got.WriteString(p.Text)
+	}
+	if want := "… vier vijf zes zeven acht negen tien elf twaalf dertien veertien vijftien zestien zeventien achttien " +
+		"negentien twintig"; got.String() != want {
+		t.Errorf("Want: %q, Got: %q", want, got.String())
+	}
+}
+
+func TestIndexUpdate(t *testing.T) {
+	cb := searchCookbook()
+	ix := NewIndex(cb)
+	// Edit: the recipe is no longer found with its old text
+	r := cb[1]
+	r.Name, r.Tags = "Gazpacho", []string{"Soep"}
+	ix.Add(r)
+	if got := ix.Search("tomatensoep"); len(got) != 0 {
+		t.Errorf("Want no results for old name, Got: %v", len(got))
+	}
+	if got := ix.Search("gazpacho"); len(got) != 1 || got[0].Recipe.Name != "Gazpacho" {
+		t.Errorf("Want Gazpacho, Got: %+v", got)
+	}
+	// Add and remove
+	ix.Add(Recipe{Id: 40, Name: "Lasagne"})
+	if got := ix.Search("lasagne"); len(got) != 1 || ix.Len() != 4 {
+		t.Errorf("Want new recipe to be found, Got: %v (%v recipes)", len(got), ix.Len())
+	}
+	ix.Remove(40)
+	ix.Remove(50)
+	if got := ix.Search("lasagne"); len(got) != 0 || ix.Len() != 3 {
+		t.Errorf("Want removed recipe not to be found, Got: %v (%v recipes)", len(got), ix.Len())
+	}
+	for term, posting := range ix.postings {
+		if _, ok := posting[40]; ok {
+			t.Errorf("Term %v still refers to removed recipe", term)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package gocookbook
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	reStepMarker   = regexp.MustCompile(`(?i)^(?:(?:stap|step)\s*\d{1,2}\s*[.):\-]?|\d{1,2}\s*[.):](?:\s|$)|[-*•·–—▪►✓])\s*`) // Numbering or bullet in front of a step, e.g. "1.", "Stap 2:" or "•".
+	reInlineMarker = regexp.MustCompile(`(?i)(?:^|\s)(?:(?:stap|step)\s*)?(\d{1,2})\s*[.):]\s+`)                              // Numbering within a paragraph, e.g. "... warm. 2. Meng ...".
+	reNoteMarker   = regexp.MustCompile(`(?i)^(?:tip|tips|note|notes|notitie|opmerking|let op)\s*[:!]`)                       // Start of a note, e.g. "Tip:".
+)
+
+// TextToSteps takes a string containing the steps of a recipe and returns a slice with each step and the notes found
+// at the end of the text. Numbering and bullets in front of the steps are removed, lines that are wrapped within a
+// step are joined and paragraphs containing multiple numbered steps are split. Trailing lines starting with e.g.
+// "Tip:" or "Note:" are returned as notes, separated by a new line.
+func TextToSteps(s string) ([]string, string) {
+	paragraphs := textToParagraphs(s)
+	steps := []string{}
+	for _, p := range paragraphs {
+		for _, step := range splitNumbered(p) {
+			step = strings.TrimSpace(reStepMarker.ReplaceAllString(step, ""))
+			if step != "" {
+				steps = append(steps, step)
+			}
+		}
+	}
+	// Move trailing notes from the steps into the notes
+	n := len(steps)
+	for n > 0 && reNoteMarker.MatchString(steps[n-1]) {
+		n--
+	}
+	notes := strings.Join(steps[n:], "\n")
+	return steps[:n], notes
+}
+
+// textToParagraphs takes a string and returns the paragraphs in it. A paragraph ends at an empty line, at the end of
+// a sentence or before a line that starts with numbering, a bullet or a note. If the text uses numbering or bullets,
+// all other lines are regarded as a continuation of the previous paragraph.
+func textToParagraphs(s string) []string {
+	lines := strings.Split(normalizeText(s), "\n")
+	markers := false
+	for _, line := range lines {
+		if reStepMarker.MatchString(strings.TrimSpace(line)) {
+			markers = true
+			break
+		}
+	}
+	paragraphs := []string{}
+	newParagraph := true
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		switch {
+		case line == "":
+			newParagraph = true
+			continue
+		case newParagraph, reStepMarker.MatchString(line), reNoteMarker.MatchString(line):
+			paragraphs = append(paragraphs, line)
+		case markers, !endOfSentence(paragraphs[len(paragraphs)-1]), startsLower(line):
+			paragraphs[len(paragraphs)-1] += " " + line
+		default:
+			paragraphs = append(paragraphs, line)
+		}
+		newParagraph = false
+	}
+	return paragraphs
+}
+
+// splitNumbered takes a paragraph and splits it into steps if it contains consecutively numbered steps, e.g.
+// "1. Verwarm de oven. 2. Meng de bloem." It returns the paragraph as is if it does not contain at least two numbered
+// steps.
+func splitNumbered(p string) []string {
+	var cuts []int
+	next := 0
+	for _, m := range reInlineMarker.FindAllStringSubmatchIndex(p, -1) {
+		n, _ := strconv.Atoi(p[m[2]:m[3]])
+		switch {
+		case len(cuts) == 0 && (n == 1 || m[0] == 0):
+			cuts = append(cuts, m[0])
+		case len(cuts) > 0 && n == next:
+			cuts = append(cuts, m[0])
+		default:
+			continue
+		}
+		next = n + 1
+	}
+	if len(cuts) < 2 {
+		return []string{p}
+	}
+	xs := []string{}
+	if pre := strings.TrimSpace(p[:cuts[0]]); pre != "" {
+		xs = append(xs, pre)
+	}
+	for i, c := range cuts {
+		end := len(p)
+		if i+1 < len(cuts) {
+			end = cuts[i+1]
+		}
+		xs = append(xs, strings.TrimSpace(p[c:end]))
+	}
+	return xs
+}
+
+// endOfSentence returns true if s ends with a punctuation mark that ends a sentence.
+func endOfSentence(s string) bool {
+	return strings.HasSuffix(s, ".") || strings.HasSuffix(s, "!") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, ":")
+}
+
+// startsLower returns true if s starts with a lowercase letter.
+func startsLower(s string) bool {
+	for _, r := range s {
+		return unicode.IsLower(r)
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package gocookbook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTextToSteps(t *testing.T) {
+	cases := []struct {
+		s     string
+		steps []string
+		notes string
+	}{
+		{"Verwarm de oven.\nMeng de bloem.", []string{"Verwarm de oven.", "Meng de bloem."}, ""},
+		{"1. Verwarm de oven.\n2) Meng de bloem.", []string{"Verwarm de oven.", "Meng de bloem."}, ""},
+		{"Stap 1: Verwarm de oven.\r\nStap 2: Meng de bloem.", []string{"Verwarm de oven.", "Meng de bloem."}, ""},
+		{"• Verwarm de oven.\n- Meng de bloem.\n* Bak 20 minuten.", []string{"Verwarm de oven.", "Meng de bloem.", "Bak 20 minuten."}, ""},
+		{"Verwarm de oven op 180 graden en\nvet de bakvorm in.\n\nMeng de bloem.", []string{"Verwarm de oven op 180 graden en vet de bakvorm in.", "Meng de bloem."}, ""},
+		{"1. Verwarm de oven.\nVet de bakvorm in.\n2. Meng de bloem.", []string{"Verwarm de oven. Vet de bakvorm in.", "Meng de bloem."}, ""},
+		{"1. Verwarm de oven. 2. Meng de bloem. 3. Bak 20 minuten.", []string{"Verwarm de oven.", "Meng de bloem.", "Bak 20 minuten."}, ""},
+		{"Bak 2. Daarna 5 minuten rusten.", []string{"Bak 2. Daarna 5 minuten rusten."}, ""},
+		{"Voeg 2,5 dl melk toe.", []string{"Voeg 2,5 dl melk toe."}, ""},
+		{"Meng de bloem.\nTip: gebruik koude boter.\nNote: keeps for 3 days.", []string{"Meng de bloem."}, "Tip: gebruik koude boter.\nNote: keeps for 3 days."},
+		{"Tip: lees eerst het recept.\nMeng de bloem.", []string{"Tip: lees eerst het recept.", "Meng de bloem."}, ""},
+		{"\n\n", []string{}, ""},
+	}
+	for i, c := range cases {
+		steps, notes := TextToSteps(c.s)
+		if !reflect.DeepEqual(steps, c.steps) || notes != c.notes {
+			t.Errorf("Case %v failed for '%v'.\nGot:\t%q, %q\nWant:\t%q, %q", i, c.s, steps, notes, c.steps, c.notes)
+		}
+	}
+}
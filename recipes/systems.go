@@ -0,0 +1,74 @@
+package gocookbook
+
+import (
+	"fmt"
+)
+
+// AsWritten is used as preferred System to display ingredients in the units they are written in.
+const AsWritten = System("origineel")
+
+// Systems contains all systems that can be selected by a user as preferred system.
+var Systems = []System{AsWritten, Metric, USCust, Imperial}
+
+// steps contains per System and Dimension the units that are used when converting, from large to small, and the
+// minimum amount for each unit. The last unit is used for all smaller amounts.
+var steps = map[System]map[Dimension][]struct {
+	u   Unit
+	min float64
+}{
+	Metric: {
+		Mass:   {{kilo, 1}, {gram, 0}},
+		Volume: {{liter, 1}, {ml, 0}},
+	},
+	USCust: {
+		Mass:   {{lb, 1}, {oz, 0}},
+		Volume: {{cup, 0.25}, {tbsp, 1}, {tsp, 0}},
+	},
+	Imperial: {
+		Mass:   {{lb, 1}, {oz, 0}},
+		Volume: {{pintUK, 1}, {flOzUK, 1}, {tbsp, 1}, {tsp, 0}},
+	},
+}
+
+// ConvertTo takes a System and returns the Ingredient with the amount converted to the preferred unit of that System.
+// The amount as written is kept in Written, so it can be displayed as the alternative unit. Ingredients with a unit
+// that is used in all systems (e.g. pieces or spoons), that already belongs to the System or that cannot be converted
+// are returned as is. US and imperial share the same units for mass.
+func (i Ingredient) ConvertTo(s System) Ingredient {
+	d, ok := Registry.Get(i.Unit)
+	if !ok || d.System == "" || d.System == s {
+		return i
+	}
+	xs, ok := steps[s][d.Dim]
+	if !ok {
+		return i
+	}
+	for _, v := range xs {
+		f, err := Registry.Convert(i.Amount, i.Unit, v.u)
+		if err != nil || f < v.min {
+			continue
+		}
+		if v.u == i.Unit {
+			return i
+		}
+		c := i
+		c.Written = fmt.Sprintf("%v %v", i.printAmount(FormatKitchen), i.Unit)
+		c.Amount, c.Unit = f, v.u
+		c.AmountMax, _ = Registry.Convert(i.AmountMax, i.Unit, v.u)
+		return c
+	}
+	return i
+}
+
+// ConvertTo takes a System and returns the Recipe with all Ingredients converted to the preferred units of that System.
+func (r Recipe) ConvertTo(s System) Recipe {
+	if s == AsWritten || s == "" {
+		return r
+	}
+	ingrs := make([]Ingredient, len(r.Ingrs))
+	for i, v := range r.Ingrs {
+		ingrs[i] = v.ConvertTo(s)
+	}
+	r.Ingrs = ingrs
+	return r
+}
@@ -0,0 +1,49 @@
+package gocookbook
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestConvertToSystem(t *testing.T) {
+	cases := []struct {
+		in     Ingredient
+		s      System
+		amount float64
+		unit   Unit
+	}{
+		{Ingredient{Amount: 1, Unit: cup, Item: "melk"}, Metric, cuptoMl, ml},
+		{Ingredient{Amount: 8, Unit: cup, Item: "melk"}, Metric, 8 * cuptoMl / 1000, liter},
+		{Ingredient{Amount: 1, Unit: lb, Item: "boter"}, Metric, 453.59237, gram},
+		{Ingredient{Amount: 500, Unit: gram, Item: "bloem"}, USCust, 1.1023113, lb},
+		{Ingredient{Amount: 100, Unit: gram, Item: "suiker"}, USCust, 3.5273962, oz},
+		{Ingredient{Amount: 250, Unit: ml, Item: "melk"}, USCust, 250 / cuptoMl, cup},
+		{Ingredient{Amount: 30, Unit: ml, Item: "olie"}, USCust, 30 / tbspToMl, tbsp},
+		{Ingredient{Amount: 600, Unit: ml, Item: "bouillon"}, Imperial, 600 / 568.26125, pintUK},
+		{Ingredient{Amount: 2, Unit: lb, Item: "boter"}, Imperial, 2, lb},
+		{Ingredient{Amount: 2, Unit: tbsp, Item: "olie"}, Metric, 2, tbsp},
+		{Ingredient{Amount: 2, Unit: pcs, Item: "eieren"}, USCust, 2, pcs},
+		{Ingredient{Amount: 1, Unit: cup, Item: "melk"}, AsWritten, 1, cup},
+	}
+	for i, c := range cases {
+		got := Recipe{Ingrs: []Ingredient{c.in}}.ConvertTo(c.s).Ingrs[0]
+		if math.Abs(got.Amount-c.amount) > 1e-6 || got.Unit != c.unit {
+			t.Errorf("Case %v failed. Want: %v %v, Got: %v %v", i, c.amount, c.unit, got.Amount, got.Unit)
+		}
+	}
+	t.Run("print as written", func(t *testing.T) {
+		in := Ingredient{Amount: 1, Unit: cup, Item: "melk"}.ConvertTo(Metric)
+		if want, got := "237 ml melk (1 cup)", in.Print(); got != want {
+			t.Errorf("Want: '%v', Got: '%v'", want, got)
+		}
+	})
+	t.Run("written is not stored", func(t *testing.T) {
+		in := Ingredient{Amount: 1, Unit: cup, Item: "melk"}.ConvertTo(Metric)
+		b, err := json.Marshal(in)
+		if err != nil || strings.Contains(string(b), "Written") {
+			t.Errorf("Want JSON without Written, Got: %s (%v)", b, err)
+		}
+	})
+}
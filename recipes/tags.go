@@ -0,0 +1,292 @@
+package gocookbook
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Tag represents the settings of a tag that is used on recipes.
+type Tag struct {
+	Parent string `json:",omitempty"` // Name of the parent tag, e.g. "Diner" for "Pasta", or empty for a top level tag.
+	Color  string `json:",omitempty"` // Colour to display the tag in, as "#rrggbb", or empty for the default colour.
+}
+
+// TagTable contains the settings of tags by name. A tag that is used on recipes but is not in the TagTable is a top
+// level tag without colour. A tag in the TagTable does not have to be used on any recipe, e.g. a parent tag that only
+// groups other tags.
+type TagTable map[string]Tag
+
+// TagCount represents a tag with the number of recipes it is used on.
+type TagCount struct {
+	Name  string // Name of the tag.
+	Tag          // Settings of the tag.
+	Depth int    // Level of the tag in the hierarchy, 0 for a top level tag.
+	Count int    // Number of recipes with the tag itself.
+	Total int    // Number of recipes with the tag or one of its descendants.
+}
+
+// TagTree contains the settings of all tags. It is used to include the descendants of a tag when filtering recipes
+// on that tag, e.g. "tag:diner" also finds recipes with the tag "Pasta" if "Diner" is the parent of "Pasta".
+var TagTree = TagTable{}
+
+// TagsSchema is the Schema of tags.json.
+var TagsSchema = Schema{Kind: "tags"}
+
+var (
+	errorTagName  = errors.New("tag name is empty")                          // Tag without name.
+	errorTagColor = errors.New("colour must be written as #rrggbb")          // Colour is not a hexadecimal RGB value.
+	errorTagCycle = errors.New("tag cannot be a descendant of itself")       // Parent would create a loop.
+	errorTagMerge = errors.New("no tags selected to merge into another tag") // Nothing to rename or merge.
+)
+
+// reTagColor matches a colour as "#rrggbb".
+var reTagColor = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// Color returns the colour of the tag with the given name, or an empty string if it has no colour.
+func (t TagTable) Color(name string) string {
+	return t[name].Color
+}
+
+// Ancestors returns the parent of the tag with the given name, the parent of that parent and so on, up to the top
+// level tag.
+func (t TagTable) Ancestors(name string) []string {
+	var xs []string
+	seen := map[string]bool{name: true}
+	for p := t[name].Parent; p != "" && !seen[p]; p = t[p].Parent {
+		xs = append(xs, p)
+		seen[p] = true
+	}
+	return xs
+}
+
+// WithAncestors takes a list of tags and returns them together with all their ancestors, sorted and without
+// duplicates.
+func (t TagTable) WithAncestors(tags []string) []string {
+	list := map[string]bool{}
+	for _, tag := range tags {
+		list[tag] = true
+		for _, a := range t.Ancestors(tag) {
+			list[a] = true
+		}
+	}
+	xs := []string{}
+	for tag := range list {
+		xs = append(xs, tag)
+	}
+	sort.Strings(xs)
+	return xs
+}
+
+// is returns true if the tag with the given name, or one of its ancestors, is the tag v. Tags are compared without
+// case and diacritics; v must be folded.
+func (t TagTable) is(name, v string) bool {
+	if fold(name) == v {
+		return true
+	}
+	for _, a := range t.Ancestors(name) {
+		if fold(a) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// List takes a Cookbook and returns all tags that are used on its recipes or are in the TagTable, with the number of
+// recipes for each tag. Tags are ordered as a tree: each tag is followed by its children, and tags with the same
+// parent are sorted by name.
+func (t TagTable) List(cb Cookbook) []TagCount {
+	counts := map[string]*TagCount{}
+	get := func(name string) *TagCount {
+		if c, ok := counts[name]; ok {
+			return c
+		}
+		c := &TagCount{Name: name, Tag: t[name]}
+		counts[name] = c
+		return c
+	}
+	for name, tag := range t {
+		get(name)
+		if tag.Parent != "" {
+			get(tag.Parent)
+		}
+	}
+	for _, r := range cb {
+		for _, tag := range r.Tags {
+			get(tag).Count++
+		}
+		for _, tag := range t.WithAncestors(r.Tags) {
+			get(tag).Total++
+		}
+	}
+	children := map[string][]string{}
+	for name, c := range counts {
+		children[c.Parent] = append(children[c.Parent], name)
+	}
+	var list []TagCount
+	var walk func(parent string, depth int)
+	walk = func(parent string, depth int) {
+		names := children[parent]
+		sort.Strings(names)
+		for _, name := range names {
+			c := counts[name]
+			c.Depth = depth
+			list = append(list, *c)
+			walk(name, depth+1)
+		}
+	}
+	walk("", 0)
+	if len(list) < len(counts) {
+		// tags in a loop of parents, which Set and Merge do not create, are listed at the top level
+		listed := map[string]bool{}
+		for _, c := range list {
+			listed[c.Name] = true
+		}
+		var names []string
+		for name := range counts {
+			if !listed[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			list = append(list, *counts[name])
+		}
+	}
+	return list
+}
+
+// Set takes the name of a tag and its settings and returns a copy of the TagTable with the new settings. The colour
+// is stored in lowercase. It returns an error if the colour is invalid or the parent is the tag itself or one of its
+// descendants.
+func (t TagTable) Set(name string, tag Tag) (TagTable, error) {
+	name, tag.Parent, tag.Color = strings.TrimSpace(name), strings.TrimSpace(tag.Parent), strings.ToLower(tag.Color)
+	switch {
+	case name == "":
+		return nil, errorTagName
+	case tag.Color != "" && !reTagColor.MatchString(tag.Color):
+		return nil, fmt.Errorf("%w: %v", errorTagColor, tag.Color)
+	case tag.Parent == name:
+		return nil, fmt.Errorf("%w: %v", errorTagCycle, name)
+	}
+	for _, a := range t.Ancestors(tag.Parent) {
+		if a == name {
+			return nil, fmt.Errorf("%w: %v", errorTagCycle, name)
+		}
+	}
+	nt := t.copy()
+	if tag == (Tag{}) {
+		delete(nt, name)
+	} else {
+		nt[name] = tag
+	}
+	return nt, nil
+}
+
+// Merge takes a Cookbook, the names of one or more tags and the name of the tag to merge them into, and returns a
+// copy of the Cookbook and of the TagTable in which the tags are replaced by the new tag. Renaming a tag is merging it
+// into a new name. The new tag keeps its own settings if it already exists, otherwise it gets the settings of the
+// first merged tag that has them. Children of the merged tags become children of the new tag. Only the recipes with
+// a merged tag are changed; neither the Cookbook nor the TagTable is modified, so the caller can store both at once.
+func (t TagTable) Merge(cb Cookbook, from []string, to string) (Cookbook, TagTable, error) {
+	to = strings.TrimSpace(to)
+	if to == "" {
+		return nil, nil, errorTagName
+	}
+	merged := map[string]bool{}
+	for _, name := range from {
+		if name = strings.TrimSpace(name); name != "" && name != to {
+			merged[name] = true
+		}
+	}
+	if len(merged) == 0 {
+		return nil, nil, errorTagMerge
+	}
+	// recipes
+	ncb := make(Cookbook, len(cb))
+	for i, r := range cb {
+		ncb[i] = r
+		changed := false
+		list := map[string]bool{}
+		for _, tag := range r.Tags {
+			if merged[tag] {
+				tag, changed = to, true
+			}
+			list[tag] = true
+		}
+		if !changed {
+			continue
+		}
+		tags := []string{}
+		for tag := range list {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		ncb[i].Tags = tags
+	}
+	// settings
+	nt := t.copy()
+	_, exists := nt[to]
+	for _, name := range from {
+		if tag, ok := nt[name]; ok && merged[name] && !exists {
+			nt[to], exists = tag, true
+		}
+	}
+	for name := range merged {
+		delete(nt, name)
+	}
+	for name, tag := range nt {
+		if merged[tag.Parent] {
+			tag.Parent = to
+			nt[name] = tag
+		}
+	}
+	if tag := nt[to]; tag.Parent == to {
+		// the new tag was a child of a merged tag, e.g. when merging "Diner" into its child "Pasta"
+		tag.Parent = t[t[to].Parent].Parent
+		if merged[tag.Parent] || tag.Parent == to {
+			tag.Parent = ""
+		}
+		nt[to] = tag
+	}
+	nt.breakCycles()
+	return ncb, nt, nil
+}
+
+// breakCycles removes the parent of tags that are their own ancestor, and the settings of tags without parent and
+// colour.
+func (t TagTable) breakCycles() {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		seen := map[string]bool{}
+		for p := t[name].Parent; p != "" && !seen[p]; p = t[p].Parent {
+			if p == name {
+				tag := t[name]
+				tag.Parent = ""
+				t[name] = tag
+				break
+			}
+			seen[p] = true
+		}
+	}
+	for name, tag := range t {
+		if tag == (Tag{}) {
+			delete(t, name)
+		}
+	}
+}
+
+// copy returns a copy of the TagTable.
+func (t TagTable) copy() TagTable {
+	nt := make(TagTable, len(t))
+	for k, v := range t {
+		nt[k] = v
+	}
+	return nt
+}
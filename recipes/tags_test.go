@@ -0,0 +1,143 @@
+package gocookbook
+
+import (
+	"errors"
+	"testing"
+)
+
+// tagCookbook returns a Cookbook and TagTable to test tags.
+func tagCookbook() (Cookbook, TagTable) {
+	cb := Cookbook{
+		{Id: 10, Name: "Lasagne", Tags: []string{"Pasta", "Vega"}},
+		{Id: 20, Name: "Spaghetti", Tags: []string{"Pasta"}},
+		{Id: 30, Name: "Tomatensoep", Tags: []string{"Soep", "Vegetarisch"}},
+		{Id: 40, Name: "Stamppot", Tags: []string{"Vegetarisch"}},
+	}
+	t := TagTable{"Pasta": {Parent: "Diner", Color: "#ff0000"}, "Soep": {Parent: "Diner"}, "Vega": {Color: "#00ff00"}}
+	return cb, t
+}
+
+func TestTagList(t *testing.T) {
+	cb, tt := tagCookbook()
+	want := []TagCount{
+		{Name: "Diner", Depth: 0, Count: 0, Total: 3},
+		{Name: "Pasta", Tag: Tag{"Diner", "#ff0000"}, Depth: 1, Count: 2, Total: 2},
+		{Name: "Soep", Tag: Tag{Parent: "Diner"}, Depth: 1, Count: 1, Total: 1},
+		{Name: "Vega", Tag: Tag{Color: "#00ff00"}, Count: 1, Total: 1},
+		{Name: "Vegetarisch", Count: 2, Total: 2},
+	}
+	got := tt.List(cb)
+	if len(got) != len(want) {
+		t.Fatalf("Want: %+v, Got: %+v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Tag %v failed. Want: %+v, Got: %+v", i, want[i], got[i])
+		}
+	}
+	// Tags in a loop are still listed
+	tt = TagTable{"A": {Parent: "B"}, "B": {Parent: "A"}}
+	if got := tt.List(nil); len(got) != 2 {
+		t.Errorf("Want 2 tags, Got: %+v", got)
+	}
+}
+
+func TestTagSet(t *testing.T) {
+	_, tt := tagCookbook()
+	cases := []struct {
+		name string
+		tag  Tag
+		err  error
+	}{
+		{"Diner", Tag{Color: "#0000FF"}, nil},
+		{"Lasagne", Tag{Parent: "Pasta"}, nil},
+		{"Vega", Tag{}, nil},
+		{"", Tag{}, errorTagName},
+		{"Soep", Tag{Color: "blauw"}, errorTagColor},
+		{"Soep", Tag{Parent: "Soep"}, errorTagCycle},
+		{"Diner", Tag{Parent: "Pasta"}, errorTagCycle},
+	}
+	for _, c := range cases {
+		got, err := tt.Set(c.name, c.tag)
+		if !errors.Is(err, c.err) {
+			t.Errorf("Set %v to %+v failed. Want error: %v, Got: %v", c.name, c.tag, c.err, err)
+			continue
+		}
+		if err == nil && got[c.name].Parent != c.tag.Parent {
+			t.Errorf("Set %v failed. Want parent: %v, Got: %+v", c.name, c.tag.Parent, got[c.name])
+		}
+	}
+	got, _ := tt.Set("Diner", Tag{Color: "#0000FF"})
+	if got["Diner"].Color != "#0000ff" || tt["Diner"].Color != "" {
+		t.Errorf("Want colour in lowercase on a copy, Got: %+v and %+v", got["Diner"], tt["Diner"])
+	}
+	if got, _ := tt.Set("Vega", Tag{}); len(got) != 2 {
+		t.Errorf("Want tag without settings removed, Got: %+v", got)
+	}
+}
+
+func TestTagMerge(t *testing.T) {
+	cb, tt := tagCookbook()
+	ncb, nt, err := tt.Merge(cb, []string{"Vega", "Vegetarisch"}, "Vegetarisch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range [][]string{{"Pasta", "Vegetarisch"}, {"Pasta"}, {"Soep", "Vegetarisch"}, {"Vegetarisch"}} {
+		if !equalStrings(ncb[i].Tags, want) {
+			t.Errorf("Recipe %v failed. Want: %v, Got: %v", ncb[i].Name, want, ncb[i].Tags)
+		}
+	}
+	if !equalStrings(cb[0].Tags, []string{"Pasta", "Vega"}) {
+		t.Errorf("Want original recipe unchanged, Got: %v", cb[0].Tags)
+	}
+	if _, ok := nt["Vega"]; ok || nt["Vegetarisch"].Color != "#00ff00" || tt["Vega"].Color == "" {
+		t.Errorf("Want colour of Vega moved to Vegetarisch on a copy, Got: %+v", nt)
+	}
+	// Rename a parent
+	_, nt, _ = tt.Merge(cb, []string{"Diner"}, "Hoofdgerecht")
+	if nt["Pasta"].Parent != "Hoofdgerecht" || nt["Soep"].Parent != "Hoofdgerecht" {
+		t.Errorf("Want children moved to Hoofdgerecht, Got: %+v", nt)
+	}
+	// Merge a parent into its child
+	_, nt, _ = tt.Merge(cb, []string{"Diner"}, "Pasta")
+	if nt["Pasta"].Parent != "" || nt["Soep"].Parent != "Pasta" {
+		t.Errorf("Want Pasta top level with child Soep, Got: %+v", nt)
+	}
+	for _, from := range [][]string{nil, {"Pasta"}, {" "}} {
+		if _, _, err := tt.Merge(cb, from, "Pasta"); !errors.Is(err, errorTagMerge) {
+			t.Errorf("Merge %q failed. Want error: %v, Got: %v", from, errorTagMerge, err)
+		}
+	}
+	if _, _, err := tt.Merge(cb, []string{"Pasta"}, ""); !errors.Is(err, errorTagName) {
+		t.Errorf("Want error: %v, Got: %v", errorTagName, err)
+	}
+}
+
+func TestQueryTagHierarchy(t *testing.T) {
+	cb, tt := tagCookbook()
+	defer func(old TagTable) { TagTree = old }(TagTree)
+	TagTree = tt
+	got, err := cb.Query("tag:diner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameIds(got, []int{10, 20, 30}) {
+		t.Errorf("Want recipes with child tags of Diner, Got: %v", ids(got))
+	}
+	if got, _ := cb.Query("tag:pasta"); !sameIds(got, []int{10, 20}) {
+		t.Errorf("Want recipes with Pasta only, Got: %v", ids(got))
+	}
+}
+
+// equalStrings returns true if xs and ys contain the same strings in the same order.
+func equalStrings(xs, ys []string) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if xs[i] != ys[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package gocookbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// tandoorRecipe represents recipe.json in a Tandoor export.
+type tandoorRecipe struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Keywords    []mealieName  `json:"keywords"`
+	Steps       []tandoorStep `json:"steps"`
+	WorkingTime int           `json:"working_time"`
+	WaitingTime int           `json:"waiting_time"`
+	Internal    bool          `json:"internal"`
+	Servings    float64       `json:"servings"`
+	SourceURL   string        `json:"source_url"`
+}
+
+// tandoorStep represents a step of a recipe in a Tandoor export.
+type tandoorStep struct {
+	Name        string              `json:"name"`
+	Instruction string              `json:"instruction"`
+	Ingredients []tandoorIngredient `json:"ingredients"`
+	Order       int                 `json:"order"`
+}
+
+// tandoorIngredient represents an ingredient of a step in a Tandoor export.
+type tandoorIngredient struct {
+	Food         *mealieName `json:"food"`
+	Unit         *mealieName `json:"unit"`
+	Amount       float64     `json:"amount"`
+	Note         string      `json:"note"`
+	Order        int         `json:"order"`
+	NoAmount     bool        `json:"no_amount"`
+	OriginalText string      `json:"original_text,omitempty"`
+}
+
+// importTandoor takes a Tandoor export, which is a zip file containing a zip file per recipe with recipe.json, and
+// returns the recipes in it. A single recipe.json, or a zip file containing recipe.json, is accepted as well.
+func importTandoor(r io.Reader, ir *ImportReport) ([]Recipe, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := readTandoorObjects(data, 0, ir)
+	if err != nil {
+		return nil, err
+	}
+	rcps := []Recipe{}
+	for _, m := range objs {
+		rcps = append(rcps, tandoorToRecipe(m, ir))
+	}
+	return rcps, nil
+}
+
+// readTandoorObjects takes a JSON file or zip file and returns the objects in all recipe.json files in it. Depth is the
+// number of zip files data is in. Zip files are only read one level deep: the zip file of a recipe in the export is
+// read, but a zip file in that zip file is skipped.
+func readTandoorObjects(data []byte, depth int, ir *ImportReport) ([]map[string]interface{}, error) {
+	if depth > 1 {
+		return decodeJSONObjects(data)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return decodeJSONObjects(data)
+	}
+	xs := []map[string]interface{}{}
+	for _, f := range zr.File {
+		ext := strings.ToLower(path.Ext(f.Name))
+		if ext == ".zip" && depth > 0 || ext != ".zip" && path.Base(f.Name) != "recipe.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := ir.unpack(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", f.Name, err)
+		}
+		objs, err := readTandoorObjects(b, depth+1, ir)
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, objs...)
+	}
+	return xs, nil
+}
+
+// tandoorToRecipe takes a Tandoor recipe and returns it as Recipe.
+func tandoorToRecipe(m map[string]interface{}, ir *ImportReport) Recipe {
+	ir.skipUnknown(m, "name", "description", "keywords", "steps", "working_time", "waiting_time", "internal",
+		"servings", "servings_text", "source_url")
+	name := schemaString(m["name"])
+	rcp := Recipe{
+		Name:       name,
+		Notes:      schemaString(m["description"]),
+		Portions:   schemaYield(m["servings"]),
+		SourceLink: schemaString(m["source_url"]),
+		Tags:       schemaStrings(m["keywords"]),
+	}
+	rcp.Source = rcp.SourceLink
+	for _, k := range []string{"working_time", "waiting_time"} {
+		if f, ok := m[k].(float64); ok {
+			rcp.Dur += time.Duration(f) * time.Minute
+		}
+	}
+	var lines, texts []string
+	for _, s := range toList(m["steps"]) {
+		step, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, v := range toList(step["ingredients"]) {
+			x, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if header, _ := x["is_header"].(bool); header {
+				ir.warn(name, "ingredient header '%v' skipped", schemaString(x["note"]))
+				continue
+			}
+			if noAmount, _ := x["no_amount"].(bool); noAmount {
+				delete(x, "amount")
+				delete(x, "unit")
+			}
+			lines = append(lines, structuredIngredient(x, "amount", "unit", "food", "note", "original_text", ""))
+		}
+		texts = append(texts, schemaString(step["instruction"]))
+	}
+	rcp.Ingrs = ir.ingredients(name, lines)
+	var notes string
+	rcp.Steps, notes = TextToSteps(strings.Join(texts, "\n\n"))
+	rcp.Notes = joinNotes(rcp.Notes, notes)
+	return rcp
+}
+
+// exportTandoor writes the recipes as Tandoor export to w: a zip file containing a zip file per recipe with
+// recipe.json. All ingredients are added to the first step.
+func exportTandoor(w io.Writer, rcps []Recipe) error {
+	zw := zip.NewWriter(w)
+	names := map[string]int{}
+	for _, rcp := range rcps {
+		t := tandoorRecipe{
+			Name:        rcp.Name,
+			Description: rcp.Notes,
+			Keywords:    make([]mealieName, len(rcp.Tags)),
+			WorkingTime: int(rcp.Dur.Minutes()),
+			Internal:    true,
+			Servings:    rcp.Portions,
+			SourceURL:   rcp.SourceLink,
+		}
+		for i, v := range rcp.Tags {
+			t.Keywords[i] = mealieName{v}
+		}
+		steps := rcp.Steps
+		if len(steps) == 0 {
+			steps = []string{""}
+		}
+		for i, v := range steps {
+			t.Steps = append(t.Steps, tandoorStep{Instruction: v, Order: i, Ingredients: []tandoorIngredient{}})
+		}
+		for i, v := range rcp.Ingrs {
+			ti := tandoorIngredient{
+				Food:         &mealieName{v.Item},
+				Amount:       v.Amount,
+				Note:         v.Notes,
+				Order:        i,
+				NoAmount:     v.Amount == 0,
+				OriginalText: v.Text(),
+			}
+			if v.Unit != pcs && v.Unit != "" {
+				ti.Unit = &mealieName{string(v.Unit)}
+			}
+			t.Steps[0].Ingredients = append(t.Steps[0].Ingredients, ti)
+		}
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return err
+		}
+		// zip file per recipe
+		var buf bytes.Buffer
+		rw := zip.NewWriter(&buf)
+		f, err := rw.Create("recipe.json")
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+		if err := rw.Close(); err != nil {
+			return err
+		}
+		f, err = zw.Create(fileName(rcp.Name, names) + ".zip")
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -46,3 +46,59 @@ func TestTextToIngrds(t *testing.T) {
 		}
 	}
 }
+
+func TestTextToIngrdsRanges(t *testing.T) {
+	cases := []struct {
+		s    string
+		want Ingredient
+	}{
+		{"2-3 teentjes knoflook", Ingredient{Amount: 2, AmountMax: 3, Unit: clove, Item: "knoflook"}},
+		{"2 to 3 cups flour", Ingredient{Amount: 2, AmountMax: 3, Unit: cup, Item: "flour"}},
+		{"1 - 1½ el olie", Ingredient{Amount: 1, AmountMax: 1.5, Unit: tbsp, Item: "olie"}},
+		{"ca. 200 g bloem", Ingredient{Amount: 200, Approx: true, Unit: gram, Item: "bloem"}},
+		{"±200 g suiker", Ingredient{Amount: 200, Approx: true, Unit: gram, Item: "suiker"}},
+		{"± 1 kg aardappels, geschild", Ingredient{Amount: 1, Approx: true, Unit: kilo, Item: "aardappels", Notes: "geschild"}},
+		{"about 2-3 kg potatoes", Ingredient{Amount: 2, AmountMax: 3, Approx: true, Unit: kilo, Item: "potatoes"}},
+	}
+	for i, c := range cases {
+		got := TextToIngrds(c.s)
+		if len(got) != 1 {
+			t.Fatalf("Case %v failed. Want 1 ingredient, Got: %v", i, len(got))
+		}
+		g := got[0]
+		if g.Amount != c.want.Amount || g.AmountMax != c.want.AmountMax || g.Approx != c.want.Approx || g.Unit != c.want.Unit || g.Item != c.want.Item || g.Notes != c.want.Notes {
+			t.Errorf("Case %v failed for '%v'.\nGot:\t'%+v'\nWant:\t'%+v'", i, c.s, g, c.want)
+		}
+	}
+}
+
+func TestPrintRanges(t *testing.T) {
+	cases := []struct {
+		in   Ingredient
+		want string
+	}{
+		{Ingredient{Amount: 2, AmountMax: 3, Unit: pcs, Item: "teentjes knoflook"}, "2-3 teentjes knoflook"},
+		{Ingredient{Amount: 0.5, AmountMax: 1, Unit: cup, Item: "melk"}, "½-1 cup melk (118.3-236.6 ml)"},
+		{Ingredient{Amount: 1, AmountMax: 1.5, Unit: kilo, Item: "kiezels"}, "1-1.5 kg kiezels (1000-1500 g)"},
+		{Ingredient{Amount: 200, Approx: true, Unit: gram, Item: "bloem"}, "ca. 200 g bloem"},
+	}
+	for i, c := range cases {
+		if got := c.in.Print(); got != c.want {
+			t.Errorf("Case %v failed. Want: '%v', Got: '%v'", i, c.want, got)
+		}
+	}
+}
+
+func TestGramsMax(t *testing.T) {
+	i := Ingredient{Amount: 1, AmountMax: 1.5, Unit: kilo, Item: "kiezels"}
+	if g, ok := i.Grams(); !ok || g != 1000 {
+		t.Errorf("Want minimum of 1000 g, Got: %v (%v)", g, ok)
+	}
+	if g, ok := i.GramsMax(); !ok || g != 1500 {
+		t.Errorf("Want maximum of 1500 g, Got: %v (%v)", g, ok)
+	}
+	i.AmountMax = 0
+	if g, ok := i.GramsMax(); !ok || g != 1000 {
+		t.Errorf("Want 1000 g without range, Got: %v (%v)", g, ok)
+	}
+}
@@ -0,0 +1,200 @@
+package gocookbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type Dimension string // Dimension represents what a Unit measures, e.g. mass or volume.
+
+// Different dimensions of units. Units can only be converted into units of the same dimension.
+const (
+	Mass   = Dimension("massa")
+	Volume = Dimension("volume")
+	Count  = Dimension("aantal")
+	Custom = Dimension("overig") // Units that cannot be converted, e.g. a pinch or a can.
+)
+
+type System string // System represents a system of measurement.
+
+// Different systems of measurement.
+const (
+	Metric   = System("metrisch")
+	USCust   = System("us")
+	Imperial = System("imperial")
+)
+
+// UnitDef represents the definition of a Unit of Measurement in a UnitRegistry.
+type UnitDef struct {
+	Unit    Unit      // Unit as stored in an Ingredient and shown when printing.
+	Dim     Dimension // Dimension of the unit.
+	Factor  float64   // Grams (mass) or milliliters (volume) for 1 unit. Zero if the unit cannot be converted.
+	System  System    // System the unit belongs to, empty if it is used in all systems.
+	Aliases []string  // Names for the unit in text, in several languages, e.g. "tablespoon" and "eetlepel".
+}
+
+// UnitRegistry contains all known Units of Measurement and their aliases.
+type UnitRegistry struct {
+	defs    map[Unit]UnitDef // Definition per unit.
+	aliases map[string]Unit  // Unit per lowercase alias.
+}
+
+// Additional units next to the units used throughout the package.
+const (
+	mg     = Unit("mg")
+	oz     = Unit("oz")
+	lb     = Unit("lb")
+	cl     = Unit("cl")
+	dl     = Unit("dl")
+	flOz   = Unit("fl oz")
+	flOzUK = Unit("fl oz (uk)")
+	cupM   = Unit("cup (metrisch)")
+	pint   = Unit("pint")
+	pintUK = Unit("pint (uk)")
+	quart  = Unit("quart")
+	clove  = Unit("teen")
+	pinch  = Unit("snufje")
+	can    = Unit("blik")
+	bunch  = Unit("bos")
+)
+
+var (
+	tbspToMl = 14.7867648 // ml for 1 tablespoon.
+	tspToMl  = 4.92892159 // ml for 1 teaspoon.
+	cuptoMl  = 236.588237 // ml for 1 cup.
+)
+
+// defaultUnits contains the units every UnitRegistry created by NewUnitRegistry starts with.
+var defaultUnits = []UnitDef{
+	{mg, Mass, 0.001, Metric, []string{"milligram", "milligrams", "milligramme"}},
+	{gram, Mass, 1, Metric, []string{"gram", "grams", "gr", "gramm", "gramme", "grammes"}},
+	{kilo, Mass, 1000, Metric, []string{"kilo", "kilos", "kilogram", "kilograms", "kilogramm", "kilogramme"}},
+	{oz, Mass, 28.3495231, USCust, []string{"ounce", "ounces", "unze"}},
+	{lb, Mass, 453.59237, USCust, []string{"lbs", "pound", "pounds"}},
+	{ml, Volume, 1, Metric, []string{"milliliter", "milliliters", "millilitre", "millilitres"}},
+	{cl, Volume, 10, Metric, []string{"centiliter", "centiliters", "centilitre"}},
+	{dl, Volume, 100, Metric, []string{"deciliter", "deciliters", "decilitre"}},
+	{liter, Volume, 1000, Metric, []string{"liter", "liters", "litre", "litres", "ltr"}},
+	{tsp, Volume, tspToMl, "", []string{"tsp", "teaspoon", "teaspoons", "theelepel", "theelepels", "tl"}},
+	{tbsp, Volume, tbspToMl, "", []string{"tbsp", "tbs", "tablespoon", "tablespoons", "eetlepel", "eetlepels", "el", "esslöffel"}},
+	{flOz, Volume, 29.5735296, USCust, []string{"fluid ounce", "fluid ounces", "fl. oz"}},
+	{flOzUK, Volume, 28.4130625, Imperial, []string{"uk fl oz", "imperial fl oz"}},
+	{cup, Volume, cuptoMl, USCust, []string{"cups", "us cup", "us cups"}},
+	{cupM, Volume, 250, Metric, []string{"metric cup", "metric cups", "kop", "kopje", "kopjes"}},
+	{pint, Volume, 473.176473, USCust, []string{"pints", "us pint"}},
+	{pintUK, Volume, 568.26125, Imperial, []string{"uk pint", "imperial pint"}},
+	{quart, Volume, 946.352946, USCust, []string{"quarts", "qt"}},
+	{pcs, Count, 1, "", []string{"stuk", "pieces", "piece", "pcs", "pc", "st", "stück"}},
+	{clove, Custom, 0, "", []string{"teentje", "teentjes", "tenen", "clove", "cloves"}},
+	{pinch, Custom, 0, "", []string{"snufjes", "snuf", "pinch", "pinches", "mespunt", "prise"}},
+	{can, Custom, 0, "", []string{"blikje", "blikjes", "blikken", "can", "cans", "tin", "tins", "dose"}},
+	{bunch, Custom, 0, "", []string{"bosje", "bosjes", "bunch", "bunches", "bund"}},
+}
+
+// Registry is the UnitRegistry used for parsing and converting ingredients.
+var Registry = NewUnitRegistry()
+
+var errorUnknownUnit = errors.New("unknown unit")               // Unit is not in the registry.
+var errorNoConversion = errors.New("units cannot be converted") // Units have a different dimension or no factor.
+
+// NewUnitRegistry creates a UnitRegistry containing the default units and returns it.
+func NewUnitRegistry() *UnitRegistry {
+	ur := &UnitRegistry{
+		defs:    map[Unit]UnitDef{},
+		aliases: map[string]Unit{},
+	}
+	for _, d := range defaultUnits {
+		ur.Add(d)
+	}
+	return ur
+}
+
+// Add takes a UnitDef and adds it to the UnitRegistry. If the unit already exists, the fields that are set in d
+// replace the existing definition and the aliases are added to the existing aliases.
+func (ur *UnitRegistry) Add(d UnitDef) {
+	if old, ok := ur.defs[d.Unit]; ok {
+		if d.Dim == "" {
+			d.Dim, d.Factor = old.Dim, old.Factor
+		}
+		if d.System == "" {
+			d.System = old.System
+		}
+		d.Aliases = append(old.Aliases, d.Aliases...)
+	}
+	ur.defs[d.Unit] = d
+	ur.aliases[normAlias(string(d.Unit))] = d.Unit
+	for _, a := range d.Aliases {
+		ur.aliases[normAlias(a)] = d.Unit
+	}
+}
+
+// Lookup takes a text, e.g. "Eetlepels" or "tbsp.", and returns the definition of the unit it refers to and true.
+// It returns false if the text is not a known unit or alias.
+func (ur *UnitRegistry) Lookup(s string) (UnitDef, bool) {
+	u, ok := ur.aliases[normAlias(s)]
+	if !ok {
+		return UnitDef{}, false
+	}
+	return ur.defs[u], true
+}
+
+// Get takes a Unit and returns its definition and true, or false if the unit is unknown.
+func (ur *UnitRegistry) Get(u Unit) (UnitDef, bool) {
+	d, ok := ur.defs[u]
+	return d, ok
+}
+
+// Convert takes an amount f in Unit from and returns the amount in Unit to. It returns an error if one of the units
+// is unknown or if the units cannot be converted into each other.
+func (ur *UnitRegistry) Convert(f float64, from, to Unit) (float64, error) {
+	dFrom, ok1 := ur.defs[from]
+	dTo, ok2 := ur.defs[to]
+	switch {
+	case !ok1 || !ok2:
+		return 0, errorUnknownUnit
+	case from == to:
+		return f, nil
+	case dFrom.Dim != dTo.Dim || dFrom.Factor == 0 || dTo.Factor == 0:
+		return 0, errorNoConversion
+	}
+	return f * dFrom.Factor / dTo.Factor, nil
+}
+
+// Units returns all units in the UnitRegistry as a sorted slice of string.
+func (ur *UnitRegistry) Units() []string {
+	xs := make([]string, 0, len(ur.defs))
+	for u := range ur.defs {
+		xs = append(xs, string(u))
+	}
+	sort.Strings(xs)
+	return xs
+}
+
+// Load takes the file name of a JSON file containing a list of UnitDefs and adds them to the UnitRegistry. Units that
+// already exist are updated, so the file can also be used to add aliases to or change the default units.
+func (ur *UnitRegistry) Load(fname string) error {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return fmt.Errorf("unable to read units from '%v': %w", fname, err)
+	}
+	var defs []UnitDef
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return fmt.Errorf("%s is corrupt. Please correct or delete the file (%v)", fname, err)
+	}
+	for _, d := range defs {
+		if d.Unit == "" {
+			continue
+		}
+		ur.Add(d)
+	}
+	return nil
+}
+
+// normAlias takes an alias and returns it in the form it is stored in the registry: lowercase without a trailing dot.
+func normAlias(s string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+}
@@ -0,0 +1,84 @@
+package gocookbook
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupUnit(t *testing.T) {
+	cases := []struct {
+		s    string
+		want Unit
+		ok   bool
+	}{
+		{"g", gram, true},
+		{"Gram", gram, true},
+		{"kg.", kilo, true},
+		{"eetlepels", tbsp, true},
+		{"Tbsp.", tbsp, true},
+		{"fl oz", flOz, true},
+		{"metric cup", cupM, true},
+		{"cups", cup, true},
+		{"teentjes", clove, true},
+		{"snufje", pinch, true},
+		{"blikken", can, true},
+		{"banaan", "", false},
+	}
+	for i, c := range cases {
+		d, ok := Registry.Lookup(c.s)
+		if ok != c.ok || d.Unit != c.want {
+			t.Errorf("Case %v failed for '%v'. Want: %v (%v), Got: %v (%v)", i, c.s, c.want, c.ok, d.Unit, ok)
+		}
+	}
+}
+
+func TestConvertUnit(t *testing.T) {
+	cases := []struct {
+		f        float64
+		from, to Unit
+		want     float64
+		err      error
+	}{
+		{1, kilo, gram, 1000, nil},
+		{1, lb, oz, 16, nil},
+		{3, tsp, tbsp, 1, nil},
+		{1, liter, dl, 10, nil},
+		{2, cupM, ml, 500, nil},
+		{1, pcs, pcs, 1, nil},
+		{1, gram, ml, 0, errorNoConversion},
+		{1, pinch, gram, 0, errorNoConversion},
+		{1, "onbekend", gram, 0, errorUnknownUnit},
+	}
+	for i, c := range cases {
+		got, err := Registry.Convert(c.f, c.from, c.to)
+		if !errors.Is(err, c.err) || math.Abs(got-c.want) > 1e-6 {
+			t.Errorf("Case %v failed. Want: %v (%v), Got: %v (%v)", i, c.want, c.err, got, err)
+		}
+	}
+}
+
+func TestLoadUnits(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "units.json")
+	data := `[{"Unit": "handje", "Dim": "overig", "Aliases": ["handful", "handjes"]}, {"Unit": "g", "Aliases": ["grammen"]}]`
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ur := NewUnitRegistry()
+	if err := ur.Load(fname); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"handful", "handjes", "grammen"} {
+		if _, ok := ur.Lookup(s); !ok {
+			t.Errorf("alias '%v' not loaded", s)
+		}
+	}
+	if d, _ := ur.Get(gram); d.Dim != Mass || d.Factor != 1 {
+		t.Errorf("existing unit changed: %+v", d)
+	}
+	if _, ok := Registry.Lookup("handful"); ok {
+		t.Errorf("alias loaded into other registry")
+	}
+}